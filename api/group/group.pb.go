@@ -74,6 +74,98 @@ func (Role) EnumDescriptor() ([]byte, []int) {
 	return file_api_group_group_proto_rawDescGZIP(), []int{0}
 }
 
+// 群事件，群服务通过 Kafka（im_group_event_topic）发给消息服务，由消息服务写成群里的系统消息
+type GroupEventType int32
+
+const (
+	GroupEventType_GROUP_EVENT_UNSPECIFIED       GroupEventType = 0
+	GroupEventType_GROUP_EVENT_GROUP_MUTED       GroupEventType = 1  // 开启全员禁言
+	GroupEventType_GROUP_EVENT_GROUP_UNMUTED     GroupEventType = 2  // 关闭全员禁言
+	GroupEventType_GROUP_EVENT_MEMBER_MUTED      GroupEventType = 3  // 禁言成员
+	GroupEventType_GROUP_EVENT_MEMBER_UNMUTED    GroupEventType = 4  // 解除成员禁言
+	GroupEventType_GROUP_EVENT_MEMBER_LEFT       GroupEventType = 5  // 成员退群，target_ids 为退群的成员
+	GroupEventType_GROUP_EVENT_GROUP_DISSOLVED   GroupEventType = 6  // 群主解散群，target_ids 为解散前的全部成员
+	GroupEventType_GROUP_EVENT_GROUP_ARCHIVED    GroupEventType = 7  // 群归档，变为只读
+	GroupEventType_GROUP_EVENT_GROUP_UNARCHIVED  GroupEventType = 8  // 取消归档
+	GroupEventType_GROUP_EVENT_GROUP_CREATED     GroupEventType = 9  // 建群，target_ids 为群主以外的初始成员，group_name 为群名
+	GroupEventType_GROUP_EVENT_MEMBER_JOINED     GroupEventType = 10 // 成员入群，operator_id 为邀请人 / 审批人，自己加入时为本人
+	GroupEventType_GROUP_EVENT_MEMBER_KICKED     GroupEventType = 11 // 成员被移出群
+	GroupEventType_GROUP_EVENT_ADMIN_PROMOTED    GroupEventType = 12 // 设为管理员
+	GroupEventType_GROUP_EVENT_ADMIN_DEMOTED     GroupEventType = 13 // 取消管理员
+	GroupEventType_GROUP_EVENT_OWNER_TRANSFERRED GroupEventType = 14 // 转让群主，target_ids 为新群主
+	GroupEventType_GROUP_EVENT_GROUP_RENAMED     GroupEventType = 15 // 修改群名，group_name 为新群名
+	GroupEventType_GROUP_EVENT_NOTICE_UPDATED    GroupEventType = 16 // 发布群公告，notice 为公告内容，announcement_id 为公告 ID
+)
+
+// Enum value maps for GroupEventType.
+var (
+	GroupEventType_name = map[int32]string{
+		0:  "GROUP_EVENT_UNSPECIFIED",
+		1:  "GROUP_EVENT_GROUP_MUTED",
+		2:  "GROUP_EVENT_GROUP_UNMUTED",
+		3:  "GROUP_EVENT_MEMBER_MUTED",
+		4:  "GROUP_EVENT_MEMBER_UNMUTED",
+		5:  "GROUP_EVENT_MEMBER_LEFT",
+		6:  "GROUP_EVENT_GROUP_DISSOLVED",
+		7:  "GROUP_EVENT_GROUP_ARCHIVED",
+		8:  "GROUP_EVENT_GROUP_UNARCHIVED",
+		9:  "GROUP_EVENT_GROUP_CREATED",
+		10: "GROUP_EVENT_MEMBER_JOINED",
+		11: "GROUP_EVENT_MEMBER_KICKED",
+		12: "GROUP_EVENT_ADMIN_PROMOTED",
+		13: "GROUP_EVENT_ADMIN_DEMOTED",
+		14: "GROUP_EVENT_OWNER_TRANSFERRED",
+		15: "GROUP_EVENT_GROUP_RENAMED",
+		16: "GROUP_EVENT_NOTICE_UPDATED",
+	}
+	GroupEventType_value = map[string]int32{
+		"GROUP_EVENT_UNSPECIFIED":       0,
+		"GROUP_EVENT_GROUP_MUTED":       1,
+		"GROUP_EVENT_GROUP_UNMUTED":     2,
+		"GROUP_EVENT_MEMBER_MUTED":      3,
+		"GROUP_EVENT_MEMBER_UNMUTED":    4,
+		"GROUP_EVENT_MEMBER_LEFT":       5,
+		"GROUP_EVENT_GROUP_DISSOLVED":   6,
+		"GROUP_EVENT_GROUP_ARCHIVED":    7,
+		"GROUP_EVENT_GROUP_UNARCHIVED":  8,
+		"GROUP_EVENT_GROUP_CREATED":     9,
+		"GROUP_EVENT_MEMBER_JOINED":     10,
+		"GROUP_EVENT_MEMBER_KICKED":     11,
+		"GROUP_EVENT_ADMIN_PROMOTED":    12,
+		"GROUP_EVENT_ADMIN_DEMOTED":     13,
+		"GROUP_EVENT_OWNER_TRANSFERRED": 14,
+		"GROUP_EVENT_GROUP_RENAMED":     15,
+		"GROUP_EVENT_NOTICE_UPDATED":    16,
+	}
+)
+
+func (x GroupEventType) Enum() *GroupEventType {
+	p := new(GroupEventType)
+	*p = x
+	return p
+}
+
+func (x GroupEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GroupEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_group_group_proto_enumTypes[1].Descriptor()
+}
+
+func (GroupEventType) Type() protoreflect.EnumType {
+	return &file_api_group_group_proto_enumTypes[1]
+}
+
+func (x GroupEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GroupEventType.Descriptor instead.
+func (GroupEventType) EnumDescriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{1}
+}
+
 // 请求：获取群组成员
 type ListGroupMembersRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -391,6 +483,588 @@ func (x *ListGroupInfosResponse) GetGroups() []*GroupInfo {
 	return nil
 }
 
+type IsMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IsMemberRequest) Reset() {
+	*x = IsMemberRequest{}
+	mi := &file_api_group_group_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IsMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsMemberRequest) ProtoMessage() {}
+
+func (x *IsMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsMemberRequest.ProtoReflect.Descriptor instead.
+func (*IsMemberRequest) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IsMemberRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *IsMemberRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type IsMemberResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsMember      bool                   `protobuf:"varint,1,opt,name=is_member,json=isMember,proto3" json:"is_member,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IsMemberResponse) Reset() {
+	*x = IsMemberResponse{}
+	mi := &file_api_group_group_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IsMemberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsMemberResponse) ProtoMessage() {}
+
+func (x *IsMemberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsMemberResponse.ProtoReflect.Descriptor instead.
+func (*IsMemberResponse) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *IsMemberResponse) GetIsMember() bool {
+	if x != nil {
+		return x.IsMember
+	}
+	return false
+}
+
+type GetMemberRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMemberRoleRequest) Reset() {
+	*x = GetMemberRoleRequest{}
+	mi := &file_api_group_group_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMemberRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMemberRoleRequest) ProtoMessage() {}
+
+func (x *GetMemberRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMemberRoleRequest.ProtoReflect.Descriptor instead.
+func (*GetMemberRoleRequest) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetMemberRoleRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GetMemberRoleRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type GetMemberRoleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsMember      bool                   `protobuf:"varint,1,opt,name=is_member,json=isMember,proto3" json:"is_member,omitempty"`
+	Role          Role                   `protobuf:"varint,2,opt,name=role,proto3,enum=group.Role" json:"role,omitempty"`
+	JoinSeq       int64                  `protobuf:"varint,3,opt,name=join_seq,json=joinSeq,proto3" json:"join_seq,omitempty"`             // 入群时群消息的 seq，之后的消息才可见
+	FullHistory   bool                   `protobuf:"varint,4,opt,name=full_history,json=fullHistory,proto3" json:"full_history,omitempty"` // 群允许新成员查看入群前的聊天记录
+	GroupMuted    bool                   `protobuf:"varint,5,opt,name=group_muted,json=groupMuted,proto3" json:"group_muted,omitempty"`    // 全员禁言中，只有群主和管理员可以发言
+	MutedUntil    int64                  `protobuf:"varint,6,opt,name=muted_until,json=mutedUntil,proto3" json:"muted_until,omitempty"`    // 成员禁言到期时间（unix 秒），0 表示没有被禁言
+	Archived      bool                   `protobuf:"varint,7,opt,name=archived,proto3" json:"archived,omitempty"`                          // 群已归档，只读
+	LargeGroup    bool                   `protobuf:"varint,8,opt,name=large_group,json=largeGroup,proto3" json:"large_group,omitempty"`    // 大群：消息服务不再逐个成员写会话和未读记录
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMemberRoleResponse) Reset() {
+	*x = GetMemberRoleResponse{}
+	mi := &file_api_group_group_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMemberRoleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMemberRoleResponse) ProtoMessage() {}
+
+func (x *GetMemberRoleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMemberRoleResponse.ProtoReflect.Descriptor instead.
+func (*GetMemberRoleResponse) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetMemberRoleResponse) GetIsMember() bool {
+	if x != nil {
+		return x.IsMember
+	}
+	return false
+}
+
+func (x *GetMemberRoleResponse) GetRole() Role {
+	if x != nil {
+		return x.Role
+	}
+	return Role_ROLE_UNSPECIFIED
+}
+
+func (x *GetMemberRoleResponse) GetJoinSeq() int64 {
+	if x != nil {
+		return x.JoinSeq
+	}
+	return 0
+}
+
+func (x *GetMemberRoleResponse) GetFullHistory() bool {
+	if x != nil {
+		return x.FullHistory
+	}
+	return false
+}
+
+func (x *GetMemberRoleResponse) GetGroupMuted() bool {
+	if x != nil {
+		return x.GroupMuted
+	}
+	return false
+}
+
+func (x *GetMemberRoleResponse) GetMutedUntil() int64 {
+	if x != nil {
+		return x.MutedUntil
+	}
+	return 0
+}
+
+func (x *GetMemberRoleResponse) GetArchived() bool {
+	if x != nil {
+		return x.Archived
+	}
+	return false
+}
+
+func (x *GetMemberRoleResponse) GetLargeGroup() bool {
+	if x != nil {
+		return x.LargeGroup
+	}
+	return false
+}
+
+type ListUserGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserGroupsRequest) Reset() {
+	*x = ListUserGroupsRequest{}
+	mi := &file_api_group_group_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserGroupsRequest) ProtoMessage() {}
+
+func (x *ListUserGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListUserGroupsRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// 用户在一个群里可见的历史范围，含义同 GetMemberRoleResponse
+type MemberScope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	JoinSeq       int64                  `protobuf:"varint,2,opt,name=join_seq,json=joinSeq,proto3" json:"join_seq,omitempty"`
+	FullHistory   bool                   `protobuf:"varint,3,opt,name=full_history,json=fullHistory,proto3" json:"full_history,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MemberScope) Reset() {
+	*x = MemberScope{}
+	mi := &file_api_group_group_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemberScope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemberScope) ProtoMessage() {}
+
+func (x *MemberScope) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemberScope.ProtoReflect.Descriptor instead.
+func (*MemberScope) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MemberScope) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *MemberScope) GetJoinSeq() int64 {
+	if x != nil {
+		return x.JoinSeq
+	}
+	return 0
+}
+
+func (x *MemberScope) GetFullHistory() bool {
+	if x != nil {
+		return x.FullHistory
+	}
+	return false
+}
+
+type ListUserGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []*MemberScope         `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserGroupsResponse) Reset() {
+	*x = ListUserGroupsResponse{}
+	mi := &file_api_group_group_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserGroupsResponse) ProtoMessage() {}
+
+func (x *ListUserGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListUserGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListUserGroupsResponse) GetGroups() []*MemberScope {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type GroupEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	EventId        string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"` // 事件 ID，消费端用来去重
+	Type           GroupEventType         `protobuf:"varint,2,opt,name=type,proto3,enum=group.GroupEventType" json:"type,omitempty"`
+	GroupId        string                 `protobuf:"bytes,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	OperatorId     int64                  `protobuf:"varint,4,opt,name=operator_id,json=operatorId,proto3" json:"operator_id,omitempty"`
+	TargetIds      []int64                `protobuf:"varint,5,rep,packed,name=target_ids,json=targetIds,proto3" json:"target_ids,omitempty"`
+	Until          int64                  `protobuf:"varint,6,opt,name=until,proto3" json:"until,omitempty"`                                          // 禁言到期时间（unix 秒）
+	Timestamp      int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                  // 事件发生时间（unix 毫秒）
+	GroupName      string                 `protobuf:"bytes,8,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`                  // 建群和改名时的群名
+	Notice         string                 `protobuf:"bytes,9,opt,name=notice,proto3" json:"notice,omitempty"`                                         // 修改后的群公告
+	AnnouncementId int64                  `protobuf:"varint,10,opt,name=announcement_id,json=announcementId,proto3" json:"announcement_id,omitempty"` // 发布的群公告 ID
+	FormerMembers  []*FormerMember        `protobuf:"bytes,11,rep,name=former_members,json=formerMembers,proto3" json:"former_members,omitempty"`     // 解散时每个成员的可见范围，消息服务据此提供只读历史
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GroupEvent) Reset() {
+	*x = GroupEvent{}
+	mi := &file_api_group_group_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupEvent) ProtoMessage() {}
+
+func (x *GroupEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupEvent.ProtoReflect.Descriptor instead.
+func (*GroupEvent) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GroupEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetType() GroupEventType {
+	if x != nil {
+		return x.Type
+	}
+	return GroupEventType_GROUP_EVENT_UNSPECIFIED
+}
+
+func (x *GroupEvent) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetOperatorId() int64 {
+	if x != nil {
+		return x.OperatorId
+	}
+	return 0
+}
+
+func (x *GroupEvent) GetTargetIds() []int64 {
+	if x != nil {
+		return x.TargetIds
+	}
+	return nil
+}
+
+func (x *GroupEvent) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+func (x *GroupEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *GroupEvent) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetNotice() string {
+	if x != nil {
+		return x.Notice
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetAnnouncementId() int64 {
+	if x != nil {
+		return x.AnnouncementId
+	}
+	return 0
+}
+
+func (x *GroupEvent) GetFormerMembers() []*FormerMember {
+	if x != nil {
+		return x.FormerMembers
+	}
+	return nil
+}
+
+// 群解散前的成员和入群位置，含义同 GetMemberRoleResponse
+type FormerMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	JoinSeq       int64                  `protobuf:"varint,2,opt,name=join_seq,json=joinSeq,proto3" json:"join_seq,omitempty"`
+	FullHistory   bool                   `protobuf:"varint,3,opt,name=full_history,json=fullHistory,proto3" json:"full_history,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FormerMember) Reset() {
+	*x = FormerMember{}
+	mi := &file_api_group_group_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FormerMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FormerMember) ProtoMessage() {}
+
+func (x *FormerMember) ProtoReflect() protoreflect.Message {
+	mi := &file_api_group_group_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FormerMember.ProtoReflect.Descriptor instead.
+func (*FormerMember) Descriptor() ([]byte, []int) {
+	return file_api_group_group_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FormerMember) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *FormerMember) GetJoinSeq() int64 {
+	if x != nil {
+		return x.JoinSeq
+	}
+	return 0
+}
+
+func (x *FormerMember) GetFullHistory() bool {
+	if x != nil {
+		return x.FullHistory
+	}
+	return false
+}
+
 var File_api_group_group_proto protoreflect.FileDescriptor
 
 const file_api_group_group_proto_rawDesc = "" +
@@ -409,22 +1083,93 @@ const file_api_group_group_proto_rawDesc = "" +
 	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x1d\n" +
 	"\n" +
 	"group_name\x18\x02 \x01(\tR\tgroupName\x12\x16\n" +
-	"\x06avatar\x18\x03 \x01(\tR\x06avatar\"c\n" +
+	"\x06avatar\x18\x03 \x01(\tR\x06avatar\"i\n" +
 	"\x18ListGroupMembersResponse\x12\x19\n" +
 	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12,\n" +
-	"\amembers\x18\x02 \x03(\v2\x12.group.GroupMemberR\amembers\"B\n" +
+	"\amembers\x18\x02 \x03(\v2\x12.group.GroupMemberR\amembersJ\x04\b\x03\x10\x04\"B\n" +
 	"\x16ListGroupInfosResponse\x12(\n" +
-	"\x06groups\x18\x01 \x03(\v2\x10.group.GroupInfoR\x06groups*M\n" +
+	"\x06groups\x18\x01 \x03(\v2\x10.group.GroupInfoR\x06groups\"E\n" +
+	"\x0fIsMemberRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\"/\n" +
+	"\x10IsMemberResponse\x12\x1b\n" +
+	"\tis_member\x18\x01 \x01(\bR\bisMember\"J\n" +
+	"\x14GetMemberRoleRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\"\x92\x02\n" +
+	"\x15GetMemberRoleResponse\x12\x1b\n" +
+	"\tis_member\x18\x01 \x01(\bR\bisMember\x12\x1f\n" +
+	"\x04role\x18\x02 \x01(\x0e2\v.group.RoleR\x04role\x12\x19\n" +
+	"\bjoin_seq\x18\x03 \x01(\x03R\ajoinSeq\x12!\n" +
+	"\ffull_history\x18\x04 \x01(\bR\vfullHistory\x12\x1f\n" +
+	"\vgroup_muted\x18\x05 \x01(\bR\n" +
+	"groupMuted\x12\x1f\n" +
+	"\vmuted_until\x18\x06 \x01(\x03R\n" +
+	"mutedUntil\x12\x1a\n" +
+	"\barchived\x18\a \x01(\bR\barchived\x12\x1f\n" +
+	"\vlarge_group\x18\b \x01(\bR\n" +
+	"largeGroup\"0\n" +
+	"\x15ListUserGroupsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\"f\n" +
+	"\vMemberScope\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x19\n" +
+	"\bjoin_seq\x18\x02 \x01(\x03R\ajoinSeq\x12!\n" +
+	"\ffull_history\x18\x03 \x01(\bR\vfullHistory\"D\n" +
+	"\x16ListUserGroupsResponse\x12*\n" +
+	"\x06groups\x18\x01 \x03(\v2\x12.group.MemberScopeR\x06groups\"\xfd\x02\n" +
+	"\n" +
+	"GroupEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12)\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x15.group.GroupEventTypeR\x04type\x12\x19\n" +
+	"\bgroup_id\x18\x03 \x01(\tR\agroupId\x12\x1f\n" +
+	"\voperator_id\x18\x04 \x01(\x03R\n" +
+	"operatorId\x12\x1d\n" +
+	"\n" +
+	"target_ids\x18\x05 \x03(\x03R\ttargetIds\x12\x14\n" +
+	"\x05until\x18\x06 \x01(\x03R\x05until\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"group_name\x18\b \x01(\tR\tgroupName\x12\x16\n" +
+	"\x06notice\x18\t \x01(\tR\x06notice\x12'\n" +
+	"\x0fannouncement_id\x18\n" +
+	" \x01(\x03R\x0eannouncementId\x12:\n" +
+	"\x0eformer_members\x18\v \x03(\v2\x13.group.FormerMemberR\rformerMembers\"e\n" +
+	"\fFormerMember\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x19\n" +
+	"\bjoin_seq\x18\x02 \x01(\x03R\ajoinSeq\x12!\n" +
+	"\ffull_history\x18\x03 \x01(\bR\vfullHistory*M\n" +
 	"\x04Role\x12\x14\n" +
 	"\x10ROLE_UNSPECIFIED\x10\x00\x12\x0f\n" +
 	"\vROLE_MEMBER\x10\x01\x12\x0e\n" +
 	"\n" +
 	"ROLE_ADMIN\x10\x02\x12\x0e\n" +
 	"\n" +
-	"ROLE_OWNER\x10\x032\xb2\x01\n" +
+	"ROLE_OWNER\x10\x03*\xa5\x04\n" +
+	"\x0eGroupEventType\x12\x1b\n" +
+	"\x17GROUP_EVENT_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17GROUP_EVENT_GROUP_MUTED\x10\x01\x12\x1d\n" +
+	"\x19GROUP_EVENT_GROUP_UNMUTED\x10\x02\x12\x1c\n" +
+	"\x18GROUP_EVENT_MEMBER_MUTED\x10\x03\x12\x1e\n" +
+	"\x1aGROUP_EVENT_MEMBER_UNMUTED\x10\x04\x12\x1b\n" +
+	"\x17GROUP_EVENT_MEMBER_LEFT\x10\x05\x12\x1f\n" +
+	"\x1bGROUP_EVENT_GROUP_DISSOLVED\x10\x06\x12\x1e\n" +
+	"\x1aGROUP_EVENT_GROUP_ARCHIVED\x10\a\x12 \n" +
+	"\x1cGROUP_EVENT_GROUP_UNARCHIVED\x10\b\x12\x1d\n" +
+	"\x19GROUP_EVENT_GROUP_CREATED\x10\t\x12\x1d\n" +
+	"\x19GROUP_EVENT_MEMBER_JOINED\x10\n" +
+	"\x12\x1d\n" +
+	"\x19GROUP_EVENT_MEMBER_KICKED\x10\v\x12\x1e\n" +
+	"\x1aGROUP_EVENT_ADMIN_PROMOTED\x10\f\x12\x1d\n" +
+	"\x19GROUP_EVENT_ADMIN_DEMOTED\x10\r\x12!\n" +
+	"\x1dGROUP_EVENT_OWNER_TRANSFERRED\x10\x0e\x12\x1d\n" +
+	"\x19GROUP_EVENT_GROUP_RENAMED\x10\x0f\x12\x1e\n" +
+	"\x1aGROUP_EVENT_NOTICE_UPDATED\x10\x102\x8a\x03\n" +
 	"\fGroupService\x12S\n" +
 	"\x10ListGroupMembers\x12\x1e.group.ListGroupMembersRequest\x1a\x1f.group.ListGroupMembersResponse\x12M\n" +
-	"\x0eListGroupInfos\x12\x1c.group.ListGroupInfosRequest\x1a\x1d.group.ListGroupInfosResponseB1Z/github.com/AdventureDe/LinkIM/api/group;grouppbb\x06proto3"
+	"\x0eListGroupInfos\x12\x1c.group.ListGroupInfosRequest\x1a\x1d.group.ListGroupInfosResponse\x12;\n" +
+	"\bIsMember\x12\x16.group.IsMemberRequest\x1a\x17.group.IsMemberResponse\x12J\n" +
+	"\rGetMemberRole\x12\x1b.group.GetMemberRoleRequest\x1a\x1c.group.GetMemberRoleResponse\x12M\n" +
+	"\x0eListUserGroups\x12\x1c.group.ListUserGroupsRequest\x1a\x1d.group.ListUserGroupsResponseB1Z/github.com/AdventureDe/LinkIM/api/group;grouppbb\x06proto3"
 
 var (
 	file_api_group_group_proto_rawDescOnce sync.Once
@@ -438,30 +1183,50 @@ func file_api_group_group_proto_rawDescGZIP() []byte {
 	return file_api_group_group_proto_rawDescData
 }
 
-var file_api_group_group_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_api_group_group_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_api_group_group_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_group_group_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_api_group_group_proto_goTypes = []any{
 	(Role)(0),                        // 0: group.Role
-	(*ListGroupMembersRequest)(nil),  // 1: group.ListGroupMembersRequest
-	(*ListGroupInfosRequest)(nil),    // 2: group.ListGroupInfosRequest
-	(*GroupMember)(nil),              // 3: group.GroupMember
-	(*GroupInfo)(nil),                // 4: group.GroupInfo
-	(*ListGroupMembersResponse)(nil), // 5: group.ListGroupMembersResponse
-	(*ListGroupInfosResponse)(nil),   // 6: group.ListGroupInfosResponse
+	(GroupEventType)(0),              // 1: group.GroupEventType
+	(*ListGroupMembersRequest)(nil),  // 2: group.ListGroupMembersRequest
+	(*ListGroupInfosRequest)(nil),    // 3: group.ListGroupInfosRequest
+	(*GroupMember)(nil),              // 4: group.GroupMember
+	(*GroupInfo)(nil),                // 5: group.GroupInfo
+	(*ListGroupMembersResponse)(nil), // 6: group.ListGroupMembersResponse
+	(*ListGroupInfosResponse)(nil),   // 7: group.ListGroupInfosResponse
+	(*IsMemberRequest)(nil),          // 8: group.IsMemberRequest
+	(*IsMemberResponse)(nil),         // 9: group.IsMemberResponse
+	(*GetMemberRoleRequest)(nil),     // 10: group.GetMemberRoleRequest
+	(*GetMemberRoleResponse)(nil),    // 11: group.GetMemberRoleResponse
+	(*ListUserGroupsRequest)(nil),    // 12: group.ListUserGroupsRequest
+	(*MemberScope)(nil),              // 13: group.MemberScope
+	(*ListUserGroupsResponse)(nil),   // 14: group.ListUserGroupsResponse
+	(*GroupEvent)(nil),               // 15: group.GroupEvent
+	(*FormerMember)(nil),             // 16: group.FormerMember
 }
 var file_api_group_group_proto_depIdxs = []int32{
-	0, // 0: group.GroupMember.role:type_name -> group.Role
-	3, // 1: group.ListGroupMembersResponse.members:type_name -> group.GroupMember
-	4, // 2: group.ListGroupInfosResponse.groups:type_name -> group.GroupInfo
-	1, // 3: group.GroupService.ListGroupMembers:input_type -> group.ListGroupMembersRequest
-	2, // 4: group.GroupService.ListGroupInfos:input_type -> group.ListGroupInfosRequest
-	5, // 5: group.GroupService.ListGroupMembers:output_type -> group.ListGroupMembersResponse
-	6, // 6: group.GroupService.ListGroupInfos:output_type -> group.ListGroupInfosResponse
-	5, // [5:7] is the sub-list for method output_type
-	3, // [3:5] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	0,  // 0: group.GroupMember.role:type_name -> group.Role
+	4,  // 1: group.ListGroupMembersResponse.members:type_name -> group.GroupMember
+	5,  // 2: group.ListGroupInfosResponse.groups:type_name -> group.GroupInfo
+	0,  // 3: group.GetMemberRoleResponse.role:type_name -> group.Role
+	13, // 4: group.ListUserGroupsResponse.groups:type_name -> group.MemberScope
+	1,  // 5: group.GroupEvent.type:type_name -> group.GroupEventType
+	16, // 6: group.GroupEvent.former_members:type_name -> group.FormerMember
+	2,  // 7: group.GroupService.ListGroupMembers:input_type -> group.ListGroupMembersRequest
+	3,  // 8: group.GroupService.ListGroupInfos:input_type -> group.ListGroupInfosRequest
+	8,  // 9: group.GroupService.IsMember:input_type -> group.IsMemberRequest
+	10, // 10: group.GroupService.GetMemberRole:input_type -> group.GetMemberRoleRequest
+	12, // 11: group.GroupService.ListUserGroups:input_type -> group.ListUserGroupsRequest
+	6,  // 12: group.GroupService.ListGroupMembers:output_type -> group.ListGroupMembersResponse
+	7,  // 13: group.GroupService.ListGroupInfos:output_type -> group.ListGroupInfosResponse
+	9,  // 14: group.GroupService.IsMember:output_type -> group.IsMemberResponse
+	11, // 15: group.GroupService.GetMemberRole:output_type -> group.GetMemberRoleResponse
+	14, // 16: group.GroupService.ListUserGroups:output_type -> group.ListUserGroupsResponse
+	12, // [12:17] is the sub-list for method output_type
+	7,  // [7:12] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_api_group_group_proto_init() }
@@ -474,8 +1239,8 @@ func file_api_group_group_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_group_group_proto_rawDesc), len(file_api_group_group_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   6,
+			NumEnums:      2,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,6 +21,9 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	GroupService_ListGroupMembers_FullMethodName = "/group.GroupService/ListGroupMembers"
 	GroupService_ListGroupInfos_FullMethodName   = "/group.GroupService/ListGroupInfos"
+	GroupService_IsMember_FullMethodName         = "/group.GroupService/IsMember"
+	GroupService_GetMemberRole_FullMethodName    = "/group.GroupService/GetMemberRole"
+	GroupService_ListUserGroups_FullMethodName   = "/group.GroupService/ListUserGroups"
 )
 
 // GroupServiceClient is the client API for GroupService service.
@@ -32,6 +35,12 @@ type GroupServiceClient interface {
 	// 获取指定群组的成员列表
 	ListGroupMembers(ctx context.Context, in *ListGroupMembersRequest, opts ...grpc.CallOption) (*ListGroupMembersResponse, error)
 	ListGroupInfos(ctx context.Context, in *ListGroupInfosRequest, opts ...grpc.CallOption) (*ListGroupInfosResponse, error)
+	// 判断用户是否是群成员
+	IsMember(ctx context.Context, in *IsMemberRequest, opts ...grpc.CallOption) (*IsMemberResponse, error)
+	// 查询用户在群里的角色和可见的历史范围，不在群里时 is_member 为 false
+	GetMemberRole(ctx context.Context, in *GetMemberRoleRequest, opts ...grpc.CallOption) (*GetMemberRoleResponse, error)
+	// 用户加入的所有群以及在每个群里可见的历史范围，不包含已解散的群
+	ListUserGroups(ctx context.Context, in *ListUserGroupsRequest, opts ...grpc.CallOption) (*ListUserGroupsResponse, error)
 }
 
 type groupServiceClient struct {
@@ -62,6 +71,36 @@ func (c *groupServiceClient) ListGroupInfos(ctx context.Context, in *ListGroupIn
 	return out, nil
 }
 
+func (c *groupServiceClient) IsMember(ctx context.Context, in *IsMemberRequest, opts ...grpc.CallOption) (*IsMemberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IsMemberResponse)
+	err := c.cc.Invoke(ctx, GroupService_IsMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupServiceClient) GetMemberRole(ctx context.Context, in *GetMemberRoleRequest, opts ...grpc.CallOption) (*GetMemberRoleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMemberRoleResponse)
+	err := c.cc.Invoke(ctx, GroupService_GetMemberRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupServiceClient) ListUserGroups(ctx context.Context, in *ListUserGroupsRequest, opts ...grpc.CallOption) (*ListUserGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUserGroupsResponse)
+	err := c.cc.Invoke(ctx, GroupService_ListUserGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GroupServiceServer is the server API for GroupService service.
 // All implementations must embed UnimplementedGroupServiceServer
 // for forward compatibility.
@@ -71,6 +110,12 @@ type GroupServiceServer interface {
 	// 获取指定群组的成员列表
 	ListGroupMembers(context.Context, *ListGroupMembersRequest) (*ListGroupMembersResponse, error)
 	ListGroupInfos(context.Context, *ListGroupInfosRequest) (*ListGroupInfosResponse, error)
+	// 判断用户是否是群成员
+	IsMember(context.Context, *IsMemberRequest) (*IsMemberResponse, error)
+	// 查询用户在群里的角色和可见的历史范围，不在群里时 is_member 为 false
+	GetMemberRole(context.Context, *GetMemberRoleRequest) (*GetMemberRoleResponse, error)
+	// 用户加入的所有群以及在每个群里可见的历史范围，不包含已解散的群
+	ListUserGroups(context.Context, *ListUserGroupsRequest) (*ListUserGroupsResponse, error)
 	mustEmbedUnimplementedGroupServiceServer()
 }
 
@@ -87,6 +132,15 @@ func (UnimplementedGroupServiceServer) ListGroupMembers(context.Context, *ListGr
 func (UnimplementedGroupServiceServer) ListGroupInfos(context.Context, *ListGroupInfosRequest) (*ListGroupInfosResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListGroupInfos not implemented")
 }
+func (UnimplementedGroupServiceServer) IsMember(context.Context, *IsMemberRequest) (*IsMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsMember not implemented")
+}
+func (UnimplementedGroupServiceServer) GetMemberRole(context.Context, *GetMemberRoleRequest) (*GetMemberRoleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMemberRole not implemented")
+}
+func (UnimplementedGroupServiceServer) ListUserGroups(context.Context, *ListUserGroupsRequest) (*ListUserGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserGroups not implemented")
+}
 func (UnimplementedGroupServiceServer) mustEmbedUnimplementedGroupServiceServer() {}
 func (UnimplementedGroupServiceServer) testEmbeddedByValue()                      {}
 
@@ -144,6 +198,60 @@ func _GroupService_ListGroupInfos_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GroupService_IsMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupServiceServer).IsMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GroupService_IsMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupServiceServer).IsMember(ctx, req.(*IsMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupService_GetMemberRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMemberRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupServiceServer).GetMemberRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GroupService_GetMemberRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupServiceServer).GetMemberRole(ctx, req.(*GetMemberRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupService_ListUserGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupServiceServer).ListUserGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GroupService_ListUserGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupServiceServer).ListUserGroups(ctx, req.(*ListUserGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GroupService_ServiceDesc is the grpc.ServiceDesc for GroupService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -159,6 +267,18 @@ var GroupService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListGroupInfos",
 			Handler:    _GroupService_ListGroupInfos_Handler,
 		},
+		{
+			MethodName: "IsMember",
+			Handler:    _GroupService_IsMember_Handler,
+		},
+		{
+			MethodName: "GetMemberRole",
+			Handler:    _GroupService_GetMemberRole_Handler,
+		},
+		{
+			MethodName: "ListUserGroups",
+			Handler:    _GroupService_ListUserGroups_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/group/group.proto",
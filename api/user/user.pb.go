@@ -23,7 +23,7 @@ const (
 
 type GetUserInfosRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserIds       []int64                `protobuf:"varint,1,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	UserIds       []int64                `protobuf:"varint,1,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"` //repeated代表的是数组/切片 查多个用户的信息
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -169,6 +169,274 @@ func (x *GetUserInfosResponse) GetUsers() []*UserInfo {
 	return nil
 }
 
+type GetBlockedByRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	CandidateIds  []int64                `protobuf:"varint,2,rep,packed,name=candidate_ids,json=candidateIds,proto3" json:"candidate_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlockedByRequest) Reset() {
+	*x = GetBlockedByRequest{}
+	mi := &file_api_user_user_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlockedByRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlockedByRequest) ProtoMessage() {}
+
+func (x *GetBlockedByRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_user_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlockedByRequest.ProtoReflect.Descriptor instead.
+func (*GetBlockedByRequest) Descriptor() ([]byte, []int) {
+	return file_api_user_user_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetBlockedByRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *GetBlockedByRequest) GetCandidateIds() []int64 {
+	if x != nil {
+		return x.CandidateIds
+	}
+	return nil
+}
+
+type GetBlockedByResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserIds       []int64                `protobuf:"varint,1,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlockedByResponse) Reset() {
+	*x = GetBlockedByResponse{}
+	mi := &file_api_user_user_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlockedByResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlockedByResponse) ProtoMessage() {}
+
+func (x *GetBlockedByResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_user_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlockedByResponse.ProtoReflect.Descriptor instead.
+func (*GetBlockedByResponse) Descriptor() ([]byte, []int) {
+	return file_api_user_user_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetBlockedByResponse) GetUserIds() []int64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+type GetPresencesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ViewerId      int64                  `protobuf:"varint,1,opt,name=viewer_id,json=viewerId,proto3" json:"viewer_id,omitempty"`
+	UserIds       []int64                `protobuf:"varint,2,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPresencesRequest) Reset() {
+	*x = GetPresencesRequest{}
+	mi := &file_api_user_user_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPresencesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPresencesRequest) ProtoMessage() {}
+
+func (x *GetPresencesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_user_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPresencesRequest.ProtoReflect.Descriptor instead.
+func (*GetPresencesRequest) Descriptor() ([]byte, []int) {
+	return file_api_user_user_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetPresencesRequest) GetViewerId() int64 {
+	if x != nil {
+		return x.ViewerId
+	}
+	return 0
+}
+
+func (x *GetPresencesRequest) GetUserIds() []int64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+type Presence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Visible       bool                   `protobuf:"varint,2,opt,name=visible,proto3" json:"visible,omitempty"` // false 表示对方隐藏了在线状态，其余字段为空
+	Online        bool                   `protobuf:"varint,3,opt,name=online,proto3" json:"online,omitempty"`
+	Platforms     []int32                `protobuf:"varint,4,rep,packed,name=platforms,proto3" json:"platforms,omitempty"`        // 在线的设备
+	LastSeen      int64                  `protobuf:"varint,5,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // unix 秒，0 表示未知
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	mi := &file_api_user_user_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_user_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_api_user_user_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Presence) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Presence) GetVisible() bool {
+	if x != nil {
+		return x.Visible
+	}
+	return false
+}
+
+func (x *Presence) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *Presence) GetPlatforms() []int32 {
+	if x != nil {
+		return x.Platforms
+	}
+	return nil
+}
+
+func (x *Presence) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+type GetPresencesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Presences     []*Presence            `protobuf:"bytes,1,rep,name=presences,proto3" json:"presences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPresencesResponse) Reset() {
+	*x = GetPresencesResponse{}
+	mi := &file_api_user_user_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPresencesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPresencesResponse) ProtoMessage() {}
+
+func (x *GetPresencesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_user_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPresencesResponse.ProtoReflect.Descriptor instead.
+func (*GetPresencesResponse) Descriptor() ([]byte, []int) {
+	return file_api_user_user_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPresencesResponse) GetPresences() []*Presence {
+	if x != nil {
+		return x.Presences
+	}
+	return nil
+}
+
 var File_api_user_user_proto protoreflect.FileDescriptor
 
 const file_api_user_user_proto_rawDesc = "" +
@@ -181,9 +449,27 @@ const file_api_user_user_proto_rawDesc = "" +
 	"\bnickname\x18\x02 \x01(\tR\bnickname\x12\x16\n" +
 	"\x06avatar\x18\x03 \x01(\tR\x06avatar\"<\n" +
 	"\x14GetUserInfosResponse\x12$\n" +
-	"\x05users\x18\x01 \x03(\v2\x0e.user.UserInfoR\x05users2T\n" +
+	"\x05users\x18\x01 \x03(\v2\x0e.user.UserInfoR\x05users\"S\n" +
+	"\x13GetBlockedByRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12#\n" +
+	"\rcandidate_ids\x18\x02 \x03(\x03R\fcandidateIds\"1\n" +
+	"\x14GetBlockedByResponse\x12\x19\n" +
+	"\buser_ids\x18\x01 \x03(\x03R\auserIds\"M\n" +
+	"\x13GetPresencesRequest\x12\x1b\n" +
+	"\tviewer_id\x18\x01 \x01(\x03R\bviewerId\x12\x19\n" +
+	"\buser_ids\x18\x02 \x03(\x03R\auserIds\"\x90\x01\n" +
+	"\bPresence\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x18\n" +
+	"\avisible\x18\x02 \x01(\bR\avisible\x12\x16\n" +
+	"\x06online\x18\x03 \x01(\bR\x06online\x12\x1c\n" +
+	"\tplatforms\x18\x04 \x03(\x05R\tplatforms\x12\x1b\n" +
+	"\tlast_seen\x18\x05 \x01(\x03R\blastSeen\"D\n" +
+	"\x14GetPresencesResponse\x12,\n" +
+	"\tpresences\x18\x01 \x03(\v2\x0e.user.PresenceR\tpresences2\xe2\x01\n" +
 	"\vUserService\x12E\n" +
-	"\fGetUserInfos\x12\x19.user.GetUserInfosRequest\x1a\x1a.user.GetUserInfosResponseB1Z/github.com/AdventureDe/tempName/api/user;userpbb\x06proto3"
+	"\fGetUserInfos\x12\x19.user.GetUserInfosRequest\x1a\x1a.user.GetUserInfosResponse\x12E\n" +
+	"\fGetBlockedBy\x12\x19.user.GetBlockedByRequest\x1a\x1a.user.GetBlockedByResponse\x12E\n" +
+	"\fGetPresences\x12\x19.user.GetPresencesRequest\x1a\x1a.user.GetPresencesResponseB/Z-github.com/AdventureDe/LinkIM/api/user;userpbb\x06proto3"
 
 var (
 	file_api_user_user_proto_rawDescOnce sync.Once
@@ -197,21 +483,31 @@ func file_api_user_user_proto_rawDescGZIP() []byte {
 	return file_api_user_user_proto_rawDescData
 }
 
-var file_api_user_user_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_api_user_user_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_api_user_user_proto_goTypes = []any{
 	(*GetUserInfosRequest)(nil),  // 0: user.GetUserInfosRequest
 	(*UserInfo)(nil),             // 1: user.UserInfo
 	(*GetUserInfosResponse)(nil), // 2: user.GetUserInfosResponse
+	(*GetBlockedByRequest)(nil),  // 3: user.GetBlockedByRequest
+	(*GetBlockedByResponse)(nil), // 4: user.GetBlockedByResponse
+	(*GetPresencesRequest)(nil),  // 5: user.GetPresencesRequest
+	(*Presence)(nil),             // 6: user.Presence
+	(*GetPresencesResponse)(nil), // 7: user.GetPresencesResponse
 }
 var file_api_user_user_proto_depIdxs = []int32{
 	1, // 0: user.GetUserInfosResponse.users:type_name -> user.UserInfo
-	0, // 1: user.UserService.GetUserInfos:input_type -> user.GetUserInfosRequest
-	2, // 2: user.UserService.GetUserInfos:output_type -> user.GetUserInfosResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6, // 1: user.GetPresencesResponse.presences:type_name -> user.Presence
+	0, // 2: user.UserService.GetUserInfos:input_type -> user.GetUserInfosRequest
+	3, // 3: user.UserService.GetBlockedBy:input_type -> user.GetBlockedByRequest
+	5, // 4: user.UserService.GetPresences:input_type -> user.GetPresencesRequest
+	2, // 5: user.UserService.GetUserInfos:output_type -> user.GetUserInfosResponse
+	4, // 6: user.UserService.GetBlockedBy:output_type -> user.GetBlockedByResponse
+	7, // 7: user.UserService.GetPresences:output_type -> user.GetPresencesResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_api_user_user_proto_init() }
@@ -225,7 +521,7 @@ func file_api_user_user_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_user_user_proto_rawDesc), len(file_api_user_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
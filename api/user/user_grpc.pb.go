@@ -8,7 +8,6 @@ package userpb
 
 import (
 	context "context"
-
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -21,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	UserService_GetUserInfos_FullMethodName = "/user.UserService/GetUserInfos"
+	UserService_GetBlockedBy_FullMethodName = "/user.UserService/GetBlockedBy"
+	UserService_GetPresences_FullMethodName = "/user.UserService/GetPresences"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -29,6 +30,10 @@ const (
 type UserServiceClient interface {
 	// 批量获取用户信息
 	GetUserInfos(ctx context.Context, in *GetUserInfosRequest, opts ...grpc.CallOption) (*GetUserInfosResponse, error)
+	// 在候选用户里找出拉黑了 user_id 的人
+	GetBlockedBy(ctx context.Context, in *GetBlockedByRequest, opts ...grpc.CallOption) (*GetBlockedByResponse, error)
+	// 批量查询在线状态，按 viewer_id 的可见性过滤
+	GetPresences(ctx context.Context, in *GetPresencesRequest, opts ...grpc.CallOption) (*GetPresencesResponse, error)
 }
 
 type userServiceClient struct {
@@ -49,12 +54,36 @@ func (c *userServiceClient) GetUserInfos(ctx context.Context, in *GetUserInfosRe
 	return out, nil
 }
 
+func (c *userServiceClient) GetBlockedBy(ctx context.Context, in *GetBlockedByRequest, opts ...grpc.CallOption) (*GetBlockedByResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBlockedByResponse)
+	err := c.cc.Invoke(ctx, UserService_GetBlockedBy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetPresences(ctx context.Context, in *GetPresencesRequest, opts ...grpc.CallOption) (*GetPresencesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPresencesResponse)
+	err := c.cc.Invoke(ctx, UserService_GetPresences_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility.
 type UserServiceServer interface {
 	// 批量获取用户信息
 	GetUserInfos(context.Context, *GetUserInfosRequest) (*GetUserInfosResponse, error)
+	// 在候选用户里找出拉黑了 user_id 的人
+	GetBlockedBy(context.Context, *GetBlockedByRequest) (*GetBlockedByResponse, error)
+	// 批量查询在线状态，按 viewer_id 的可见性过滤
+	GetPresences(context.Context, *GetPresencesRequest) (*GetPresencesResponse, error)
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -68,6 +97,12 @@ type UnimplementedUserServiceServer struct{}
 func (UnimplementedUserServiceServer) GetUserInfos(context.Context, *GetUserInfosRequest) (*GetUserInfosResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserInfos not implemented")
 }
+func (UnimplementedUserServiceServer) GetBlockedBy(context.Context, *GetBlockedByRequest) (*GetBlockedByResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockedBy not implemented")
+}
+func (UnimplementedUserServiceServer) GetPresences(context.Context, *GetPresencesRequest) (*GetPresencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPresences not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
 
@@ -107,6 +142,42 @@ func _UserService_GetUserInfos_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_GetBlockedBy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockedByRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetBlockedBy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetBlockedBy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetBlockedBy(ctx, req.(*GetBlockedByRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetPresences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPresencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetPresences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetPresences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetPresences(ctx, req.(*GetPresencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -118,6 +189,14 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUserInfos",
 			Handler:    _UserService_GetUserInfos_Handler,
 		},
+		{
+			MethodName: "GetBlockedBy",
+			Handler:    _UserService_GetBlockedBy_Handler,
+		},
+		{
+			MethodName: "GetPresences",
+			Handler:    _UserService_GetPresences_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/user/user.proto",
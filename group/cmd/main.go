@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
 
@@ -8,8 +9,10 @@ import (
 	"github.com/AdventureDe/LinkIM/group/config"
 	"github.com/AdventureDe/LinkIM/group/handler"
 	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
 	"github.com/AdventureDe/LinkIM/group/router"
 	"github.com/AdventureDe/LinkIM/group/service"
+	"github.com/IBM/sarama"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
@@ -49,24 +52,48 @@ func main() {
 	}
 	defer m.Close()
 
-	// 5. 初始化 HTTP 服务
+	// 5. 初始化 Kafka 同步生产者，群事件从发件箱表经它发给消息服务，收到确认后才删除
+	producerConfig := sarama.NewConfig()
+	producerConfig.Producer.RequiredAcks = sarama.WaitForAll
+	producerConfig.Producer.Return.Successes = true
+	kafkaProducer, err := sarama.NewSyncProducer([]string{cfg.KafkaHost}, producerConfig)
+	if err != nil {
+		log.Fatalf("Fail to initialize Kafka Producer:%v", err)
+	}
+	defer kafkaProducer.Close()
+
+	// 6. 初始化 HTTP 服务
 	r := gin.Default()
 	r.Use(cors.New(config.CorsConfig))
+	// 生成的九宫格群头像
+	r.Static("/static/group_avatar", cfg.AvatarDir)
 
-	// 6. 初始化核心架构层
-	groupRepo := repo.NewGroupRepo(db, m)
+	// 7. 初始化核心架构层
+	groupRepo := repo.NewGroupRepo(db, m, repo.TierCaps{
+		model.TierStandard: cfg.StandardGroupCap,
+		model.TierLarge:    cfg.LargeGroupCap,
+		model.TierSuper:    cfg.SuperGroupCap,
+	})
 	groupRedis := repo.NewGroupRedis(rdb)
-	groupService := service.NewGroupService(groupRepo, groupRedis)
+	groupEvents := repo.NewGroupEventPublisher(db, kafkaProducer)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go groupEvents.Run(relayCtx)
+	avatarStore, err := repo.NewLocalAvatarStore(cfg.AvatarDir, cfg.AvatarBaseURL)
+	if err != nil {
+		log.Fatalf("Fail to initialize avatar store:%v", err)
+	}
+	groupService := service.NewGroupService(groupRepo, groupRedis, groupEvents, avatarStore, cfg.AvatarFetchHosts)
 	groupHandler := handler.NewGroupHandler(groupService)
 	router.SetGroupRouter(r, groupHandler)
 
-	// 7. 初始化并注册 gRPC 服务端
+	// 8. 初始化并注册 gRPC 服务端
 	grpcServer := grpc.NewServer()
-	groupServer := repo.NewGroupServiceServer(groupRepo)
+	groupServer := repo.NewGroupServiceServer(groupRepo, groupService)
 	grouppb.RegisterGroupServiceServer(grpcServer, groupServer)
 	reflection.Register(grpcServer)
 
-	// 8. 并发启动 gRPC 服务器
+	// 9. 并发启动 gRPC 服务器
 	go func() {
 		log.Println("GroupService gRPC listening on :50053")
 		if err := grpcServer.Serve(lis); err != nil {
@@ -74,7 +101,7 @@ func main() {
 		}
 	}()
 
-	// 9. 启动 HTTP 服务
+	// 10. 启动 HTTP 服务
 	log.Printf("Group service started at http://0.0.0.0:%d", cfg.Port)
 	if err := r.Run(cfg.Addr()); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 )
@@ -12,7 +13,16 @@ type Config struct {
 	DBHost          string // 数据库地址
 	RedisHost       string // Redis地址
 	UserServiceAddr string // 新增：User 服务 gRPC 地址！(刚才结构体里漏了这行)
-	// KafkaHost    string // 如果 group 暂未用到 Kafka，这行可以注释掉或删掉
+	KafkaHost       string // Kafka地址，群事件通过它发给消息服务
+
+	// 各等级群的人数上限
+	StandardGroupCap int
+	LargeGroupCap    int
+	SuperGroupCap    int
+
+	AvatarDir        string   // 生成的群头像保存目录
+	AvatarBaseURL    string   // 群头像对外访问的地址前缀
+	AvatarFetchHosts []string // 生成九宫格时允许下载成员头像的主机（上传存储的域名），为空时不下载
 }
 
 var CorsConfig = cors.Config{
@@ -31,6 +41,26 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// 逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func Load() *Config {
 	port := 10009 // Group 服务的默认端口
 	// 允许通过环境变量修改端口
@@ -46,6 +76,15 @@ func Load() *Config {
 		DBHost:          getEnv("DB_HOST", "localhost"),
 		RedisHost:       getEnv("REDIS_HOST", "localhost"),
 		UserServiceAddr: getEnv("USER_HOST", "localhost:50051"), // 指向 User 服务的 gRPC 端口
+		KafkaHost:       getEnv("KAFKA_HOST", "localhost:19092"),
+
+		StandardGroupCap: getEnvInt("GROUP_CAP_STANDARD", 500),
+		LargeGroupCap:    getEnvInt("GROUP_CAP_LARGE", 2000),
+		SuperGroupCap:    getEnvInt("GROUP_CAP_SUPER", 10000),
+
+		AvatarDir:        getEnv("GROUP_AVATAR_DIR", "./data/group_avatar"),
+		AvatarBaseURL:    getEnv("GROUP_AVATAR_BASE_URL", "http://localhost:10009/static/group_avatar"),
+		AvatarFetchHosts: getEnvList("GROUP_AVATAR_FETCH_HOSTS"),
 	}
 }
 
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *GroupHandler) PostAnnouncement(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Content    string    `json:"content"`
+		Pinned     bool      `json:"pinned"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	a, err := h.service.PostAnnouncement(c.Request.Context(), input.GroupID, input.ExecutorID, input.Content, input.Pinned)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "post announcement ok",
+		"detail":  a,
+	})
+}
+
+func (h *GroupHandler) EditAnnouncement(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64  `json:"announcement_id"`
+		ExecutorID     int64  `json:"executor_id"`
+		Content        string `json:"content"`
+		Platform       int    `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	a, err := h.service.EditAnnouncement(c.Request.Context(), input.AnnouncementID, input.ExecutorID, input.Content)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "edit announcement ok",
+		"detail":  a,
+	})
+}
+
+func (h *GroupHandler) DeleteAnnouncement(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64 `json:"announcement_id"`
+		ExecutorID     int64 `json:"executor_id"`
+		Platform       int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.DeleteAnnouncement(c.Request.Context(), input.AnnouncementID, input.ExecutorID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "delete announcement ok",
+	})
+}
+
+func (h *GroupHandler) SetAnnouncementPinned(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64 `json:"announcement_id"`
+		ExecutorID     int64 `json:"executor_id"`
+		Pinned         bool  `json:"pinned"`
+		Platform       int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetAnnouncementPinned(c.Request.Context(), input.AnnouncementID, input.ExecutorID, input.Pinned); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set announcement pinned ok",
+	})
+}
+
+func (h *GroupHandler) ListAnnouncements(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		UserID   int64     `json:"user_id"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	list, err := h.service.ListAnnouncements(c.Request.Context(), input.GroupID, input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "list announcements ok",
+		"detail":  list,
+	})
+}
+
+func (h *GroupHandler) ListAnnouncementRevisions(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64 `json:"announcement_id"`
+		UserID         int64 `json:"user_id"`
+		Platform       int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	list, err := h.service.ListAnnouncementRevisions(c.Request.Context(), input.AnnouncementID, input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "list announcement revisions ok",
+		"detail":  list,
+	})
+}
+
+func (h *GroupHandler) ConfirmAnnouncement(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64 `json:"announcement_id"`
+		UserID         int64 `json:"user_id"`
+		Platform       int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.ConfirmAnnouncement(c.Request.Context(), input.AnnouncementID, input.UserID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "confirm announcement ok",
+	})
+}
+
+func (h *GroupHandler) GetAnnouncementStats(c *gin.Context) {
+	var input struct {
+		AnnouncementID int64 `json:"announcement_id"`
+		ExecutorID     int64 `json:"executor_id"`
+		Platform       int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	stats, err := h.service.GetAnnouncementStats(c.Request.Context(), input.AnnouncementID, input.ExecutorID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get announcement stats ok",
+		"detail":  stats,
+	})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"time"
+
 	"github.com/AdventureDe/LinkIM/group/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -44,23 +46,27 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 
 func (h *GroupHandler) AddGroupMember(c *gin.Context) {
 	var input struct {
-		GroupID  uuid.UUID `json:"group_id"`
-		UserIDs  []int64   `json:"user_ids"`
-		Platform int       `json:"platform"`
+		GroupID   uuid.UUID `json:"group_id"`
+		InviterID int64     `json:"inviter_id"`
+		UserIDs   []int64   `json:"user_ids"`
+		Platform  int       `json:"platform"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
 		return
 	}
-	if err := h.service.AddGroupMember(c.Request.Context(), input.GroupID, input.UserIDs); err != nil {
+	result, err := h.service.AddGroupMember(c.Request.Context(), input.GroupID, input.InviterID, input.UserIDs)
+	if err != nil {
 		c.JSON(502, gin.H{
 			"code":  1,
 			"error": err.Error(),
 		})
+		return
 	}
 	c.JSON(200, gin.H{
 		"code":    0,
 		"message": "invite successfullu",
+		"detail":  result,
 	})
 }
 
@@ -256,6 +262,27 @@ func (h *GroupHandler) GetGroupAvatar(c *gin.Context) {
 	})
 }
 
+func (h *GroupHandler) SetGroupAvatar(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		AvatarURL  string    `json:"avatar_url"` // 为空时恢复自动生成的九宫格头像
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetGroupAvatar(c.Request.Context(), input.GroupID, input.ExecutorID, input.AvatarURL); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set group avatar ok",
+	})
+}
+
 func (h *GroupHandler) UpdateSelfName(c *gin.Context) {
 	var input struct {
 		GroupID  uuid.UUID `json:"group_id"`
@@ -277,3 +304,113 @@ func (h *GroupHandler) UpdateSelfName(c *gin.Context) {
 		"userid":  input.UserID,
 	})
 }
+
+func (h *GroupHandler) SetHistoryVisible(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Visible    bool      `json:"visible"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetHistoryVisible(c.Request.Context(), input.GroupID, input.ExecutorID, input.Visible); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set history visible ok",
+	})
+}
+
+func (h *GroupHandler) GetGroupCapacity(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	capacity, err := h.service.GetGroupCapacity(c.Request.Context(), input.GroupID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get group capacity ok",
+		"detail":  capacity,
+	})
+}
+
+func (h *GroupHandler) SetGroupTier(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Tier       string    `json:"tier"`
+		MemberCap  int       `json:"member_cap"` // 0 表示使用等级的默认上限
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetGroupTier(c.Request.Context(), input.GroupID, input.ExecutorID, input.Tier, input.MemberCap); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set group tier ok",
+	})
+}
+
+func (h *GroupHandler) SetGroupMuted(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Muted      bool      `json:"muted"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetGroupMuted(c.Request.Context(), input.GroupID, input.ExecutorID, input.Muted); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set group muted ok",
+	})
+}
+
+// seconds 为 0 表示解除禁言
+func (h *GroupHandler) MuteMember(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		UserID     int64     `json:"user_id"`
+		Seconds    int64     `json:"seconds"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.MuteMember(c.Request.Context(), input.GroupID, input.ExecutorID, input.UserID,
+		time.Duration(input.Seconds)*time.Second); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "mute member ok",
+		"userid":  input.UserID,
+	})
+}
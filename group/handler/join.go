@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *GroupHandler) ApplyToJoin(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		UserID   int64     `json:"user_id"`
+		Answer   string    `json:"answer"`
+		Message  string    `json:"message"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	joined, err := h.service.ApplyToJoin(c.Request.Context(), input.GroupID, input.UserID, input.Answer, input.Message)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "apply to join ok",
+		"joined":  joined,
+	})
+}
+
+func (h *GroupHandler) GetJoinSettings(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	settings, err := h.service.GetJoinSettings(c.Request.Context(), input.GroupID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":     0,
+		"message":  "get join settings ok",
+		"policy":   settings.Policy,
+		"question": settings.Question,
+	})
+}
+
+func (h *GroupHandler) SetJoinPolicy(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Policy     string    `json:"policy"`
+		Question   string    `json:"question"`
+		Answer     string    `json:"answer"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetJoinPolicy(c.Request.Context(), input.GroupID, input.ExecutorID,
+		input.Policy, input.Question, input.Answer); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set join policy ok",
+	})
+}
+
+func (h *GroupHandler) ListJoinRequests(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	list, err := h.service.ListJoinRequests(c.Request.Context(), input.GroupID, input.ExecutorID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "list join requests ok",
+		"detail":  list,
+	})
+}
+
+func (h *GroupHandler) ReviewJoinRequest(c *gin.Context) {
+	var input struct {
+		RequestID  int64 `json:"request_id"`
+		ExecutorID int64 `json:"executor_id"`
+		Approve    bool  `json:"approve"`
+		Platform   int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	req, err := h.service.ReviewJoinRequest(c.Request.Context(), input.RequestID, input.ExecutorID, input.Approve)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "review join request ok",
+		"detail":  req,
+	})
+}
+
+func (h *GroupHandler) CreateInviteLink(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		TTLSeconds int64     `json:"ttl_seconds"` // 0 表示不过期
+		MaxUses    int       `json:"max_uses"`    // 0 表示不限次数
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	link, err := h.service.CreateInviteLink(c.Request.Context(), input.GroupID, input.ExecutorID,
+		time.Duration(input.TTLSeconds)*time.Second, input.MaxUses)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "create invite link ok",
+		"detail":  link,
+	})
+}
+
+func (h *GroupHandler) RevokeInviteLink(c *gin.Context) {
+	var input struct {
+		Token      string `json:"token"`
+		ExecutorID int64  `json:"executor_id"`
+		Platform   int    `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.RevokeInviteLink(c.Request.Context(), input.Token, input.ExecutorID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "revoke invite link ok",
+	})
+}
+
+func (h *GroupHandler) JoinByInviteLink(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token"`
+		UserID   int64  `json:"user_id"`
+		Platform int    `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	groupID, err := h.service.JoinByInviteLink(c.Request.Context(), input.Token, input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "join by invite link ok",
+		"groupID": groupID,
+	})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *GroupHandler) LeaveGroup(c *gin.Context) {
+	var input struct {
+		GroupID      uuid.UUID `json:"group_id"`
+		UserID       int64     `json:"user_id"`
+		AutoTransfer bool      `json:"auto_transfer"` // 群主退群时自动转让群主
+		Platform     int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	newOwnerID, err := h.service.LeaveGroup(c.Request.Context(), input.GroupID, input.UserID, input.AutoTransfer)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":         0,
+		"message":      "leave group ok",
+		"new_owner_id": newOwnerID,
+	})
+}
+
+func (h *GroupHandler) DissolveGroup(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.DissolveGroup(c.Request.Context(), input.GroupID, input.ExecutorID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "dissolve group ok",
+	})
+}
+
+func (h *GroupHandler) SetGroupArchived(c *gin.Context) {
+	var input struct {
+		GroupID    uuid.UUID `json:"group_id"`
+		ExecutorID int64     `json:"executor_id"`
+		Archived   bool      `json:"archived"`
+		Platform   int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetGroupArchived(c.Request.Context(), input.GroupID, input.ExecutorID, input.Archived); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set group archived ok",
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *GroupHandler) GetGroupInfo(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		UserID   int64     `json:"user_id"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	detail, err := h.service.GetGroupDetail(c.Request.Context(), input.GroupID, input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get group info ok",
+		"detail":  detail,
+	})
+}
+
+func (h *GroupHandler) ListGroupMembers(c *gin.Context) {
+	var input struct {
+		GroupID  uuid.UUID `json:"group_id"`
+		UserID   int64     `json:"user_id"`
+		Roles    []string  `json:"roles"`  // 按角色过滤，为空时返回全部成员
+		Cursor   string    `json:"cursor"` // 上一页返回的 next_cursor，第一页为空
+		Limit    int       `json:"limit"`
+		Platform int       `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	page, err := h.service.ListGroupMembers(c.Request.Context(), input.GroupID, input.UserID, input.Roles,
+		input.Cursor, input.Limit)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "list group members ok",
+		"detail":  page,
+	})
+}
+
+func (h *GroupHandler) ListUserGroups(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	groups, err := h.service.ListUserGroups(c.Request.Context(), input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "list user groups ok",
+		"userid":  input.UserID,
+		"detail":  groups,
+	})
+}
@@ -0,0 +1,300 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 一个群最多同时置顶的公告数
+const maxPinnedAnnouncements = 5
+
+var (
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	ErrTooManyPinned        = errors.New("too many pinned announcements")
+	ErrNotGroupMember       = errors.New("user not in group")
+)
+
+// 公告列表中的一项，Confirmed 表示查询的用户是否已确认
+type AnnouncementView struct {
+	ID        int64     `json:"id"`
+	AuthorID  int64     `json:"author_id"`
+	EditorID  *int64    `json:"editor_id"`
+	Content   string    `json:"content"`
+	Pinned    bool      `json:"pinned"`
+	Edited    bool      `json:"edited"`
+	Confirmed bool      `json:"confirmed"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 公告的确认情况，只有群主/管理员可以查看
+type AnnouncementStats struct {
+	AnnouncementID   int64   `json:"announcement_id"`
+	MemberCount      int64   `json:"member_count"`
+	ConfirmedCount   int64   `json:"confirmed_count"`
+	UnconfirmedCount int64   `json:"unconfirmed_count"`
+	ConfirmedUserIDs []int64 `json:"confirmed_user_ids"` // 按确认时间排序
+}
+
+// 锁住公告并检查操作人是群主/管理员，归档和解散的群不能修改公告
+func lockAnnouncement(tx *gorm.DB, announcementID, executorID int64) (*model.GroupAnnouncement, error) {
+	var a model.GroupAnnouncement
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", announcementID).
+		First(&a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+	if err := requireAdmin(tx, a.GroupID, executorID); err != nil {
+		return nil, err
+	}
+	var g model.Group
+	if err := tx.Select("status").Where("id = ?", a.GroupID).First(&g).Error; err != nil {
+		return nil, err
+	}
+	if err := StatusError(g.Status); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// 公告、群资料和成员列表只对群成员可见
+func requireMember(tx *gorm.DB, groupID uuid.UUID, userID int64) error {
+	role, err := memberRole(tx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+func findAnnouncement(tx *gorm.DB, announcementID int64) (*model.GroupAnnouncement, error) {
+	var a model.GroupAnnouncement
+	if err := tx.Where("id = ?", announcementID).First(&a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// 置顶前检查数量上限
+func checkPinnedLimit(tx *gorm.DB, groupID uuid.UUID) error {
+	var pinned int64
+	if err := tx.Model(&model.GroupAnnouncement{}).
+		Where("group_id = ? AND pinned = ?", groupID, true).
+		Count(&pinned).Error; err != nil {
+		return err
+	}
+	if pinned >= maxPinnedAnnouncements {
+		return ErrTooManyPinned
+	}
+	return nil
+}
+
+// Group.Notice 跟随最新一条公告，没有公告时清空
+func syncNotice(tx *gorm.DB, groupID uuid.UUID) error {
+	var contents []string
+	if err := tx.Model(&model.GroupAnnouncement{}).
+		Where("group_id = ?", groupID).
+		Order("created_at DESC, id DESC").
+		Limit(1).
+		Pluck("content", &contents).Error; err != nil {
+		return err
+	}
+	notice := ""
+	if len(contents) > 0 {
+		notice = contents[0]
+	}
+	return tx.Model(&model.Group{}).Where("id = ?", groupID).Update("notice", notice).Error
+}
+
+// 发布公告，只有群主/管理员可以操作
+func (r *groupRepo) CreateAnnouncement(ctx context.Context, a *model.GroupAnnouncement) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireAdmin(tx, a.GroupID, a.AuthorID); err != nil {
+			return err
+		}
+		if a.Pinned {
+			if _, err := lockGroup(tx, a.GroupID); err != nil {
+				return err
+			}
+			if err := checkPinnedLimit(tx, a.GroupID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		return syncNotice(tx, a.GroupID)
+	})
+}
+
+// UpdateAnnouncement 编辑公告，编辑前的内容写进编辑记录；已确认的记录保留
+func (r *groupRepo) UpdateAnnouncement(ctx context.Context, announcementID, editorID int64,
+	content string) (*model.GroupAnnouncement, error) {
+	var a *model.GroupAnnouncement
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = lockAnnouncement(tx, announcementID, editorID); err != nil {
+			return err
+		}
+		if a.Content == content {
+			return nil
+		}
+		if err := tx.Create(&model.GroupAnnouncementRevision{
+			AnnouncementID: a.ID,
+			EditorID:       editorID,
+			Content:        a.Content,
+		}).Error; err != nil {
+			return err
+		}
+		a.Content = content
+		a.EditorID = &editorID
+		if err := tx.Model(a).Select("content", "editor_id").Updates(a).Error; err != nil {
+			return err
+		}
+		return syncNotice(tx, a.GroupID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// 删除公告以及它的编辑记录和确认记录
+func (r *groupRepo) DeleteAnnouncement(ctx context.Context, announcementID, executorID int64) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		a, err := lockAnnouncement(tx, announcementID, executorID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("announcement_id = ?", a.ID).Delete(&model.GroupAnnouncementRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("announcement_id = ?", a.ID).Delete(&model.GroupAnnouncementRead{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(a).Error; err != nil {
+			return err
+		}
+		return syncNotice(tx, a.GroupID)
+	})
+}
+
+// 置顶 / 取消置顶
+func (r *groupRepo) SetAnnouncementPinned(ctx context.Context, announcementID, executorID int64, pinned bool) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		a, err := lockAnnouncement(tx, announcementID, executorID)
+		if err != nil {
+			return err
+		}
+		if a.Pinned == pinned {
+			return nil
+		}
+		if pinned {
+			// 锁住群，避免并发置顶超过上限
+			if _, err := lockGroup(tx, a.GroupID); err != nil {
+				return err
+			}
+			if err := checkPinnedLimit(tx, a.GroupID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(a).UpdateColumn("pinned", pinned).Error
+	})
+}
+
+// ListAnnouncements 群公告列表，置顶的在前，其余按发布时间倒序
+func (r *groupRepo) ListAnnouncements(ctx context.Context, groupID uuid.UUID, userID int64) ([]*AnnouncementView, error) {
+	db := r.conn(ctx)
+	if err := requireMember(db, groupID, userID); err != nil {
+		return nil, err
+	}
+	list := make([]*AnnouncementView, 0)
+	if err := db.Table("group_announcements AS a").
+		Select("a.id, a.author_id, a.editor_id, a.content, a.pinned, a.created_at, a.updated_at, "+
+			"a.editor_id IS NOT NULL AS edited, rd.user_id IS NOT NULL AS confirmed").
+		Joins("LEFT JOIN group_announcement_reads AS rd ON rd.announcement_id = a.id AND rd.user_id = ?", userID).
+		Where("a.group_id = ?", groupID).
+		Order("a.pinned DESC, a.created_at DESC, a.id DESC").
+		Scan(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// 公告的编辑记录，最近的在前，群成员可以查看
+func (r *groupRepo) ListAnnouncementRevisions(ctx context.Context, announcementID, userID int64) ([]*model.GroupAnnouncementRevision, error) {
+	db := r.conn(ctx)
+	a, err := findAnnouncement(db, announcementID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireMember(db, a.GroupID, userID); err != nil {
+		return nil, err
+	}
+	list := make([]*model.GroupAnnouncementRevision, 0)
+	if err := db.Where("announcement_id = ?", announcementID).
+		Order("created_at DESC, id DESC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// 确认已读公告，重复确认不会更新时间
+func (r *groupRepo) ConfirmAnnouncement(ctx context.Context, announcementID, userID int64) error {
+	db := r.conn(ctx)
+	a, err := findAnnouncement(db, announcementID)
+	if err != nil {
+		return err
+	}
+	if err := requireMember(db, a.GroupID, userID); err != nil {
+		return err
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.GroupAnnouncementRead{AnnouncementID: announcementID, UserID: userID}).Error
+}
+
+// GetAnnouncementStats 公告的确认情况，只统计当前还在群里的成员
+func (r *groupRepo) GetAnnouncementStats(ctx context.Context, announcementID, executorID int64) (*AnnouncementStats, error) {
+	db := r.conn(ctx)
+	a, err := findAnnouncement(db, announcementID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAdmin(db, a.GroupID, executorID); err != nil {
+		return nil, err
+	}
+	memberCount, err := countMembers(db, a.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	confirmed := make([]int64, 0)
+	if err := db.Table("group_announcement_reads AS rd").
+		Joins("JOIN group_members AS m ON m.group_id = ? AND m.user_id = rd.user_id", a.GroupID).
+		Where("rd.announcement_id = ?", announcementID).
+		Order("rd.read_at ASC").
+		Pluck("rd.user_id", &confirmed).Error; err != nil {
+		return nil, err
+	}
+	return &AnnouncementStats{
+		AnnouncementID:   announcementID,
+		MemberCount:      memberCount,
+		ConfirmedCount:   int64(len(confirmed)),
+		UnconfirmedCount: memberCount - int64(len(confirmed)),
+		ConfirmedUserIDs: confirmed,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 群头像状态
+type AvatarState struct {
+	Avatar       string
+	AvatarCustom bool
+	AvatarKey    string
+	Status       model.GroupStatus
+}
+
+func (r *groupRepo) GetAvatarState(ctx context.Context, groupID uuid.UUID) (*AvatarState, error) {
+	var g model.Group
+	if err := r.conn(ctx).
+		Select("avatar", "avatar_custom", "avatar_key", "status").
+		Where("id = ?", groupID).
+		First(&g).Error; err != nil {
+		return nil, err
+	}
+	return &AvatarState{
+		Avatar:       g.Avatar,
+		AvatarCustom: g.AvatarCustom,
+		AvatarKey:    g.AvatarKey,
+		Status:       g.Status,
+	}, nil
+}
+
+// 保存生成的九宫格头像，期间被设置了自定义头像时不覆盖
+func (r *groupRepo) SetGeneratedAvatar(ctx context.Context, groupID uuid.UUID, url, key string) error {
+	return r.conn(ctx).Model(&model.Group{}).
+		Where("id = ? AND avatar_custom = ?", groupID, false).
+		Updates(map[string]interface{}{
+			"avatar":     url,
+			"avatar_key": key,
+		}).Error
+}
+
+// SetCustomAvatar 设置自定义头像，只有群主/管理员可以操作；url 为空时恢复自动生成
+func (r *groupRepo) SetCustomAvatar(ctx context.Context, groupID uuid.UUID, executorID int64, url string) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireAdmin(tx, groupID, executorID); err != nil {
+			return err
+		}
+		return tx.Model(&model.Group{}).
+			Where("id = ?", groupID).
+			Updates(map[string]interface{}{
+				"avatar":        url,
+				"avatar_custom": url != "",
+				"avatar_key":    "",
+			}).Error
+	})
+}
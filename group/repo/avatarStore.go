@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 群头像文件存储，Save 返回客户端可以访问的地址
+type AvatarStore interface {
+	Save(ctx context.Context, name string, data []byte) (url string, err error)
+	// Delete 删除不再使用的头像，文件不存在时不报错
+	Delete(ctx context.Context, name string) error
+}
+
+// 存在本地目录，由 HTTP 服务以静态文件的方式对外提供
+type localAvatarStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalAvatarStore(dir, baseURL string) (AvatarStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &localAvatarStore{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (s *localAvatarStore) Save(ctx context.Context, name string, data []byte) (string, error) {
+	// 先写临时文件再改名，避免客户端读到写了一半的图片
+	path := filepath.Join(s.dir, filepath.Base(name))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return s.baseURL + "/" + filepath.Base(name), nil
+}
+
+func (s *localAvatarStore) Delete(ctx context.Context, name string) error {
+	err := os.Remove(filepath.Join(s.dir, filepath.Base(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
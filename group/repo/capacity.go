@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 加入后会超过群人数上限
+var ErrGroupFull = errors.New("group member limit reached")
+
+// 各等级群的默认人数上限
+type TierCaps map[model.GroupTier]int
+
+// 群容量
+type GroupCapacity struct {
+	Tier        model.GroupTier `json:"tier"`
+	MemberCap   int             `json:"member_cap"`
+	MemberCount int64           `json:"member_count"`
+	LargeGroup  bool            `json:"large_group"`
+}
+
+// 群实际的人数上限：自定义上限不能超过等级上限
+func (r *groupRepo) capOf(g *model.Group) int {
+	limit := r.caps[g.Tier]
+	if g.MemberCap > 0 && g.MemberCap < limit {
+		return g.MemberCap
+	}
+	return limit
+}
+
+func countMembers(tx *gorm.DB, groupID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&model.GroupMember{}).Where("group_id = ?", groupID).Count(&count).Error
+	return count, err
+}
+
+func (r *groupRepo) GetGroupCapacity(ctx context.Context, groupID uuid.UUID) (*GroupCapacity, error) {
+	db := r.conn(ctx)
+	var g model.Group
+	if err := db.Select("id", "tier", "member_cap").Where("id = ?", groupID).First(&g).Error; err != nil {
+		return nil, err
+	}
+	count, err := countMembers(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupCapacity{
+		Tier:        g.Tier,
+		MemberCap:   r.capOf(&g),
+		MemberCount: count,
+		LargeGroup:  g.Tier.IsLarge(),
+	}, nil
+}
+
+// SetGroupTier 修改群等级和自定义人数上限，只有群主可以操作；当前人数不能超过新的上限
+func (r *groupRepo) SetGroupTier(ctx context.Context, groupID uuid.UUID, executorID int64, tier model.GroupTier, memberCap int) error {
+	limit, ok := r.caps[tier]
+	if !ok {
+		return fmt.Errorf("invalid group tier")
+	}
+	if memberCap < 0 || memberCap > limit {
+		return fmt.Errorf("member cap must be between 0 and %d", limit)
+	}
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var g model.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", groupID).
+			First(&g).Error; err != nil {
+			return err
+		}
+		if g.OwnerID != executorID {
+			return fmt.Errorf("only group owner can change group tier")
+		}
+		g.Tier, g.MemberCap = tier, memberCap
+		count, err := countMembers(tx, groupID)
+		if err != nil {
+			return err
+		}
+		if count > int64(r.capOf(&g)) {
+			return fmt.Errorf("group already has %d members", count)
+		}
+		return tx.Model(&model.Group{}).
+			Where("id = ?", groupID).
+			Updates(map[string]interface{}{
+				"tier":       tier,
+				"member_cap": memberCap,
+			}).Error
+	})
+}
+
+// 批量加入普通成员，已经在群里的跳过，返回实际加入的成员；
+// 锁住群记录保证并发加入时不会超过人数上限
+func (r *groupRepo) insertMembers(tx *gorm.DB, groupID uuid.UUID, userIDs []int64, joinSeq int64) ([]int64, error) {
+	var g model.Group
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status", "tier", "member_cap").
+		Where("id = ?", groupID).
+		First(&g).Error; err != nil {
+		return nil, err
+	}
+	if err := StatusError(g.Status); err != nil {
+		return nil, err
+	}
+
+	var existing []int64
+	if err := tx.Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id IN ?", groupID, userIDs).
+		Pluck("user_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	skip := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		skip[id] = true
+	}
+	added := make([]int64, 0, len(userIDs))
+	members := make([]model.GroupMember, 0, len(userIDs))
+	for _, id := range userIDs {
+		if skip[id] {
+			continue
+		}
+		skip[id] = true
+		added = append(added, id)
+		members = append(members, model.GroupMember{
+			GroupID: groupID,
+			UserID:  id,
+			Role:    model.Member,
+			IsOwner: false,
+			JoinSeq: joinSeq,
+		})
+	}
+	if len(members) == 0 {
+		return added, nil
+	}
+	if err := tx.CreateInBatches(&members, 100).Error; err != nil {
+		return nil, err
+	}
+
+	count, err := countMembers(tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if count > int64(r.capOf(&g)) {
+		return nil, ErrGroupFull
+	}
+	return added, nil
+}
@@ -31,6 +31,31 @@ type UserInfo struct {
 	Avatar   string `json:"avatar"`
 }
 
+// 成员在群里的状态，消息服务用来做权限校验和历史记录过滤
+type MemberState struct {
+	Role        model.GroupRole `json:"role"`
+	JoinSeq     int64           `json:"join_seq"`
+	FullHistory bool            `json:"full_history"`
+	GroupMuted  bool            `json:"group_muted"`
+	MutedUntil  *time.Time      `json:"muted_until"`
+	Archived    bool            `json:"archived"`
+	LargeGroup  bool            `json:"large_group"`
+}
+
+// 用户在一个群里可见的历史范围，消息服务搜索时按群过滤
+type MemberScope struct {
+	GroupID     uuid.UUID `json:"group_id"`
+	JoinSeq     int64     `json:"join_seq"`
+	FullHistory bool      `json:"full_history"`
+}
+
+// 群解散前的成员和入群位置，解散后消息服务据此继续提供只读历史
+type FormerMember struct {
+	UserID      int64 `json:"user_id"`
+	JoinSeq     int64 `json:"join_seq"`
+	FullHistory bool  `json:"full_history"`
+}
+
 type GroupInfo struct {
 	GroupID   uuid.UUID `gorm:"column:id"`
 	GroupName string    `gorm:"column:name"`
@@ -38,8 +63,9 @@ type GroupInfo struct {
 }
 
 type GroupRepo interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 	CreateGroup(ctx context.Context, ownerID int64, userIDs []int64, groupName string) (groupID uuid.UUID, err error)
-	AddGroupMember(ctx context.Context, groupID uuid.UUID, userIDs []int64) error // 可进一步拓展
+	AddGroupMember(ctx context.Context, groupID uuid.UUID, userIDs []int64, joinSeq int64) ([]int64, error) // 可进一步拓展
 	KickOutGroupMember(ctx context.Context, groupID uuid.UUID, executorID int64, userIDs []int64) error
 	PromoteToAdmin(ctx context.Context, groupID uuid.UUID, executorID int64, userID int64) error
 	TransferGroupOwner(ctx context.Context, groupID uuid.UUID, executorID int64, userID int64) error
@@ -52,25 +78,96 @@ type GroupRepo interface {
 	GetGroupInfos(ctx context.Context, groupID uuid.UUIDs) ([]*GroupInfo, error)
 	UpdateSelfName(ctx context.Context, groupID uuid.UUID, userID int64, newName string) error
 	GetGroupMembers(ctx context.Context, groupID uuid.UUID) ([]GroupMember, error)
+	GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (*MemberState, error)
+	ListMemberScopes(ctx context.Context, userID int64) ([]*MemberScope, error)
+	SetHistoryVisible(ctx context.Context, groupID uuid.UUID, executorID int64, visible bool) error
+	SetGroupMuted(ctx context.Context, groupID uuid.UUID, executorID int64, muted bool) error
+	MuteMember(ctx context.Context, groupID uuid.UUID, executorID int64, userID int64, until *time.Time) error
+	// 入群方式、审批和邀请链接
+	GetJoinSettings(ctx context.Context, groupID uuid.UUID) (*JoinSettings, error)
+	SetJoinPolicy(ctx context.Context, groupID uuid.UUID, executorID int64, settings *JoinSettings) error
+	CreateJoinRequests(ctx context.Context, groupID uuid.UUID, inviterID *int64, userIDs []int64,
+		message string) ([]*model.GroupJoinRequest, error)
+	ListJoinRequests(ctx context.Context, groupID uuid.UUID, executorID int64) ([]*model.GroupJoinRequest, error)
+	ReviewJoinRequest(ctx context.Context, requestID, reviewerID int64, approve bool,
+		joinSeq func(groupID uuid.UUID) (int64, error)) (*model.GroupJoinRequest, []int64, error)
+	CreateInviteLink(ctx context.Context, link *model.GroupInviteLink) error
+	RevokeInviteLink(ctx context.Context, token string, executorID int64) error
+	UseInviteLink(ctx context.Context, token string, userID int64,
+		joinSeq func(groupID uuid.UUID) (int64, error)) (groupID uuid.UUID, joined bool, err error)
+	// 群等级和人数上限
+	GetGroupCapacity(ctx context.Context, groupID uuid.UUID) (*GroupCapacity, error)
+	SetGroupTier(ctx context.Context, groupID uuid.UUID, executorID int64, tier model.GroupTier, memberCap int) error
+	// 退群、解散和归档
+	GetGroupStatus(ctx context.Context, groupID uuid.UUID) (model.GroupStatus, error)
+	LeaveGroup(ctx context.Context, groupID uuid.UUID, userID int64, autoTransfer bool) (newOwnerID int64, err error)
+	DissolveGroup(ctx context.Context, groupID uuid.UUID, executorID int64) ([]*FormerMember, error)
+	SetGroupArchived(ctx context.Context, groupID uuid.UUID, executorID int64, archived bool) error
+	// 群头像
+	GetAvatarState(ctx context.Context, groupID uuid.UUID) (*AvatarState, error)
+	SetGeneratedAvatar(ctx context.Context, groupID uuid.UUID, url, key string) error
+	SetCustomAvatar(ctx context.Context, groupID uuid.UUID, executorID int64, url string) error
+	// 群公告
+	CreateAnnouncement(ctx context.Context, a *model.GroupAnnouncement) error
+	UpdateAnnouncement(ctx context.Context, announcementID, editorID int64, content string) (*model.GroupAnnouncement, error)
+	DeleteAnnouncement(ctx context.Context, announcementID, executorID int64) error
+	SetAnnouncementPinned(ctx context.Context, announcementID, executorID int64, pinned bool) error
+	ListAnnouncements(ctx context.Context, groupID uuid.UUID, userID int64) ([]*AnnouncementView, error)
+	ListAnnouncementRevisions(ctx context.Context, announcementID, userID int64) ([]*model.GroupAnnouncementRevision, error)
+	ConfirmAnnouncement(ctx context.Context, announcementID, userID int64) error
+	GetAnnouncementStats(ctx context.Context, announcementID, executorID int64) (*AnnouncementStats, error)
+	// 群资料、成员分页和我的群
+	GetGroupDetail(ctx context.Context, groupID uuid.UUID, userID int64) (*GroupDetail, error)
+	ListMembersPage(ctx context.Context, groupID uuid.UUID, userID int64, roles []model.GroupRole,
+		cursor string, limit int) (*MemberPage, error)
+	ListUserGroups(ctx context.Context, userID int64) ([]*UserGroup, error)
 }
 
 type groupRepo struct {
 	db         *gorm.DB
 	userClient userpb.UserServiceClient
+	caps       TierCaps
 }
 
-func NewGroupRepo(db *gorm.DB, m *groupService) GroupRepo {
+// 事务放在 ctx 里传给仓储方法，同一个 ctx 里的修改和群事件一起提交
+type txKey struct{}
+
+// Transaction 在事务里执行 fn；fn 里用传入的 ctx 调用的仓储方法和 GroupEventPublisher.Publish 都加入这个事务
+func (r *groupRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// ctx 里带着事务时用事务，否则用连接池
+func (r *groupRepo) conn(ctx context.Context) *gorm.DB {
+	return dbFromContext(ctx, r.db)
+}
+
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+func NewGroupRepo(db *gorm.DB, m *groupService, caps TierCaps) GroupRepo {
 	return &groupRepo{
 		db:         db,
 		userClient: m.userClient,
+		caps:       caps,
 	}
 }
 
 func (r *groupRepo) CreateGroup(ctx context.Context, ownerID int64, userIDs []int64, groupName string) (groupID uuid.UUID, err error) {
-	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		if len(userIDs) <= 1 {
 			return fmt.Errorf("Number of people is less than three")
 		}
+		// 新建的群都是普通群，更大的群需要群主再升级
+		if len(userIDs)+1 > r.caps[model.TierStandard] {
+			return ErrGroupFull
+		}
 		// 创建群组
 		group := model.Group{
 			Name:    groupName,
@@ -117,26 +214,17 @@ func (r *groupRepo) CreateGroup(ctx context.Context, ownerID int64, userIDs []in
 	return
 }
 
-func (r *groupRepo) AddGroupMember(ctx context.Context, groupid uuid.UUID, userIDs []int64) error {
+// joinSeq 是入群时群消息的 seq，新成员默认只能看到之后的消息；已经在群里的用户会被跳过，
+// 返回实际加入的成员
+func (r *groupRepo) AddGroupMember(ctx context.Context, groupid uuid.UUID, userIDs []int64, joinSeq int64) (added []int64, err error) {
 	if len(userIDs) == 0 {
-		return fmt.Errorf("fail to add groupMember, num == 0")
-	}
-	var members []model.GroupMember
-	for _, id := range userIDs {
-		members = append(members, model.GroupMember{
-			GroupID: groupid,
-			UserID:  id,
-			Role:    model.Member,
-			IsOwner: false,
-		})
+		return nil, fmt.Errorf("fail to add groupMember, num == 0")
 	}
-	// 避免空指针
-	if len(members) > 0 {
-		if err := r.db.WithContext(ctx).CreateInBatches(&members, 100).Error; err != nil {
-			return err
-		}
-	}
-	return nil
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		added, err = r.insertMembers(tx, groupid, userIDs, joinSeq)
+		return err
+	})
+	return
 }
 
 func (r *groupRepo) KickOutGroupMember(ctx context.Context, groupid uuid.UUID, executorid int64,
@@ -145,7 +233,7 @@ func (r *groupRepo) KickOutGroupMember(ctx context.Context, groupid uuid.UUID, e
 		return fmt.Errorf("fail to kick out groupMember, num == 0")
 	}
 
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		// 查询执行者的角色
 		var executor model.GroupMember
 		if err := tx.Select("role").
@@ -194,7 +282,7 @@ func (r *groupRepo) KickOutGroupMember(ctx context.Context, groupid uuid.UUID, e
 }
 
 func (r *groupRepo) PromoteToAdmin(ctx context.Context, groupid uuid.UUID, executorid int64, userid int64) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		var target []model.GroupMember
 		if err := tx.Select("user_id", "role").Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("group_id = ? AND user_id IN ?", groupid, []int64{executorid, userid}).
@@ -232,7 +320,7 @@ func (r *groupRepo) PromoteToAdmin(ctx context.Context, groupid uuid.UUID, execu
 
 func (r *groupRepo) TransferGroupOwner(ctx context.Context, groupid uuid.UUID,
 	executorid int64, userid int64) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		// 查询执行者，并加行锁
 		var executor model.GroupMember
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}). //Clauses 用来给 SQL 语句增加一些额外的 SQL 子句。
@@ -302,7 +390,7 @@ func (r *groupRepo) TransferGroupOwner(ctx context.Context, groupid uuid.UUID,
 
 // 撤销管理员
 func (r *groupRepo) DemotedToMember(ctx context.Context, groupid uuid.UUID, executorid int64, userid int64) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		var target []model.GroupMember
 		if err := tx.Select("user_id", "role").Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("group_id = ? AND user_id IN ?", groupid, []int64{executorid, userid}).
@@ -341,7 +429,7 @@ func (r *groupRepo) DemotedToMember(ctx context.Context, groupid uuid.UUID, exec
 
 func (r *groupRepo) UpdateNotice(ctx context.Context, groupid uuid.UUID, executorid int64,
 	newnoticetext string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		var executor model.GroupMember
 		if err := tx.Select("role").Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("group_id = ? AND user_id = ?", groupid, executorid).
@@ -370,7 +458,7 @@ func (r *groupRepo) UpdateNotice(ctx context.Context, groupid uuid.UUID, executo
 
 func (r *groupRepo) GetNotice(ctx context.Context, groupid uuid.UUID) (string, error) {
 	var g model.Group
-	if err := r.db.WithContext(ctx).
+	if err := r.conn(ctx).
 		Select("notice").
 		Where("id = ?", groupid).
 		First(&g).Error; err != nil {
@@ -380,7 +468,7 @@ func (r *groupRepo) GetNotice(ctx context.Context, groupid uuid.UUID) (string, e
 }
 
 func (r *groupRepo) UpdateGroupName(ctx context.Context, groupid uuid.UUID, executorid int64, newgroupname string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
 		var executor model.GroupMember
 		if err := tx.Select("role").Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("group_id = ? AND user_id = ?", groupid, executorid).
@@ -408,7 +496,7 @@ func (r *groupRepo) UpdateGroupName(ctx context.Context, groupid uuid.UUID, exec
 
 func (r *groupRepo) GetGroupName(ctx context.Context, groupid uuid.UUID) (string, error) {
 	var g model.Group
-	if err := r.db.WithContext(ctx).
+	if err := r.conn(ctx).
 		Select("name").
 		Where("id = ?", groupid).
 		First(&g).Error; err != nil {
@@ -419,16 +507,22 @@ func (r *groupRepo) GetGroupName(ctx context.Context, groupid uuid.UUID) (string
 
 func (r *groupRepo) GetGroupAvatar(ctx context.Context, groupid uuid.UUID) (gas *GroupAvatarSet, err error) {
 	var userids []int64
-	err = r.db.WithContext(ctx).Model(&model.GroupMember{}).
+	err = r.conn(ctx).Model(&model.GroupMember{}).
 		Select("user_id").
 		Where("group_id = ?", groupid).
 		Order("join_time ASC").
 		Limit(9).
 		Find(&userids).Error
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
 		UserIds: userids,
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	userMap := make(map[int64]*userpb.UserInfo)
 	for _, u := range resp.Users {
@@ -438,8 +532,10 @@ func (r *groupRepo) GetGroupAvatar(ctx context.Context, groupid uuid.UUID) (gas
 	res := make([]*UserInfo, 0, len(userids))
 	for _, userid := range userids {
 		u, ok := userMap[userid]
+		// 查不到的用户保留位置，头像留空
 		if !ok || u == nil {
-			err = fmt.Errorf("user info not found for userid: %d", userid)
+			res = append(res, &UserInfo{UserID: userid})
+			continue
 		}
 		userinfo := &UserInfo{
 			UserID:   u.UserId,
@@ -462,7 +558,7 @@ func (r *groupRepo) GetGroupInfos(ctx context.Context, groupIDs uuid.UUIDs) ([]*
 		return nil, nil
 	}
 	groupInfos := make([]*GroupInfo, 0, len(groupIDs))
-	if err := r.db.WithContext(ctx).Model(&model.Group{}).
+	if err := r.conn(ctx).Model(&model.Group{}).
 		Select("id,name,avatar").
 		Where("id IN ?", groupIDs).
 		Find(&groupInfos).Error; err != nil {
@@ -476,7 +572,7 @@ func (r *groupRepo) UpdateSelfName(ctx context.Context, groupid uuid.UUID, useri
 		return fmt.Errorf("invalid nickname length")
 	}
 	// 严格检查用户是否存在
-	res := r.db.WithContext(ctx).Model(&model.GroupMember{}).
+	res := r.conn(ctx).Model(&model.GroupMember{}).
 		Where("group_id = ? AND user_id = ?", groupid, userid).
 		Update("nickname", newname)
 	if res.Error != nil {
@@ -492,16 +588,124 @@ var ErrInvalidGroup = errors.New("invalid group")
 
 func (r *groupRepo) GetGroupMembers(ctx context.Context, groupID uuid.UUID) ([]GroupMember, error) {
 	var members []GroupMember
-	if err := r.db.WithContext(ctx).
+	if err := r.conn(ctx).
 		Model(&model.GroupMember{}).
 		Where("group_id = ?", groupID).
 		Find(&members).Error; err != nil {
 		return nil, err
 	}
 
-	if len(members) <= 2 {
+	// 群不存在或已解散
+	if len(members) == 0 {
 		return nil, ErrInvalidGroup
 	}
 
 	return members, nil
 }
+
+// 查询成员状态，不在群里返回 nil
+func (r *groupRepo) GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (*MemberState, error) {
+	var states []MemberState
+	if err := r.conn(ctx).
+		Table("group_members AS m").
+		Select("m.role, m.join_seq, m.muted_until, g.history_visible AS full_history, g.is_banned AS group_muted, "+
+			"g.status = 'archived' AS archived, g.tier IN ? AS large_group", model.LargeTiers).
+		Joins("JOIN groups g ON g.id = m.group_id").
+		Where("m.group_id = ? AND m.user_id = ?", groupID, userID).
+		Limit(1).
+		Scan(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return &states[0], nil
+}
+
+// ListMemberScopes 用户加入的所有群和入群位置，不包含已解散的群
+func (r *groupRepo) ListMemberScopes(ctx context.Context, userID int64) ([]*MemberScope, error) {
+	scopes := make([]*MemberScope, 0)
+	if err := r.conn(ctx).
+		Table("group_members AS m").
+		Select("m.group_id, m.join_seq, g.history_visible AS full_history").
+		Joins("JOIN groups g ON g.id = m.group_id").
+		Where("m.user_id = ? AND g.status <> ?", userID, model.GroupDeleted).
+		Scan(&scopes).Error; err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// 设置新成员是否可以查看入群前的聊天记录，只有群主/管理员可以操作
+func (r *groupRepo) SetHistoryVisible(ctx context.Context, groupID uuid.UUID, executorID int64, visible bool) error {
+	var executor model.GroupMember
+	if err := r.conn(ctx).Select("role").
+		Where("group_id = ? AND user_id = ?", groupID, executorID).
+		First(&executor).Error; err != nil {
+		return fmt.Errorf("executor not in group: %w", err)
+	}
+	if executor.Role != model.Owner && executor.Role != model.Admin {
+		return fmt.Errorf("insufficient permissions")
+	}
+	return r.conn(ctx).Model(&model.Group{}).
+		Where("id = ?", groupID).
+		Update("history_visible", visible).Error
+}
+
+// 全员禁言开关，只有群主/管理员可以操作
+func (r *groupRepo) SetGroupMuted(ctx context.Context, groupID uuid.UUID, executorID int64, muted bool) error {
+	var executor model.GroupMember
+	if err := r.conn(ctx).Select("role").
+		Where("group_id = ? AND user_id = ?", groupID, executorID).
+		First(&executor).Error; err != nil {
+		return fmt.Errorf("executor not in group: %w", err)
+	}
+	if executor.Role != model.Owner && executor.Role != model.Admin {
+		return fmt.Errorf("insufficient permissions")
+	}
+	return r.conn(ctx).Model(&model.Group{}).
+		Where("id = ?", groupID).
+		Update("is_banned", muted).Error
+}
+
+// 禁言成员到 until，until 为 nil 表示解除禁言。
+// 规则和踢人一致：群主可以禁言管理员和普通成员，管理员只能禁言普通成员
+func (r *groupRepo) MuteMember(ctx context.Context, groupID uuid.UUID, executorID int64, userID int64,
+	until *time.Time) error {
+	if executorID == userID {
+		return fmt.Errorf("cannot mute yourself")
+	}
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var target []model.GroupMember
+		if err := tx.Select("user_id", "role").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("group_id = ? AND user_id IN ?", groupID, []int64{executorID, userID}).
+			Find(&target).Error; err != nil {
+			return err
+		}
+		if len(target) != 2 {
+			return fmt.Errorf("either executor or target not in group")
+		}
+		var executorRole, userRole model.GroupRole
+		for _, m := range target {
+			switch m.UserID {
+			case executorID:
+				executorRole = m.Role
+			case userID:
+				userRole = m.Role
+			}
+		}
+		switch executorRole {
+		case model.Owner:
+		case model.Admin:
+			if userRole != model.Member {
+				return fmt.Errorf("admin can only mute members")
+			}
+		default:
+			return fmt.Errorf("insufficient permissions")
+		}
+
+		return tx.Model(&model.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", groupID, userID).
+			Update("muted_until", until).Error
+	})
+}
@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// 群事件的 Kafka topic，消息服务消费后写成系统消息
+const GroupEventTopic = "im_group_event_topic"
+
+const (
+	outboxBatchSize     = 100
+	outboxRelayInterval = time.Second
+	outboxRelayLockKey  = 0x67726f7570657674 // 发件箱转发的 advisory lock，同一时间只有一个实例在发
+)
+
+type GroupEventPublisher interface {
+	// Publish 把群事件写进发件箱，ctx 里带着 GroupRepo.Transaction 的事务时和群的修改一起提交
+	Publish(ctx context.Context, event *grouppb.GroupEvent) error
+	// Notify 事务提交后调用，让 Run 立刻转发，不用等下一轮
+	Notify()
+	// Run 把发件箱里的事件发到 Kafka，直到 ctx 结束
+	Run(ctx context.Context)
+}
+
+// 群事件和群的修改在同一个事务里写进发件箱表，再由 Run 按写入顺序用同步生产者发出，
+// 收到 Kafka 确认后才删除；Kafka 不可用时事件留在表里，下一轮继续发
+type groupEventPublisher struct {
+	db       *gorm.DB
+	producer sarama.SyncProducer
+	notify   chan struct{}
+}
+
+func NewGroupEventPublisher(db *gorm.DB, p sarama.SyncProducer) GroupEventPublisher {
+	return &groupEventPublisher{
+		db:       db,
+		producer: p,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Publish 把群事件写进发件箱，同一个群的事件按群 ID 分区保证顺序
+func (p *groupEventPublisher) Publish(ctx context.Context, event *grouppb.GroupEvent) error {
+	if event.EventId == "" {
+		event.EventId = uuid.NewString()
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+	val, err := proto.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("fail to marshal group event: %w", err)
+	}
+	return dbFromContext(ctx, p.db).
+		Create(&model.GroupEventOutbox{GroupID: event.GroupId, Payload: val}).Error
+}
+
+func (p *groupEventPublisher) Notify() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (p *groupEventPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.notify:
+		}
+		for {
+			sent, err := p.relay(ctx)
+			if err != nil {
+				log.Printf("fail to relay group events: %v", err)
+				break
+			}
+			if sent < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// 发送一批事件。多个实例时只有拿到锁的实例发送，保证同一个群的事件按顺序发出；
+// 一批里部分发送成功时整批会重发，消息服务按 event_id 去重
+func (p *groupEventPublisher) relay(ctx context.Context) (int, error) {
+	sent := 0
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		var rows []*model.GroupEventOutbox
+		if err := tx.Order("id ASC").
+			Limit(outboxBatchSize).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		msgs := make([]*sarama.ProducerMessage, 0, len(rows))
+		ids := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			msgs = append(msgs, &sarama.ProducerMessage{
+				Topic: GroupEventTopic,
+				Key:   sarama.StringEncoder(row.GroupID),
+				Value: sarama.ByteEncoder(row.Payload),
+			})
+			ids = append(ids, row.ID)
+		}
+		if err := p.producer.SendMessages(msgs); err != nil {
+			return err
+		}
+		sent = len(rows)
+		return tx.Where("id IN ?", ids).Delete(&model.GroupEventOutbox{}).Error
+	})
+	return sent, err
+}
@@ -1,8 +1,24 @@
 package repo
 
-import "github.com/go-redis/redis/v8"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// 成员状态缓存的有效期，成员变动时会主动删除，这里只是兜底
+const memberStateTTL = 10 * time.Minute
 
 type GroupRedis interface {
+	GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (state *MemberState, hit bool, err error)
+	SetMemberState(ctx context.Context, groupID uuid.UUID, userID int64, state *MemberState) error
+	DelMemberStates(ctx context.Context, groupID uuid.UUID, userIDs ...int64) error
+	DelGroupMemberStates(ctx context.Context, groupID uuid.UUID) error
+	CurrentSeq(ctx context.Context, groupID uuid.UUID) (int64, error)
 }
 
 type groupRedis struct {
@@ -14,3 +30,89 @@ func NewGroupRedis(r *redis.Client) GroupRedis {
 		redis: r,
 	}
 }
+
+func memberStateKey(groupID uuid.UUID, userID int64) string {
+	return fmt.Sprintf("group:%s:member:%d", groupID.String(), userID)
+}
+
+// 记录这个群缓存了哪些成员状态 key，清整个群时不用扫描整个 keyspace
+func memberStateSetKey(groupID uuid.UUID) string {
+	return fmt.Sprintf("group:%s:member_keys", groupID.String())
+}
+
+// 读取缓存的成员状态，hit 为 true 且 state 为 nil 表示缓存了 "不在群里"
+func (g *groupRedis) GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (*MemberState, bool, error) {
+	val, err := g.redis.Get(ctx, memberStateKey(groupID, userID)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(val) == 0 {
+		return nil, true, nil
+	}
+	var state MemberState
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, false, err
+	}
+	return &state, true, nil
+}
+
+// state 为 nil 时缓存 "不在群里"，避免非成员反复请求打到数据库
+func (g *groupRedis) SetMemberState(ctx context.Context, groupID uuid.UUID, userID int64, state *MemberState) error {
+	var val []byte
+	if state != nil {
+		var err error
+		if val, err = json.Marshal(state); err != nil {
+			return err
+		}
+	}
+	key := memberStateKey(groupID, userID)
+	setKey := memberStateSetKey(groupID)
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, val, memberStateTTL)
+		pipe.SAdd(ctx, setKey, key)
+		pipe.Expire(ctx, setKey, memberStateTTL)
+		return nil
+	})
+	return err
+}
+
+func (g *groupRedis) DelMemberStates(ctx context.Context, groupID uuid.UUID, userIDs ...int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	keys := make([]interface{}, 0, len(userIDs))
+	strKeys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		key := memberStateKey(groupID, id)
+		keys = append(keys, key)
+		strKeys = append(strKeys, key)
+	}
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, strKeys...)
+		pipe.SRem(ctx, memberStateSetKey(groupID), keys...)
+		return nil
+	})
+	return err
+}
+
+// 群设置变化时清掉整个群的成员状态缓存
+func (g *groupRedis) DelGroupMemberStates(ctx context.Context, groupID uuid.UUID) error {
+	setKey := memberStateSetKey(groupID)
+	keys, err := g.redis.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	return g.redis.Del(ctx, append(keys, setKey)...).Err()
+}
+
+// 群消息当前的 seq，由消息服务发送时 INCR，群里还没有消息时为 0
+func (g *groupRedis) CurrentSeq(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	seq, err := g.redis.Get(ctx, fmt.Sprintf("linkim:seq:group:%s", groupID.String())).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return seq, err
+}
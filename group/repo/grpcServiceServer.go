@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	grouppb "github.com/AdventureDe/LinkIM/api/group"
 	"github.com/AdventureDe/LinkIM/group/repo/model"
@@ -21,12 +22,19 @@ import (
 
 type GroupServiceServer struct {
 	grouppb.UnimplementedGroupServiceServer
-	repo GroupRepo
+	repo    GroupRepo
+	members MemberReader
 }
 
-func NewGroupServiceServer(r GroupRepo) *GroupServiceServer {
+// 成员状态查询带缓存，由 service 层实现
+type MemberReader interface {
+	GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (*MemberState, error)
+}
+
+func NewGroupServiceServer(r GroupRepo, m MemberReader) *GroupServiceServer {
 	return &GroupServiceServer{
-		repo: r,
+		repo:    r,
+		members: m,
 	}
 }
 
@@ -47,13 +55,13 @@ func toProtoRole(r model.GroupRole) grouppb.Role {
 func (s *GroupServiceServer) ListGroupMembers(ctx context.Context, req *grouppb.ListGroupMembersRequest,
 ) (*grouppb.ListGroupMembersResponse, error) {
 
-	groupID := req.GetGroupId()
-	if groupID == "" {
-		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	groupID, err := uuid.Parse(req.GetGroupId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "group_id is invalid")
 	}
 
 	// 调用 repo 获取数据
-	members, err := s.repo.GetGroupMembers(ctx, uuid.MustParse(groupID))
+	members, err := s.repo.GetGroupMembers(ctx, groupID)
 	if err != nil {
 		log.Printf("failed to get group members: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to get members")
@@ -71,6 +79,7 @@ func (s *GroupServiceServer) ListGroupMembers(ctx context.Context, req *grouppb.
 	}
 
 	return &grouppb.ListGroupMembersResponse{
+		GroupId: groupID.String(),
 		Members: pbMembers,
 	}, nil
 }
@@ -111,3 +120,69 @@ func (s GroupServiceServer) ListGroupInfos(ctx context.Context, req *grouppb.Lis
 
 	return &grouppb.ListGroupInfosResponse{Groups: pbInfos}, nil
 }
+
+func (s *GroupServiceServer) IsMember(ctx context.Context, req *grouppb.IsMemberRequest) (*grouppb.IsMemberResponse, error) {
+	state, err := s.getMemberState(ctx, req.GetGroupId(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &grouppb.IsMemberResponse{IsMember: state != nil}, nil
+}
+
+func (s *GroupServiceServer) GetMemberRole(ctx context.Context, req *grouppb.GetMemberRoleRequest,
+) (*grouppb.GetMemberRoleResponse, error) {
+	state, err := s.getMemberState(ctx, req.GetGroupId(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return &grouppb.GetMemberRoleResponse{IsMember: false}, nil
+	}
+	res := &grouppb.GetMemberRoleResponse{
+		IsMember:    true,
+		Role:        toProtoRole(state.Role),
+		JoinSeq:     state.JoinSeq,
+		FullHistory: state.FullHistory,
+		GroupMuted:  state.GroupMuted,
+		Archived:    state.Archived,
+		LargeGroup:  state.LargeGroup,
+	}
+	if state.MutedUntil != nil && state.MutedUntil.After(time.Now()) {
+		res.MutedUntil = state.MutedUntil.Unix()
+	}
+	return res, nil
+}
+
+func (s *GroupServiceServer) ListUserGroups(ctx context.Context, req *grouppb.ListUserGroupsRequest,
+) (*grouppb.ListUserGroupsResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is invalid")
+	}
+	scopes, err := s.repo.ListMemberScopes(ctx, req.GetUserId())
+	if err != nil {
+		log.Printf("failed to list user groups: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to list user groups")
+	}
+	pbScopes := make([]*grouppb.MemberScope, 0, len(scopes))
+	for _, sc := range scopes {
+		pbScopes = append(pbScopes, &grouppb.MemberScope{
+			GroupId:     sc.GroupID.String(),
+			JoinSeq:     sc.JoinSeq,
+			FullHistory: sc.FullHistory,
+		})
+	}
+	return &grouppb.ListUserGroupsResponse{Groups: pbScopes}, nil
+}
+
+func (s *GroupServiceServer) getMemberState(ctx context.Context, groupIDStr string, userID int64) (*MemberState, error) {
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil || userID <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "group_id or user_id is invalid")
+	}
+	state, err := s.members.GetMemberState(ctx, groupID, userID)
+	if err != nil {
+		log.Printf("failed to get member state: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to get member state")
+	}
+	return state, nil
+}
@@ -0,0 +1,246 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 邀请链接不存在、已撤销、已过期或次数已用完
+var ErrInviteLinkInvalid = errors.New("invite link is invalid or expired")
+
+// 入群设置
+type JoinSettings struct {
+	Policy   model.JoinPolicy
+	Question string
+	Answer   string
+}
+
+// 查询用户在群里的角色，不在群里返回空字符串
+func memberRole(tx *gorm.DB, groupID uuid.UUID, userID int64) (model.GroupRole, error) {
+	var roles []model.GroupRole
+	if err := tx.Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Limit(1).
+		Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+	if len(roles) == 0 {
+		return "", nil
+	}
+	return roles[0], nil
+}
+
+func requireAdmin(tx *gorm.DB, groupID uuid.UUID, executorID int64) error {
+	role, err := memberRole(tx, groupID, executorID)
+	if err != nil {
+		return err
+	}
+	if role != model.Owner && role != model.Admin {
+		return fmt.Errorf("insufficient permissions")
+	}
+	return nil
+}
+
+func (r *groupRepo) GetJoinSettings(ctx context.Context, groupID uuid.UUID) (*JoinSettings, error) {
+	var g model.Group
+	if err := r.conn(ctx).
+		Select("join_policy", "join_question", "join_answer").
+		Where("id = ?", groupID).
+		First(&g).Error; err != nil {
+		return nil, err
+	}
+	return &JoinSettings{Policy: g.JoinPolicy, Question: g.JoinQuestion, Answer: g.JoinAnswer}, nil
+}
+
+// 修改入群方式，只有群主/管理员可以操作
+func (r *groupRepo) SetJoinPolicy(ctx context.Context, groupID uuid.UUID, executorID int64, settings *JoinSettings) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireAdmin(tx, groupID, executorID); err != nil {
+			return err
+		}
+		return tx.Model(&model.Group{}).
+			Where("id = ?", groupID).
+			Updates(map[string]interface{}{
+				"join_policy":   settings.Policy,
+				"join_question": settings.Question,
+				"join_answer":   settings.Answer,
+			}).Error
+	})
+}
+
+// 创建待审批的入群记录，已经是成员或者已有待审批记录的用户会被跳过
+func (r *groupRepo) CreateJoinRequests(ctx context.Context, groupID uuid.UUID, inviterID *int64, userIDs []int64,
+	message string) ([]*model.GroupJoinRequest, error) {
+	var created []*model.GroupJoinRequest
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var skip []int64
+		if err := tx.Model(&model.GroupMember{}).
+			Where("group_id = ? AND user_id IN ?", groupID, userIDs).
+			Pluck("user_id", &skip).Error; err != nil {
+			return err
+		}
+		var pending []int64
+		if err := tx.Model(&model.GroupJoinRequest{}).
+			Where("group_id = ? AND user_id IN ? AND status = ?", groupID, userIDs, model.JoinPending).
+			Pluck("user_id", &pending).Error; err != nil {
+			return err
+		}
+		skipped := make(map[int64]bool, len(skip)+len(pending))
+		for _, id := range append(skip, pending...) {
+			skipped[id] = true
+		}
+
+		for _, id := range userIDs {
+			if skipped[id] {
+				continue
+			}
+			skipped[id] = true
+			created = append(created, &model.GroupJoinRequest{
+				GroupID:   groupID,
+				UserID:    id,
+				InviterID: inviterID,
+				Message:   message,
+				Status:    model.JoinPending,
+			})
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		return tx.Create(&created).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// 待审批的入群记录，只有群主/管理员可以查看
+func (r *groupRepo) ListJoinRequests(ctx context.Context, groupID uuid.UUID, executorID int64) ([]*model.GroupJoinRequest, error) {
+	db := r.conn(ctx)
+	if err := requireAdmin(db, groupID, executorID); err != nil {
+		return nil, err
+	}
+	var list []*model.GroupJoinRequest
+	if err := db.Where("group_id = ? AND status = ?", groupID, model.JoinPending).
+		Order("created_at ASC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ReviewJoinRequest 审批入群记录，通过时把用户加入群，joinSeq 返回群当前的消息 seq；
+// added 是实际加入的成员，用户已经在群里时为空
+func (r *groupRepo) ReviewJoinRequest(ctx context.Context, requestID, reviewerID int64, approve bool,
+	joinSeq func(groupID uuid.UUID) (int64, error)) (*model.GroupJoinRequest, []int64, error) {
+	var req model.GroupJoinRequest
+	var added []int64
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", requestID, model.JoinPending).
+			First(&req).Error; err != nil {
+			return fmt.Errorf("join request not found or already reviewed: %w", err)
+		}
+		if err := requireAdmin(tx, req.GroupID, reviewerID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		req.Status = model.JoinRejected
+		if approve {
+			req.Status = model.JoinApproved
+		}
+		req.ReviewerID = &reviewerID
+		req.ReviewedAt = &now
+		if err := tx.Model(&req).
+			Select("status", "reviewer_id", "reviewed_at").
+			Updates(&req).Error; err != nil {
+			return err
+		}
+		if !approve {
+			return nil
+		}
+		seq, err := joinSeq(req.GroupID)
+		if err != nil {
+			return err
+		}
+		added, err = r.insertMembers(tx, req.GroupID, []int64{req.UserID}, seq)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &req, added, nil
+}
+
+// 创建邀请链接，只有群主/管理员可以操作
+func (r *groupRepo) CreateInviteLink(ctx context.Context, link *model.GroupInviteLink) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireAdmin(tx, link.GroupID, link.CreatorID); err != nil {
+			return err
+		}
+		return tx.Create(link).Error
+	})
+}
+
+// 撤销邀请链接，只有群主/管理员可以操作
+func (r *groupRepo) RevokeInviteLink(ctx context.Context, token string, executorID int64) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var link model.GroupInviteLink
+		if err := tx.Where("token = ?", token).First(&link).Error; err != nil {
+			return ErrInviteLinkInvalid
+		}
+		if err := requireAdmin(tx, link.GroupID, executorID); err != nil {
+			return err
+		}
+		return tx.Model(&link).Update("revoked", true).Error
+	})
+}
+
+// UseInviteLink 通过邀请链接入群，已经在群里时不消耗次数，joined 为 false
+func (r *groupRepo) UseInviteLink(ctx context.Context, token string, userID int64,
+	joinSeq func(groupID uuid.UUID) (int64, error)) (groupID uuid.UUID, joined bool, err error) {
+	var link model.GroupInviteLink
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ?", token).
+			First(&link).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInviteLinkInvalid
+			}
+			return err
+		}
+		if link.Revoked || (link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now())) ||
+			(link.MaxUses > 0 && link.UsedCount >= link.MaxUses) {
+			return ErrInviteLinkInvalid
+		}
+
+		role, err := memberRole(tx, link.GroupID, userID)
+		if err != nil {
+			return err
+		}
+		if role != "" {
+			return nil
+		}
+		seq, err := joinSeq(link.GroupID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.insertMembers(tx, link.GroupID, []int64{userID}, seq); err != nil {
+			return err
+		}
+		joined = true
+		return tx.Model(&link).UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error
+	})
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return link.GroupID, joined, nil
+}
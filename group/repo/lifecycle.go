@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGroupArchived  = errors.New("group is archived")
+	ErrGroupDissolved = errors.New("group is dissolved")
+)
+
+// 归档的群只读，解散的群不能再做任何操作
+func StatusError(status model.GroupStatus) error {
+	switch status {
+	case model.GroupArchived:
+		return ErrGroupArchived
+	case model.GroupDeleted:
+		return ErrGroupDissolved
+	default:
+		return nil
+	}
+}
+
+func (r *groupRepo) GetGroupStatus(ctx context.Context, groupID uuid.UUID) (model.GroupStatus, error) {
+	var g model.Group
+	if err := r.conn(ctx).Select("status").Where("id = ?", groupID).First(&g).Error; err != nil {
+		return "", err
+	}
+	return g.Status, nil
+}
+
+// 锁住群记录，解散的群直接返回错误
+func lockGroup(tx *gorm.DB, groupID uuid.UUID) (*model.Group, error) {
+	var g model.Group
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "owner_id", "status").
+		Where("id = ?", groupID).
+		First(&g).Error; err != nil {
+		return nil, err
+	}
+	if g.Status == model.GroupDeleted {
+		return nil, ErrGroupDissolved
+	}
+	return &g, nil
+}
+
+// LeaveGroup 退群。群主必须先转让群主，或者 autoTransfer 为 true 时自动转让给
+// 最早入群的管理员，没有管理员时转让给最早入群的成员；返回新群主，没有转让时为 0
+func (r *groupRepo) LeaveGroup(ctx context.Context, groupID uuid.UUID, userID int64, autoTransfer bool) (int64, error) {
+	var newOwnerID int64
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockGroup(tx, groupID); err != nil {
+			return err
+		}
+		role, err := memberRole(tx, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return fmt.Errorf("not in group")
+		}
+
+		if role == model.Owner {
+			if !autoTransfer {
+				return fmt.Errorf("group owner must transfer ownership before leaving")
+			}
+			var successors []int64
+			if err := tx.Model(&model.GroupMember{}).
+				Where("group_id = ? AND user_id <> ?", groupID, userID).
+				Order(clause.Expr{SQL: "CASE WHEN role = ? THEN 0 ELSE 1 END, join_time ASC", Vars: []interface{}{model.Admin}}).
+				Limit(1).
+				Pluck("user_id", &successors).Error; err != nil {
+				return err
+			}
+			if len(successors) == 0 {
+				return fmt.Errorf("group owner is the last member, dissolve the group instead")
+			}
+			newOwnerID = successors[0]
+			if err := tx.Model(&model.GroupMember{}).
+				Where("group_id = ? AND user_id = ?", groupID, newOwnerID).
+				Updates(map[string]interface{}{
+					"role":     model.Owner,
+					"is_owner": true,
+				}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Group{}).
+				Where("id = ?", groupID).
+				Update("owner_id", newOwnerID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("group_id = ? AND user_id = ?", groupID, userID).
+			Delete(&model.GroupMember{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newOwnerID, nil
+}
+
+// DissolveGroup 解散群，只有群主可以操作；移除所有成员，待审批记录和邀请链接一并作废，
+// 返回解散前的成员和入群位置
+func (r *groupRepo) DissolveGroup(ctx context.Context, groupID uuid.UUID, executorID int64) ([]*FormerMember, error) {
+	members := make([]*FormerMember, 0)
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		g, err := lockGroup(tx, groupID)
+		if err != nil {
+			return err
+		}
+		if g.OwnerID != executorID {
+			return fmt.Errorf("only group owner can dissolve the group")
+		}
+		if err := tx.Table("group_members AS m").
+			Select("m.user_id, m.join_seq, g.history_visible AS full_history").
+			Joins("JOIN groups g ON g.id = m.group_id").
+			Where("m.group_id = ?", groupID).
+			Scan(&members).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GroupJoinRequest{}).
+			Where("group_id = ? AND status = ?", groupID, model.JoinPending).
+			Update("status", model.JoinRejected).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GroupInviteLink{}).
+			Where("group_id = ?", groupID).
+			Update("revoked", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Group{}).
+			Where("id = ?", groupID).
+			Update("status", model.GroupDeleted).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetGroupArchived 归档 / 取消归档，只有群主可以操作
+func (r *groupRepo) SetGroupArchived(ctx context.Context, groupID uuid.UUID, executorID int64, archived bool) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		g, err := lockGroup(tx, groupID)
+		if err != nil {
+			return err
+		}
+		if g.OwnerID != executorID {
+			return fmt.Errorf("only group owner can archive the group")
+		}
+		status := model.GroupActive
+		if archived {
+			status = model.GroupArchived
+		}
+		return tx.Model(&model.Group{}).
+			Where("id = ?", groupID).
+			Update("status", status).Error
+	})
+}
@@ -25,8 +25,46 @@ type Group struct {
 	IsBanned  bool        `gorm:"not null;default:false"` //是否禁言
 	CreatedAt time.Time   `gorm:"autoCreateTime"`
 	UpdatedAt time.Time   `gorm:"autoUpdateTime"`
+
+	HistoryVisible bool `gorm:"not null;default:false"` // 新成员是否可以查看入群前的聊天记录
+
+	JoinPolicy   JoinPolicy `gorm:"type:varchar(16);not null;default:'approval'"` // 入群方式
+	JoinQuestion string     `gorm:"type:varchar(255)"`                            // JoinByQuestion 时的入群问题
+	JoinAnswer   string     `gorm:"type:varchar(255)" json:"-"`                   // 入群问题的答案，不对外返回
+
+	Tier      GroupTier `gorm:"type:varchar(16);not null;default:'standard'"` // 群等级，决定人数上限和是否为大群
+	MemberCap int       `gorm:"not null;default:0"`                           // 自定义人数上限，0 表示使用等级的默认上限
+
+	AvatarCustom bool   `gorm:"not null;default:false"` // 头像是群主/管理员设置的，不再自动生成
+	AvatarKey    string `gorm:"type:varchar(64)"`       // 生成九宫格头像时前九个成员的指纹，没变化时不重新生成
+}
+
+// 群等级：large 及以上是大群，消息服务不再给每个成员写未读记录，改用已读游标
+type GroupTier string
+
+const (
+	TierStandard GroupTier = "standard"
+	TierLarge    GroupTier = "large"
+	TierSuper    GroupTier = "super"
+)
+
+// 大群模式的等级，SQL 里判断大群时使用
+var LargeTiers = []GroupTier{TierLarge, TierSuper}
+
+func (t GroupTier) IsLarge() bool {
+	return t == TierLarge || t == TierSuper
 }
 
+// 入群方式
+type JoinPolicy string
+
+const (
+	JoinFree       JoinPolicy = "free"        // 任何人可以直接加入
+	JoinInviteOnly JoinPolicy = "invite_only" // 只能由成员邀请，不能主动申请
+	JoinApproval   JoinPolicy = "approval"    // 申请和普通成员的邀请都需要群主/管理员审批
+	JoinByQuestion JoinPolicy = "question"    // 回答正确入群问题后直接加入
+)
+
 // 群成员角色枚举
 type GroupRole string
 
@@ -43,4 +81,77 @@ type GroupMember struct {
 	JoinTime time.Time `gorm:"column:join_time;autoCreateTime"`
 	Nickname string    `gorm:"type:varchar(50)"`
 	IsOwner  bool      `gorm:"not null;default:false"`
+	JoinSeq  int64     `gorm:"not null;default:0"` // 入群时群消息的 seq，入群前的消息对其不可见
+
+	MutedUntil *time.Time // 禁言到期时间，nil 或已过期表示没有被禁言
+}
+
+// 入群申请状态
+type JoinRequestStatus string
+
+const (
+	JoinPending  JoinRequestStatus = "pending"
+	JoinApproved JoinRequestStatus = "approved"
+	JoinRejected JoinRequestStatus = "rejected"
+)
+
+// 待审批的入群记录：InviterID 为空是用户主动申请，否则是成员发出的邀请
+type GroupJoinRequest struct {
+	ID         int64             `gorm:"primaryKey;autoIncrement"`
+	GroupID    uuid.UUID         `gorm:"type:uuid;not null;index:idx_join_request_group,priority:1"`
+	UserID     int64             `gorm:"not null;index"`
+	InviterID  *int64            // 邀请人
+	Message    string            `gorm:"type:varchar(255)"` // 申请附言
+	Status     JoinRequestStatus `gorm:"type:varchar(16);not null;default:'pending';index:idx_join_request_group,priority:2"`
+	ReviewerID *int64            // 审批人
+	ReviewedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// 邀请链接 / 二维码，Token 即二维码内容
+type GroupInviteLink struct {
+	Token     string     `gorm:"type:varchar(64);primaryKey"`
+	GroupID   uuid.UUID  `gorm:"type:uuid;not null;index"`
+	CreatorID int64      `gorm:"not null"`
+	ExpiresAt *time.Time // nil 表示不过期
+	MaxUses   int        `gorm:"not null;default:0"` // 0 表示不限次数
+	UsedCount int        `gorm:"not null;default:0"`
+	Revoked   bool       `gorm:"not null;default:false"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// 群公告，可以同时置顶多条；Group.Notice 保存最新一条的内容，兼容旧接口
+type GroupAnnouncement struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	GroupID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	AuthorID  int64     `gorm:"not null"`
+	EditorID  *int64    // 最后一次编辑的人，没编辑过为空
+	Content   string    `gorm:"type:text;not null"`
+	Pinned    bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// 公告的编辑记录，保存的是编辑前的内容
+type GroupAnnouncementRevision struct {
+	ID             int64     `gorm:"primaryKey;autoIncrement"`
+	AnnouncementID int64     `gorm:"not null;index"`
+	EditorID       int64     `gorm:"not null"` // 这次编辑的人
+	Content        string    `gorm:"type:text;not null"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+// 成员确认已读公告的记录
+type GroupAnnouncementRead struct {
+	AnnouncementID int64     `gorm:"primaryKey"`
+	UserID         int64     `gorm:"primaryKey"`
+	ReadAt         time.Time `gorm:"autoCreateTime"`
+}
+
+// 待发送的群事件（发件箱），发到 Kafka 成功后删除
+type GroupEventOutbox struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	GroupID   string    `gorm:"not null"` // Kafka 分区键
+	Payload   []byte    `gorm:"not null"` // 序列化后的 GroupEvent
+	CreatedAt time.Time `gorm:"autoCreateTime"`
 }
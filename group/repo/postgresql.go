@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	groupmodel "github.com/AdventureDe/LinkIM/group/repo/model"
 	"github.com/AdventureDe/LinkIM/message/repo/model"
 
 	"gorm.io/driver/postgres"
@@ -28,13 +29,36 @@ func InitDB(host string) (*gorm.DB, error) {
 	return DB, nil
 }
 
+// 群组表用到的枚举类型，AutoMigrate 不会创建，需要提前建好
+var enumTypes = []string{
+	`DO $$ BEGIN
+		CREATE TYPE group_status AS ENUM ('active', 'archived', 'deleted');
+	EXCEPTION WHEN duplicate_object THEN NULL; END $$`,
+	`DO $$ BEGIN
+		CREATE TYPE group_role AS ENUM ('member', 'admin', 'owner');
+	EXCEPTION WHEN duplicate_object THEN NULL; END $$`,
+}
+
 // autoMigrate 自动迁移所有模型
 func autoMigrate() {
+	for _, stmt := range enumTypes {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatal("创建枚举类型失败：", err)
+		}
+	}
 	err := DB.AutoMigrate(
 		&model.Thread{},
 		&model.Conversation{},
 		&model.Message{},
 		&model.MessageStatus{},
+		&groupmodel.Group{},
+		&groupmodel.GroupMember{},
+		&groupmodel.GroupJoinRequest{},
+		&groupmodel.GroupInviteLink{},
+		&groupmodel.GroupAnnouncement{},
+		&groupmodel.GroupAnnouncementRevision{},
+		&groupmodel.GroupAnnouncementRead{},
+		&groupmodel.GroupEventOutbox{},
 	)
 	if err != nil {
 		log.Fatal("数据库迁移失败：", err)
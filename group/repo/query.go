@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	userpb "github.com/AdventureDe/LinkIM/api/user"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMemberPageSize = 50
+	maxMemberPageSize     = 200
+)
+
+// 成员列表的分页游标格式不正确
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// 群资料，入群问题的答案不对外返回
+type GroupDetail struct {
+	GroupID        uuid.UUID         `json:"group_id"`
+	Name           string            `json:"name"`
+	OwnerID        int64             `json:"owner_id"`
+	Avatar         string            `json:"avatar"`
+	AvatarCustom   bool              `json:"avatar_custom"`
+	Notice         string            `json:"notice"`
+	Status         model.GroupStatus `json:"status"`
+	Muted          bool              `json:"muted"`
+	HistoryVisible bool              `json:"history_visible"`
+	JoinPolicy     model.JoinPolicy  `json:"join_policy"`
+	JoinQuestion   string            `json:"join_question"`
+	Tier           model.GroupTier   `json:"tier"`
+	MemberCap      int               `json:"member_cap"`
+	MemberCount    int64             `json:"member_count"`
+	LargeGroup     bool              `json:"large_group"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// 成员列表中的一项，Nickname 是群昵称，UserNickname/Avatar 来自用户服务
+type MemberInfo struct {
+	UserID       int64           `json:"user_id"`
+	Role         model.GroupRole `json:"role"`
+	Nickname     string          `json:"nickname"`
+	UserNickname string          `json:"user_nickname"`
+	Avatar       string          `json:"avatar"`
+	JoinTime     time.Time       `json:"join_time"`
+	MutedUntil   *time.Time      `json:"muted_until"`
+}
+
+// 一页成员，NextCursor 为空表示没有更多
+type MemberPage struct {
+	Members    []*MemberInfo `json:"members"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// 用户加入的群
+type UserGroup struct {
+	GroupID     uuid.UUID         `json:"group_id"`
+	Name        string            `json:"name"`
+	Avatar      string            `json:"avatar"`
+	Status      model.GroupStatus `json:"status"`
+	Tier        model.GroupTier   `json:"tier"`
+	Role        model.GroupRole   `json:"role"`
+	Nickname    string            `json:"nickname"`
+	MemberCount int64             `json:"member_count"`
+	JoinTime    time.Time         `json:"join_time"`
+}
+
+// GetGroupDetail 群资料，只有群成员可以查看
+func (r *groupRepo) GetGroupDetail(ctx context.Context, groupID uuid.UUID, userID int64) (*GroupDetail, error) {
+	db := r.conn(ctx)
+	var g model.Group
+	if err := db.Where("id = ?", groupID).First(&g).Error; err != nil {
+		return nil, err
+	}
+	if g.Status == model.GroupDeleted {
+		return nil, ErrGroupDissolved
+	}
+	if err := requireMember(db, groupID, userID); err != nil {
+		return nil, err
+	}
+	count, err := countMembers(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupDetail{
+		GroupID:        g.ID,
+		Name:           g.Name,
+		OwnerID:        g.OwnerID,
+		Avatar:         g.Avatar,
+		AvatarCustom:   g.AvatarCustom,
+		Notice:         g.Notice,
+		Status:         g.Status,
+		Muted:          g.IsBanned,
+		HistoryVisible: g.HistoryVisible,
+		JoinPolicy:     g.JoinPolicy,
+		JoinQuestion:   g.JoinQuestion,
+		Tier:           g.Tier,
+		MemberCap:      r.capOf(&g),
+		MemberCount:    count,
+		LargeGroup:     g.Tier.IsLarge(),
+		CreatedAt:      g.CreatedAt,
+		UpdatedAt:      g.UpdatedAt,
+	}, nil
+}
+
+// 游标是上一页最后一个成员的 "入群时间(unix 纳秒):用户 ID"
+func encodeMemberCursor(m *model.GroupMember) string {
+	return fmt.Sprintf("%d:%d", m.JoinTime.UnixNano(), m.UserID)
+}
+
+func decodeMemberCursor(cursor string) (time.Time, int64, error) {
+	ts, id, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return time.Unix(0, nanos), userID, nil
+}
+
+// ListMembersPage 按入群时间分页查询成员，roles 为空时不过滤角色；只有群成员可以查看
+func (r *groupRepo) ListMembersPage(ctx context.Context, groupID uuid.UUID, userID int64, roles []model.GroupRole,
+	cursor string, limit int) (*MemberPage, error) {
+	if limit <= 0 {
+		limit = defaultMemberPageSize
+	}
+	if limit > maxMemberPageSize {
+		limit = maxMemberPageSize
+	}
+
+	db := r.conn(ctx)
+	if err := requireMember(db, groupID, userID); err != nil {
+		return nil, err
+	}
+	query := db.Where("group_id = ?", groupID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	if cursor != "" {
+		joinTime, userID, err := decodeMemberCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(join_time, user_id) > (?, ?)", joinTime, userID)
+	}
+	// 多查一条用来判断是否还有下一页
+	var rows []*model.GroupMember
+	if err := query.Order("join_time ASC, user_id ASC").
+		Limit(limit + 1).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &MemberPage{}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.NextCursor = encodeMemberCursor(rows[limit-1])
+	}
+	if len(rows) == 0 {
+		page.Members = []*MemberInfo{}
+		return page, nil
+	}
+
+	userIDs := make([]int64, 0, len(rows))
+	for _, m := range rows {
+		userIDs = append(userIDs, m.UserID)
+	}
+	resp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	userMap := make(map[int64]*userpb.UserInfo, len(resp.Users))
+	for _, u := range resp.Users {
+		userMap[u.UserId] = u
+	}
+
+	page.Members = make([]*MemberInfo, 0, len(rows))
+	for _, m := range rows {
+		info := &MemberInfo{
+			UserID:     m.UserID,
+			Role:       m.Role,
+			Nickname:   m.Nickname,
+			JoinTime:   m.JoinTime,
+			MutedUntil: m.MutedUntil,
+		}
+		// 用户服务查不到的成员只返回群里的信息
+		if u, ok := userMap[m.UserID]; ok && u != nil {
+			info.UserNickname = u.Nickname
+			info.Avatar = u.Avatar
+		}
+		page.Members = append(page.Members, info)
+	}
+	return page, nil
+}
+
+// 用户加入的所有群，不包含已解散的群，最近加入的在前
+func (r *groupRepo) ListUserGroups(ctx context.Context, userID int64) ([]*UserGroup, error) {
+	groups := make([]*UserGroup, 0)
+	if err := r.conn(ctx).
+		Table("group_members AS m").
+		Select("g.id AS group_id, g.name, g.avatar, g.status, g.tier, m.role, m.nickname, m.join_time, "+
+			"(SELECT COUNT(*) FROM group_members AS c WHERE c.group_id = g.id) AS member_count").
+		Joins("JOIN groups AS g ON g.id = m.group_id").
+		Where("m.user_id = ? AND g.status <> ?", userID, model.GroupDeleted).
+		Order("m.join_time DESC").
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
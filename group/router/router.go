@@ -15,8 +15,36 @@ func SetGroupRouter(r *gin.Engine, g *handler.GroupHandler) {
 	r.PUT("/group/promote/owner", g.TransferGroupOwner)
 	r.PUT("/group/notice", g.UpdateNotice)
 	r.GET("/group/notice", g.GetNotice)
+	r.POST("/group/announcement", g.PostAnnouncement)
+	r.PUT("/group/announcement", g.EditAnnouncement)
+	r.DELETE("/group/announcement", g.DeleteAnnouncement)
+	r.PUT("/group/announcement/pin", g.SetAnnouncementPinned)
+	r.GET("/group/announcements", g.ListAnnouncements)
+	r.GET("/group/announcement/history", g.ListAnnouncementRevisions)
+	r.POST("/group/announcement/confirm", g.ConfirmAnnouncement)
+	r.GET("/group/announcement/stats", g.GetAnnouncementStats)
 	r.PUT("/group/name", g.UpdateGroupName)
 	r.GET("/group/name", g.GetGroupName)
 	r.GET("/group/avatar", g.GetGroupAvatar)
+	r.PUT("/group/avatar", g.SetGroupAvatar)
+	r.GET("/group/info", g.GetGroupInfo)
+	r.GET("/group/members", g.ListGroupMembers)
+	r.GET("/group/mine", g.ListUserGroups)
 	r.PUT("/group/nickname", g.UpdateSelfName)
+	r.PUT("/group/history_visible", g.SetHistoryVisible)
+	r.GET("/group/capacity", g.GetGroupCapacity)
+	r.PUT("/group/tier", g.SetGroupTier)
+	r.POST("/group/leave", g.LeaveGroup)
+	r.DELETE("/group/dissolve", g.DissolveGroup)
+	r.PUT("/group/archive", g.SetGroupArchived)
+	r.PUT("/group/mute", g.SetGroupMuted)
+	r.PUT("/group/member/mute", g.MuteMember)
+	r.POST("/group/join", g.ApplyToJoin)
+	r.GET("/group/join/settings", g.GetJoinSettings)
+	r.PUT("/group/join/policy", g.SetJoinPolicy)
+	r.GET("/group/join/requests", g.ListJoinRequests)
+	r.PUT("/group/join/review", g.ReviewJoinRequest)
+	r.POST("/group/invite/link", g.CreateInviteLink)
+	r.DELETE("/group/invite/link", g.RevokeInviteLink)
+	r.POST("/group/invite/link/join", g.JoinByInviteLink)
 }
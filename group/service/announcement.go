@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+)
+
+/* ----------------------------------------------------- */
+// 群公告部分
+
+// 公告内容最长字数
+const maxAnnouncementLength = 2000
+
+func checkAnnouncementContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return errors.New("announcement content cannot be empty")
+	}
+	if utf8.RuneCountInString(content) > maxAnnouncementLength {
+		return errors.New("announcement content too long")
+	}
+	return nil
+}
+
+// PostAnnouncement 发布公告，同时在群里发一条系统消息
+func (s *GroupService) PostAnnouncement(ctx context.Context, groupID uuid.UUID, executorID int64,
+	content string, pinned bool) (*model.GroupAnnouncement, error) {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if err := checkAnnouncementContent(content); err != nil {
+		return nil, err
+	}
+	a := &model.GroupAnnouncement{
+		GroupID:  groupID,
+		AuthorID: executorID,
+		Content:  content,
+		Pinned:   pinned,
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateAnnouncement(ctx, a); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, &grouppb.GroupEvent{
+			Type:           grouppb.GroupEventType_GROUP_EVENT_NOTICE_UPDATED,
+			GroupId:        groupID.String(),
+			OperatorId:     executorID,
+			Notice:         content,
+			AnnouncementId: a.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// EditAnnouncement 编辑公告，不发系统消息，成员在公告列表里看到已编辑标记
+func (s *GroupService) EditAnnouncement(ctx context.Context, announcementID, executorID int64,
+	content string) (*model.GroupAnnouncement, error) {
+	if err := checkAnnouncementContent(content); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateAnnouncement(ctx, announcementID, executorID, content)
+}
+
+func (s *GroupService) DeleteAnnouncement(ctx context.Context, announcementID, executorID int64) error {
+	return s.repo.DeleteAnnouncement(ctx, announcementID, executorID)
+}
+
+func (s *GroupService) SetAnnouncementPinned(ctx context.Context, announcementID, executorID int64, pinned bool) error {
+	return s.repo.SetAnnouncementPinned(ctx, announcementID, executorID, pinned)
+}
+
+func (s *GroupService) ListAnnouncements(ctx context.Context, groupID uuid.UUID, userID int64) ([]*repo.AnnouncementView, error) {
+	return s.repo.ListAnnouncements(ctx, groupID, userID)
+}
+
+func (s *GroupService) ListAnnouncementRevisions(ctx context.Context, announcementID, userID int64) ([]*model.GroupAnnouncementRevision, error) {
+	return s.repo.ListAnnouncementRevisions(ctx, announcementID, userID)
+}
+
+// ConfirmAnnouncement 确认已读公告，归档的群里也可以确认
+func (s *GroupService) ConfirmAnnouncement(ctx context.Context, announcementID, userID int64) error {
+	return s.repo.ConfirmAnnouncement(ctx, announcementID, userID)
+}
+
+func (s *GroupService) GetAnnouncementStats(ctx context.Context, announcementID, executorID int64) (*repo.AnnouncementStats, error) {
+	return s.repo.GetAnnouncementStats(ctx, announcementID, executorID)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+)
+
+/* ----------------------------------------------------- */
+// 群头像部分
+
+const (
+	avatarSize         = 300             // 九宫格头像边长
+	avatarGap          = 6               // 格子间距
+	avatarFetchTimeout = 5 * time.Second // 下载单个成员头像的超时
+	avatarMaxBytes     = 5 << 20         // 成员头像最大 5MB
+	avatarMaxSide      = 4096            // 成员头像最大边长，解码前先检查，防止小文件解出超大图
+	avatarTaskTimeout  = 30 * time.Second
+)
+
+var (
+	avatarBackground  = color.RGBA{R: 0xdd, G: 0xde, B: 0xe0, A: 0xff}
+	avatarPlaceholder = color.RGBA{R: 0xb0, G: 0xb4, B: 0xba, A: 0xff} // 没有头像或下载失败的成员
+)
+
+// 同一个群的头像锁，refs 为持有和等待的数量，归零时从 map 中删除
+type avatarLock struct {
+	sync.Mutex
+	refs int
+}
+
+// SetGroupAvatar 设置自定义群头像，优先于自动生成的九宫格；url 为空时恢复自动生成
+func (s *GroupService) SetGroupAvatar(ctx context.Context, groupID uuid.UUID, executorID int64, url string) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	if url != "" && !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("invalid avatar url")
+	}
+	// 和生成串行，避免刚生成的九宫格文件被漏删
+	unlock := s.lockAvatar(groupID)
+	defer unlock()
+
+	state, err := s.repo.GetAvatarState(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetCustomAvatar(ctx, groupID, executorID, url); err != nil {
+		return err
+	}
+	// 自动生成的文件不再被引用
+	s.removeGeneratedAvatar(ctx, groupID, state.AvatarKey)
+	if url == "" {
+		s.refreshAvatar(groupID)
+	}
+	return nil
+}
+
+// 成员变化后在后台重新生成九宫格头像，不阻塞请求
+func (s *GroupService) refreshAvatar(groupID uuid.UUID) {
+	if s.avatars == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), avatarTaskTimeout)
+		defer cancel()
+		if err := s.regenerateAvatar(ctx, groupID); err != nil {
+			log.Printf("fail to regenerate group avatar %s: %v", groupID, err)
+		}
+	}()
+}
+
+// 获取群头像锁，返回的函数用于释放
+func (s *GroupService) lockAvatar(groupID uuid.UUID) func() {
+	s.avatarMu.Lock()
+	lock := s.avatarLocks[groupID]
+	if lock == nil {
+		lock = &avatarLock{}
+		s.avatarLocks[groupID] = lock
+	}
+	lock.refs++
+	s.avatarMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.avatarMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.avatarLocks, groupID)
+		}
+		s.avatarMu.Unlock()
+	}
+}
+
+// 同一个群的生成串行执行，后执行的一次总能读到最新的成员
+func (s *GroupService) regenerateAvatar(ctx context.Context, groupID uuid.UUID) error {
+	unlock := s.lockAvatar(groupID)
+	defer unlock()
+
+	state, err := s.repo.GetAvatarState(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if state.AvatarCustom || state.Status == model.GroupDeleted {
+		return nil
+	}
+	set, err := s.repo.GetGroupAvatar(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if len(set.UserInfo) == 0 {
+		return nil
+	}
+	key := avatarKey(set.UserInfo)
+	if key == state.AvatarKey && state.Avatar != "" {
+		return nil
+	}
+
+	tiles := make([]image.Image, len(set.UserInfo))
+	for i, u := range set.UserInfo {
+		img, err := s.fetchAvatar(ctx, u.Avatar)
+		if err != nil {
+			log.Printf("fail to fetch avatar of user %d: %v", u.UserID, err)
+			continue
+		}
+		tiles[i] = img
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, composeNineGrid(tiles)); err != nil {
+		return err
+	}
+	url, err := s.avatars.Save(ctx, generatedAvatarName(groupID, key), buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetGeneratedAvatar(ctx, groupID, url, key); err != nil {
+		return err
+	}
+	if state.AvatarKey != key {
+		s.removeGeneratedAvatar(ctx, groupID, state.AvatarKey)
+	}
+	return nil
+}
+
+func generatedAvatarName(groupID uuid.UUID, key string) string {
+	return fmt.Sprintf("%s-%s.png", groupID, key[:12])
+}
+
+// 删除不再引用的九宫格文件，失败只记日志
+func (s *GroupService) removeGeneratedAvatar(ctx context.Context, groupID uuid.UUID, key string) {
+	if key == "" || s.avatars == nil {
+		return
+	}
+	if err := s.avatars.Delete(ctx, generatedAvatarName(groupID, key)); err != nil {
+		log.Printf("fail to remove group avatar %s: %v", groupID, err)
+	}
+}
+
+// 前九个成员和他们的头像地址都没变时指纹不变
+func avatarKey(users []*repo.UserInfo) string {
+	h := sha1.New()
+	for _, u := range users {
+		fmt.Fprintf(h, "%d:%s;", u.UserID, u.Avatar)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 头像地址由用户填写，只允许从配置的上传存储下载，跳转后的地址同样检查
+func (s *GroupService) allowedAvatarURL(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && s.avatarHosts[strings.ToLower(u.Hostname())]
+}
+
+func (s *GroupService) fetchAvatar(ctx context.Context, rawURL string) (image.Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !s.allowedAvatarURL(u) {
+		return nil, fmt.Errorf("unsupported avatar url: %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: avatarFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 || !s.allowedAvatarURL(req.URL) {
+				return fmt.Errorf("redirect to %q is not allowed", req.URL)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, avatarMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > avatarMaxBytes {
+		return nil, fmt.Errorf("avatar is larger than %d bytes", avatarMaxBytes)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > avatarMaxSide || cfg.Height > avatarMaxSide {
+		return nil, fmt.Errorf("unsupported avatar size %dx%d", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// composeNineGrid 把最多九个头像拼成一张图；nil 的格子用占位色填充
+func composeNineGrid(tiles []image.Image) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, avatarSize, avatarSize))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: avatarBackground}, image.Point{}, draw.Src)
+
+	for i, rect := range nineGridLayout(len(tiles)) {
+		if tiles[i] == nil {
+			draw.Draw(canvas, rect, &image.Uniform{C: avatarPlaceholder}, image.Point{}, draw.Src)
+			continue
+		}
+		drawScaled(canvas, rect, tiles[i])
+	}
+	return canvas
+}
+
+// n 个头像各自的位置：不超过四个排两列，否则排三列，不满的一行放在最上面居中
+func nineGridLayout(n int) []image.Rectangle {
+	if n <= 0 {
+		return nil
+	}
+	cols := 3
+	if n <= 4 {
+		cols = 2
+	}
+	if n == 1 {
+		cols = 1
+	}
+	rows := (n + cols - 1) / cols
+	cell := (avatarSize - avatarGap*(cols+1)) / cols
+	top := (avatarSize - rows*cell - (rows-1)*avatarGap) / 2
+
+	first := n - (rows-1)*cols // 第一行的格子数
+	rects := make([]image.Rectangle, n)
+	for i := range rects {
+		row, col, inRow := 0, i, first
+		if i >= first {
+			row = (i-first)/cols + 1
+			col = (i - first) % cols
+			inRow = cols
+		}
+		left := (avatarSize - inRow*cell - (inRow-1)*avatarGap) / 2
+		x := left + col*(cell+avatarGap)
+		y := top + row*(cell+avatarGap)
+		rects[i] = image.Rect(x, y, x+cell, y+cell)
+	}
+	return rects
+}
+
+// 把 src 居中裁成正方形后按最近邻缩放到 rect
+func drawScaled(dst *image.RGBA, rect image.Rectangle, src image.Image) {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	if side == 0 {
+		draw.Draw(dst, rect, &image.Uniform{C: avatarPlaceholder}, image.Point{}, draw.Src)
+		return
+	}
+	ox := b.Min.X + (b.Dx()-side)/2
+	oy := b.Min.Y + (b.Dy()-side)/2
+	w, h := rect.Dx(), rect.Dy()
+	for y := 0; y < h; y++ {
+		sy := oy + y*side/h
+		for x := 0; x < w; x++ {
+			sx := ox + x*side/w
+			dst.Set(rect.Min.X+x, rect.Min.Y+y, src.At(sx, sy))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestNineGridLayout(t *testing.T) {
+	r := image.Rect
+	tests := []struct {
+		n    int
+		want []image.Rectangle
+	}{
+		{0, nil},
+		{1, []image.Rectangle{r(6, 6, 294, 294)}},
+		{2, []image.Rectangle{r(6, 79, 147, 220), r(153, 79, 294, 220)}},
+		// 不满的一行在上面居中
+		{3, []image.Rectangle{r(79, 6, 220, 147), r(6, 153, 147, 294), r(153, 153, 294, 294)}},
+		{4, []image.Rectangle{r(6, 6, 147, 147), r(153, 6, 294, 147), r(6, 153, 147, 294), r(153, 153, 294, 294)}},
+		{5, []image.Rectangle{
+			r(55, 55, 147, 147), r(153, 55, 245, 147),
+			r(6, 153, 98, 245), r(104, 153, 196, 245), r(202, 153, 294, 245),
+		}},
+		{9, []image.Rectangle{
+			r(6, 6, 98, 98), r(104, 6, 196, 98), r(202, 6, 294, 98),
+			r(6, 104, 98, 196), r(104, 104, 196, 196), r(202, 104, 294, 196),
+			r(6, 202, 98, 294), r(104, 202, 196, 294), r(202, 202, 294, 294),
+		}},
+	}
+	for _, tt := range tests {
+		if got := nineGridLayout(tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("nineGridLayout(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+// 任意人数的格子都在画布内、大小一致且互不重叠
+func TestNineGridLayoutNoOverlap(t *testing.T) {
+	canvas := image.Rect(0, 0, avatarSize, avatarSize)
+	for n := 1; n <= 9; n++ {
+		rects := nineGridLayout(n)
+		if len(rects) != n {
+			t.Fatalf("nineGridLayout(%d) returned %d rects", n, len(rects))
+		}
+		for i, a := range rects {
+			if !a.In(canvas) || a.Size() != rects[0].Size() {
+				t.Errorf("n=%d: rect %d %v out of canvas or size differs", n, i, a)
+			}
+			for j := i + 1; j < n; j++ {
+				if a.Overlaps(rects[j]) {
+					t.Errorf("n=%d: rect %d %v overlaps rect %d %v", n, i, a, j, rects[j])
+				}
+			}
+		}
+	}
+}
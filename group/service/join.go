@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+)
+
+/* ----------------------------------------------------- */
+// 入群部分
+
+// 邀请链接最长有效期
+const maxInviteLinkTTL = 30 * 24 * time.Hour
+
+// 邀请结果：Added 已直接入群，Pending 需要群主/管理员审批
+type InviteResult struct {
+	Added   []int64 `json:"added"`
+	Pending []int64 `json:"pending"`
+}
+
+// AddGroupMember 邀请用户入群，邀请人必须在群里。
+// 群主/管理员的邀请直接生效；普通成员的邀请在需要审批的群里会变成待审批记录
+func (s *GroupService) AddGroupMember(ctx context.Context, groupID uuid.UUID, inviterID int64,
+	userIDs []int64) (*InviteResult, error) {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, errors.New("user ids cannot be empty")
+	}
+	inviter, err := s.GetMemberState(ctx, groupID, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if inviter == nil {
+		return nil, errors.New("inviter not in group")
+	}
+	settings, err := s.repo.GetJoinSettings(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if inviter.Role == model.Owner || inviter.Role == model.Admin || settings.Policy != model.JoinApproval {
+		added, err := s.addMembers(ctx, groupID, inviterID, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		return &InviteResult{Added: added, Pending: []int64{}}, nil
+	}
+	created, err := s.repo.CreateJoinRequests(ctx, groupID, &inviterID, userIDs, "")
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]int64, 0, len(created))
+	for _, req := range created {
+		pending = append(pending, req.UserID)
+	}
+	return &InviteResult{Added: []int64{}, Pending: pending}, nil
+}
+
+// ApplyToJoin 主动申请入群，返回是否已经直接入群
+func (s *GroupService) ApplyToJoin(ctx context.Context, groupID uuid.UUID, userID int64, answer, message string) (bool, error) {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return false, err
+	}
+	if userID <= 0 {
+		return false, errors.New("invalid userID")
+	}
+	state, err := s.GetMemberState(ctx, groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	if state != nil {
+		return false, errors.New("already in group")
+	}
+	settings, err := s.repo.GetJoinSettings(ctx, groupID)
+	if err != nil {
+		return false, err
+	}
+
+	switch settings.Policy {
+	case model.JoinFree:
+	case model.JoinByQuestion:
+		if !strings.EqualFold(strings.TrimSpace(answer), strings.TrimSpace(settings.Answer)) {
+			return false, errors.New("wrong answer")
+		}
+	case model.JoinApproval:
+		if _, err := s.repo.CreateJoinRequests(ctx, groupID, nil, []int64{userID}, message); err != nil {
+			return false, err
+		}
+		return false, nil
+	default:
+		return false, errors.New("group only accepts invitations")
+	}
+	if _, err := s.addMembers(ctx, groupID, userID, []int64{userID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 入群设置，不包含问题答案
+func (s *GroupService) GetJoinSettings(ctx context.Context, groupID uuid.UUID) (*repo.JoinSettings, error) {
+	settings, err := s.repo.GetJoinSettings(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	settings.Answer = ""
+	return settings, nil
+}
+
+func (s *GroupService) SetJoinPolicy(ctx context.Context, groupID uuid.UUID, executorID int64,
+	policy, question, answer string) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	p := model.JoinPolicy(policy)
+	switch p {
+	case model.JoinFree, model.JoinInviteOnly, model.JoinApproval:
+		question, answer = "", ""
+	case model.JoinByQuestion:
+		if strings.TrimSpace(question) == "" || strings.TrimSpace(answer) == "" {
+			return errors.New("question and answer are required")
+		}
+	default:
+		return errors.New("invalid join policy")
+	}
+	return s.repo.SetJoinPolicy(ctx, groupID, executorID, &repo.JoinSettings{
+		Policy:   p,
+		Question: question,
+		Answer:   answer,
+	})
+}
+
+func (s *GroupService) ListJoinRequests(ctx context.Context, groupID uuid.UUID, executorID int64) ([]*model.GroupJoinRequest, error) {
+	return s.repo.ListJoinRequests(ctx, groupID, executorID)
+}
+
+// ReviewJoinRequest 审批入群申请或邀请
+func (s *GroupService) ReviewJoinRequest(ctx context.Context, requestID, executorID int64, approve bool) (*model.GroupJoinRequest, error) {
+	var req *model.GroupJoinRequest
+	var added []int64
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		req, added, err = s.repo.ReviewJoinRequest(ctx, requestID, executorID, approve, s.joinSeq(ctx))
+		if err != nil {
+			return err
+		}
+		// 申请人可能在审批前已经通过其他方式入群，这时不用再处理
+		if len(added) == 0 {
+			return nil
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED, req.GroupID, executorID, req.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		s.dropMemberStates(ctx, req.GroupID, req.UserID)
+		s.refreshAvatar(req.GroupID)
+	}
+	return req, nil
+}
+
+// CreateInviteLink 创建邀请链接，ttl 为 0 表示不过期，maxUses 为 0 表示不限次数
+func (s *GroupService) CreateInviteLink(ctx context.Context, groupID uuid.UUID, executorID int64,
+	ttl time.Duration, maxUses int) (*model.GroupInviteLink, error) {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if ttl < 0 || ttl > maxInviteLinkTTL || maxUses < 0 {
+		return nil, errors.New("invalid ttl or max uses")
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("fail to generate token: %w", err)
+	}
+	link := &model.GroupInviteLink{
+		Token:     hex.EncodeToString(buf),
+		GroupID:   groupID,
+		CreatorID: executorID,
+		MaxUses:   maxUses,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		link.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.CreateInviteLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func (s *GroupService) RevokeInviteLink(ctx context.Context, token string, executorID int64) error {
+	return s.repo.RevokeInviteLink(ctx, token, executorID)
+}
+
+// JoinByInviteLink 通过邀请链接 / 二维码入群，不需要审批
+func (s *GroupService) JoinByInviteLink(ctx context.Context, token string, userID int64) (uuid.UUID, error) {
+	if token == "" || userID <= 0 {
+		return uuid.Nil, errors.New("invalid token or userID")
+	}
+	var groupID uuid.UUID
+	var joined bool
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		groupID, joined, err = s.repo.UseInviteLink(ctx, token, userID, s.joinSeq(ctx))
+		if err != nil || !joined {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED, groupID, userID, userID)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if joined {
+		s.dropMemberStates(ctx, groupID, userID)
+		s.refreshAvatar(groupID)
+	}
+	return groupID, nil
+}
+
+// 直接加入成员，新成员从当前 seq 之后开始可见；返回实际加入的成员
+func (s *GroupService) addMembers(ctx context.Context, groupID uuid.UUID, operatorID int64, userIDs []int64) ([]int64, error) {
+	joinSeq, err := s.redis.CurrentSeq(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get group seq: %w", err)
+	}
+	var added []int64
+	err = s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		added, err = s.repo.AddGroupMember(ctx, groupID, userIDs, joinSeq)
+		if err != nil || len(added) == 0 {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED, groupID, operatorID, added...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		s.dropMemberStates(ctx, groupID, added...)
+		s.refreshAvatar(groupID)
+	}
+	return added, nil
+}
+
+func (s *GroupService) joinSeq(ctx context.Context) func(groupID uuid.UUID) (int64, error) {
+	return func(groupID uuid.UUID) (int64, error) {
+		return s.redis.CurrentSeq(ctx, groupID)
+	}
+}
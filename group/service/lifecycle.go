@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/google/uuid"
+)
+
+/* ----------------------------------------------------- */
+// 退群、解散和归档部分
+
+// 归档和解散后的群只读，修改类操作先检查群状态
+func (s *GroupService) requireActive(ctx context.Context, groupID uuid.UUID) error {
+	status, err := s.repo.GetGroupStatus(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	return repo.StatusError(status)
+}
+
+// LeaveGroup 退群，群主需要先转让，或者传 autoTransfer 自动转让给管理员 / 最早入群的成员
+func (s *GroupService) LeaveGroup(ctx context.Context, groupID uuid.UUID, userID int64, autoTransfer bool) (int64, error) {
+	var newOwnerID int64
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if newOwnerID, err = s.repo.LeaveGroup(ctx, groupID, userID, autoTransfer); err != nil {
+			return err
+		}
+		if newOwnerID != 0 {
+			err := s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_OWNER_TRANSFERRED, groupID, userID, newOwnerID)
+			if err != nil {
+				return err
+			}
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_MEMBER_LEFT, groupID, userID, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if newOwnerID != 0 {
+		s.dropMemberStates(ctx, groupID, userID, newOwnerID)
+	} else {
+		s.dropMemberStates(ctx, groupID, userID)
+	}
+	s.refreshAvatar(groupID)
+	return newOwnerID, nil
+}
+
+// DissolveGroup 解散群，所有成员立刻失去权限，消息服务收到事件后把群会话标记为已解散
+func (s *GroupService) DissolveGroup(ctx context.Context, groupID uuid.UUID, executorID int64) error {
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		members, err := s.repo.DissolveGroup(ctx, groupID, executorID)
+		if err != nil {
+			return err
+		}
+		memberIDs := make([]int64, 0, len(members))
+		former := make([]*grouppb.FormerMember, 0, len(members))
+		for _, m := range members {
+			memberIDs = append(memberIDs, m.UserID)
+			former = append(former, &grouppb.FormerMember{
+				UserId:      m.UserID,
+				JoinSeq:     m.JoinSeq,
+				FullHistory: m.FullHistory,
+			})
+		}
+		return s.events.Publish(ctx, &grouppb.GroupEvent{
+			Type:          grouppb.GroupEventType_GROUP_EVENT_GROUP_DISSOLVED,
+			GroupId:       groupID.String(),
+			OperatorId:    executorID,
+			TargetIds:     memberIDs,
+			FormerMembers: former,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.redis.DelGroupMemberStates(ctx, groupID); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+	return nil
+}
+
+// SetGroupArchived 归档 / 取消归档，归档后成员还能查看历史，但不能发言和修改群资料
+func (s *GroupService) SetGroupArchived(ctx context.Context, groupID uuid.UUID, executorID int64, archived bool) error {
+	eventType := grouppb.GroupEventType_GROUP_EVENT_GROUP_UNARCHIVED
+	if archived {
+		eventType = grouppb.GroupEventType_GROUP_EVENT_GROUP_ARCHIVED
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetGroupArchived(ctx, groupID, executorID, archived); err != nil {
+			return err
+		}
+		return s.publish(ctx, eventType, groupID, executorID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.redis.DelGroupMemberStates(ctx, groupID); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/google/uuid"
+)
+
+/* ----------------------------------------------------- */
+// 禁言部分
+
+// 单次禁言的最长时间
+const maxMuteDuration = 30 * 24 * time.Hour
+
+// SetGroupMuted 开启 / 关闭全员禁言，禁言期间只有群主和管理员可以发言
+func (s *GroupService) SetGroupMuted(ctx context.Context, groupID uuid.UUID, executorID int64, muted bool) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	eventType := grouppb.GroupEventType_GROUP_EVENT_GROUP_UNMUTED
+	if muted {
+		eventType = grouppb.GroupEventType_GROUP_EVENT_GROUP_MUTED
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetGroupMuted(ctx, groupID, executorID, muted); err != nil {
+			return err
+		}
+		return s.publish(ctx, eventType, groupID, executorID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.redis.DelGroupMemberStates(ctx, groupID); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+	return nil
+}
+
+// MuteMember 禁言成员 duration 时长，duration 为 0 表示解除禁言
+func (s *GroupService) MuteMember(ctx context.Context, groupID uuid.UUID, executorID int64, userID int64,
+	duration time.Duration) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	if duration < 0 || duration > maxMuteDuration {
+		return errors.New("invalid mute duration")
+	}
+	var until *time.Time
+	if duration > 0 {
+		t := time.Now().Add(duration)
+		until = &t
+	}
+	event := &grouppb.GroupEvent{
+		Type:       grouppb.GroupEventType_GROUP_EVENT_MEMBER_UNMUTED,
+		GroupId:    groupID.String(),
+		OperatorId: executorID,
+		TargetIds:  []int64{userID},
+	}
+	if until != nil {
+		event.Type = grouppb.GroupEventType_GROUP_EVENT_MEMBER_MUTED
+		event.Until = until.Unix()
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.MuteMember(ctx, groupID, executorID, userID, until); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+	s.dropMemberStates(ctx, groupID, userID)
+	return nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
+	"github.com/google/uuid"
+)
+
+func (s *GroupService) GetGroupDetail(ctx context.Context, groupID uuid.UUID, userID int64) (*repo.GroupDetail, error) {
+	return s.repo.GetGroupDetail(ctx, groupID, userID)
+}
+
+// ListGroupMembers 分页查询群成员，roles 可选 owner / admin / member
+func (s *GroupService) ListGroupMembers(ctx context.Context, groupID uuid.UUID, userID int64, roles []string,
+	cursor string, limit int) (*repo.MemberPage, error) {
+	filter := make([]model.GroupRole, 0, len(roles))
+	for _, role := range roles {
+		switch r := model.GroupRole(role); r {
+		case model.Owner, model.Admin, model.Member:
+			filter = append(filter, r)
+		default:
+			return nil, fmt.Errorf("unknown role: %s", role)
+		}
+	}
+	return s.repo.ListMembersPage(ctx, groupID, userID, filter, cursor, limit)
+}
+
+func (s *GroupService) ListUserGroups(ctx context.Context, userID int64) ([]*repo.UserGroup, error) {
+	return s.repo.ListUserGroups(ctx, userID)
+}
@@ -2,57 +2,155 @@ package service
 
 import (
 	"context"
+	"log"
+	"strings"
+	"sync"
 
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
 	"github.com/AdventureDe/LinkIM/group/repo"
+	"github.com/AdventureDe/LinkIM/group/repo/model"
 	"github.com/google/uuid"
 )
 
 type GroupService struct {
-	repo  repo.GroupRepo
-	redis repo.GroupRedis
+	repo    repo.GroupRepo
+	redis   repo.GroupRedis
+	events  repo.GroupEventPublisher
+	avatars repo.AvatarStore
+
+	avatarHosts map[string]bool // 允许下载成员头像的主机
+	avatarMu    sync.Mutex
+	avatarLocks map[uuid.UUID]*avatarLock // 同一个群的头像生成串行执行，没人等待时删除
 }
 
-func NewGroupService(r repo.GroupRepo, u repo.GroupRedis) *GroupService {
+func NewGroupService(r repo.GroupRepo, u repo.GroupRedis, e repo.GroupEventPublisher, a repo.AvatarStore,
+	avatarHosts []string) *GroupService {
+	hosts := make(map[string]bool, len(avatarHosts))
+	for _, h := range avatarHosts {
+		hosts[strings.ToLower(h)] = true
+	}
 	return &GroupService{
-		repo:  r,
-		redis: u,
+		repo:        r,
+		redis:       u,
+		events:      e,
+		avatars:     a,
+		avatarHosts: hosts,
+		avatarLocks: make(map[uuid.UUID]*avatarLock),
 	}
 }
 
 func (s *GroupService) CreateGroup(ctx context.Context, ownerID int64, userIDs []int64, groupName string) (uuid.UUID, error) {
-	groupID, err := s.repo.CreateGroup(ctx, ownerID, userIDs, groupName)
+	var groupID uuid.UUID
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if groupID, err = s.repo.CreateGroup(ctx, ownerID, userIDs, groupName); err != nil {
+			return err
+		}
+		members := make([]int64, 0, len(userIDs))
+		for _, id := range userIDs {
+			if id != ownerID {
+				members = append(members, id)
+			}
+		}
+		return s.events.Publish(ctx, &grouppb.GroupEvent{
+			Type:       grouppb.GroupEventType_GROUP_EVENT_GROUP_CREATED,
+			GroupId:    groupID.String(),
+			OperatorId: ownerID,
+			TargetIds:  members,
+			GroupName:  groupName,
+		})
+	})
 	if err != nil {
 		return uuid.Nil, err
 	}
+	s.refreshAvatar(groupID)
 	return groupID, nil
 }
 
-func (s *GroupService) AddGroupMember(ctx context.Context, groupID uuid.UUID, userIDs []int64) error {
-	return s.repo.AddGroupMember(ctx, groupID, userIDs)
-}
-
 func (s *GroupService) KickOutGroupMember(ctx context.Context, groupID uuid.UUID,
 	executorID int64, userIDs []int64) error {
-	return s.repo.KickOutGroupMember(ctx, groupID, executorID, userIDs)
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.KickOutGroupMember(ctx, groupID, executorID, userIDs); err != nil {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_MEMBER_KICKED, groupID, executorID, userIDs...)
+	})
+	if err != nil {
+		return err
+	}
+	// 被踢的成员要立刻失去权限
+	s.dropMemberStates(ctx, groupID, userIDs...)
+	s.refreshAvatar(groupID)
+	return nil
 }
 
 func (s *GroupService) PromoteToAdmin(ctx context.Context, groupID uuid.UUID,
 	executorID int64, userID int64) error {
-	return s.repo.PromoteToAdmin(ctx, groupID, executorID, userID)
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.PromoteToAdmin(ctx, groupID, executorID, userID); err != nil {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_ADMIN_PROMOTED, groupID, executorID, userID)
+	})
+	if err != nil {
+		return err
+	}
+	s.dropMemberStates(ctx, groupID, userID)
+	return nil
 }
 
 func (s *GroupService) TransferGroupOwner(ctx context.Context, groupID uuid.UUID,
 	executorID int64, userID int64) error {
-	return s.repo.TransferGroupOwner(ctx, groupID, executorID, userID)
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.TransferGroupOwner(ctx, groupID, executorID, userID); err != nil {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_OWNER_TRANSFERRED, groupID, executorID, userID)
+	})
+	if err != nil {
+		return err
+	}
+	s.dropMemberStates(ctx, groupID, executorID, userID)
+	return nil
 }
 
 func (s *GroupService) DemotedToMember(ctx context.Context, groupID uuid.UUID,
 	executorID int64, userID int64) error {
-	return s.repo.DemotedToMember(ctx, groupID, executorID, userID)
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.DemotedToMember(ctx, groupID, executorID, userID); err != nil {
+			return err
+		}
+		return s.publish(ctx, grouppb.GroupEventType_GROUP_EVENT_ADMIN_DEMOTED, groupID, executorID, userID)
+	})
+	if err != nil {
+		return err
+	}
+	s.dropMemberStates(ctx, groupID, userID)
+	return nil
 }
 
+// UpdateNotice 旧的修改群公告接口：非空时发布一条新公告，为空时只清空群公告
 func (s *GroupService) UpdateNotice(ctx context.Context, groupID uuid.UUID,
 	executorID int64, newNoticeText string) error {
+	if newNoticeText != "" {
+		_, err := s.PostAnnouncement(ctx, groupID, executorID, newNoticeText, false)
+		return err
+	}
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
 	return s.repo.UpdateNotice(ctx, groupID, executorID, newNoticeText)
 }
 
@@ -62,7 +160,20 @@ func (s *GroupService) GetNotice(ctx context.Context, groupID uuid.UUID) (string
 
 func (s *GroupService) UpdateGroupName(ctx context.Context, groupID uuid.UUID,
 	executorID int64, newGroupName string) error {
-	return s.repo.UpdateGroupName(ctx, groupID, executorID, newGroupName)
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateGroupName(ctx, groupID, executorID, newGroupName); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, &grouppb.GroupEvent{
+			Type:       grouppb.GroupEventType_GROUP_EVENT_GROUP_RENAMED,
+			GroupId:    groupID.String(),
+			OperatorId: executorID,
+			GroupName:  newGroupName,
+		})
+	})
 }
 
 func (s *GroupService) GetGroupName(ctx context.Context, groupID uuid.UUID) (string, error) {
@@ -75,5 +186,89 @@ func (s *GroupService) GetGroupAvatar(ctx context.Context, groupID uuid.UUID) (*
 
 func (s *GroupService) UpdateSelfName(ctx context.Context, groupID uuid.UUID,
 	userID int64, newName string) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
 	return s.repo.UpdateSelfName(ctx, groupID, userID, newName)
 }
+
+// gRPC 的成员查询直接复用 GroupService
+var _ repo.MemberReader = (*GroupService)(nil)
+
+// GetMemberState 查询成员状态，先查缓存，不在群里返回 nil
+func (s *GroupService) GetMemberState(ctx context.Context, groupID uuid.UUID, userID int64) (*repo.MemberState, error) {
+	state, hit, err := s.redis.GetMemberState(ctx, groupID, userID)
+	if err != nil {
+		log.Printf("fail to get member state from cache: %v", err)
+	} else if hit {
+		return state, nil
+	}
+	state, err = s.repo.GetMemberState(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.SetMemberState(ctx, groupID, userID, state); err != nil {
+		log.Printf("fail to cache member state: %v", err)
+	}
+	return state, nil
+}
+
+// 设置新成员是否可以查看入群前的聊天记录
+func (s *GroupService) SetHistoryVisible(ctx context.Context, groupID uuid.UUID, executorID int64, visible bool) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.repo.SetHistoryVisible(ctx, groupID, executorID, visible); err != nil {
+		return err
+	}
+	if err := s.redis.DelGroupMemberStates(ctx, groupID); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+	return nil
+}
+
+func (s *GroupService) GetGroupCapacity(ctx context.Context, groupID uuid.UUID) (*repo.GroupCapacity, error) {
+	return s.repo.GetGroupCapacity(ctx, groupID)
+}
+
+// SetGroupTier 升降群等级；large 及以上为大群模式
+func (s *GroupService) SetGroupTier(ctx context.Context, groupID uuid.UUID, executorID int64, tier string, memberCap int) error {
+	if err := s.requireActive(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.repo.SetGroupTier(ctx, groupID, executorID, model.GroupTier(tier), memberCap); err != nil {
+		return err
+	}
+	// 成员状态里缓存了是否大群
+	if err := s.redis.DelGroupMemberStates(ctx, groupID); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+	return nil
+}
+
+// 在一个事务里修改群并把群事件写进发件箱，两者一起提交，进程在中间退出也不会丢事件；
+// 提交后唤醒发件箱转发
+func (s *GroupService) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := s.repo.Transaction(ctx, fn); err != nil {
+		return err
+	}
+	s.events.Notify()
+	return nil
+}
+
+// 发布只带操作人和目标成员的群事件，消息服务会写成群里的系统消息；ctx 需要来自 inTx
+func (s *GroupService) publish(ctx context.Context, eventType grouppb.GroupEventType, groupID uuid.UUID,
+	operatorID int64, targetIDs ...int64) error {
+	return s.events.Publish(ctx, &grouppb.GroupEvent{
+		Type:       eventType,
+		GroupId:    groupID.String(),
+		OperatorId: operatorID,
+		TargetIds:  targetIDs,
+	})
+}
+
+func (s *GroupService) dropMemberStates(ctx context.Context, groupID uuid.UUID, userIDs ...int64) {
+	if err := s.redis.DelMemberStates(ctx, groupID, userIDs...); err != nil {
+		log.Printf("fail to drop member state cache: %v", err)
+	}
+}
@@ -77,14 +77,20 @@ func main() {
 	}()
 
 	// 7. 初始化核心架构层
-	messageRepo := repo.NewMessageRepo(db, m)
-	messageService := service.NewMessageService(messageRepo, rdb, logger, kafkaProducer, idGen)
+	searchIndex := repo.NewPostgresSearchIndex(db)
+	messageRepo := repo.NewMessageRepo(db, m, searchIndex)
+	go repo.ReindexMissing(context.Background(), db, searchIndex)
+	messageService := service.NewMessageService(messageRepo, rdb, logger, kafkaProducer, idGen, cfg)
 	messageHandler := handler.NewMessageHandler(messageService)
+	go messageService.RunScheduler(context.Background())
+	go messageService.RunReaper(context.Background())
 
 	// 8. 启动 Kafka 消费者
 	consumerGroupID := "im_message_group"
 	consumerClient := StartMessageConsumer(kafkaBrokers, consumerGroupID, messageRepo, rdb, logger)
 	defer consumerClient.Close()
+	groupEventClient := StartGroupEventConsumer(kafkaBrokers, "im_group_event_group", messageService, logger)
+	defer groupEventClient.Close()
 
 	// 9. 启动 HTTP 路由与服务
 	r := gin.Default()
@@ -126,3 +132,33 @@ func StartMessageConsumer(brokers []string, groupID string, messageRepo repo.Mes
 
 	return client
 }
+
+// StartGroupEventConsumer 消费群服务发来的群事件，写成群里的系统消息
+func StartGroupEventConsumer(brokers []string, groupID string, messageService *service.MessageService, logger *zap.Logger) sarama.ConsumerGroup {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategySticky()
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	client, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating consumer group client: %v", err))
+	}
+
+	handler := service.NewGroupEventHandler(messageService, logger)
+	ctx := context.Background()
+
+	go func() {
+		for {
+			topics := []string{service.GroupEventTopic}
+			if err := client.Consume(ctx, topics, handler); err != nil {
+				logger.Error("Error from Kafka consumer", zap.Error(err))
+				time.Sleep(2 * time.Second)
+			}
+		}
+	}()
+
+	logger.Info("Kafka Consumer started successfully", zap.Strings("brokers", brokers), zap.String("topic", service.GroupEventTopic))
+
+	return client
+}
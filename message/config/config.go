@@ -3,15 +3,27 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-contrib/cors"
 )
 
+// 消息编辑权限
+const (
+	EditBySender        = "sender"          // 只有发送者本人可以编辑
+	EditBySenderOrAdmin = "sender_or_admin" // 发送者 + 群主/管理员（仅群聊）
+)
+
 type Config struct {
 	Port      int
 	DBHost    string // 新增：数据库地址
 	RedisHost string // 新增：Redis地址
 	KafkaHost string // 新增：Kafka地址
+
+	EditWindow time.Duration // 消息可编辑的时间窗口，0 表示不限制
+	EditPolicy string        // 谁可以编辑消息：sender / sender_or_admin
+
+	RecallWindow time.Duration // 普通成员撤回消息的时间窗口，群聊可以单独覆盖
 }
 
 var CorsConfig = cors.Config{
@@ -30,6 +42,16 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// 辅助函数：读取时长类型的环境变量，例如 "15m"、"24h"，解析失败时使用默认值
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
 func Load() *Config {
 	port := 10010 // 默认端口
 	// 允许通过环境变量修改端口
@@ -45,6 +67,11 @@ func Load() *Config {
 		DBHost:    getEnv("DB_HOST", "localhost"),
 		RedisHost: getEnv("REDIS_HOST", "localhost"),
 		KafkaHost: getEnv("KAFKA_HOST", "localhost:19092"), // 本地默认用外部映射端口
+
+		EditWindow: getDurationEnv("MESSAGE_EDIT_WINDOW", 24*time.Hour),
+		EditPolicy: getEnv("MESSAGE_EDIT_POLICY", EditBySender),
+
+		RecallWindow: getDurationEnv("MESSAGE_RECALL_WINDOW", 3*time.Minute),
 	}
 }
 
@@ -16,11 +16,102 @@ type ConversationMessagesDTO struct {
 
 type MessageDTO struct {
 	ID            int64
+	SeqID         int64 // 会话内连续序号，用于双向翻页
+	Kind          int16 // 消息类型 1. text 2. image 3. file 4. 合并转发
+	ForwardedFrom *int64
 	Content       string
 	Sender        int64
 	GroupNickname string
 	UserInfo      *UserInfoDTO
 	CreateTime    time.Time
+	EditedAt      *time.Time
+	IsWithdrawn   bool
+	Tombstone     string // 撤回提示，例如 "X recalled a message"
+	ReplyTo       *QuotedMessageDTO
+	ReplyCount    int // 群聊中回复这条消息的数量
+	Mentions      []int64
+	MentionAll    bool
+	Reactions     []*ReactionDTO
+	ExpiresAt     *time.Time // 阅后即焚消息的过期时间
+}
+
+// 转发结果，每个目标会话单独返回
+type ForwardResultDTO struct {
+	TargetID int64      `json:"target_id,omitempty"`
+	GroupID  *uuid.UUID `json:"group_id,omitempty"`
+	MsgIDs   []int64    `json:"msg_ids"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// 合并转发消息的 Content：被转发消息的快照
+type MergedForwardDTO struct {
+	Title string                  `json:"title"`
+	Items []*MergedForwardItemDTO `json:"items"`
+}
+
+type MergedForwardItemDTO struct {
+	MsgID          int64     `json:"msg_id"`
+	SenderID       int64     `json:"sender_id"`
+	SenderNickname string    `json:"sender_nickname"`
+	SenderAvatar   string    `json:"sender_avatar"`
+	Kind           int16     `json:"kind"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SearchResultDTO struct {
+	Items      []*SearchHitDTO `json:"items"`
+	HasMore    bool            `json:"has_more"`
+	NextCursor int64           `json:"next_cursor,omitempty"` // 下一页请求时作为 cursor 传回
+}
+
+type SearchHitDTO struct {
+	MsgID          int64      `json:"msg_id"`
+	ThreadID       int64      `json:"thread_id"`
+	GroupID        *uuid.UUID `json:"group_id,omitempty"`
+	PeerID         int64      `json:"peer_id,omitempty"`
+	SenderID       int64      `json:"sender_id"`
+	SenderNickname string     `json:"sender_nickname"`
+	SenderAvatar   string     `json:"sender_avatar"`
+	Kind           int16      `json:"kind"`
+	Content        string     `json:"content"`
+	Highlight      string     `json:"highlight"` // 命中的关键词用 <em> 包裹，已做 HTML 转义
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ReactionDTO struct {
+	Emoji   string  `json:"emoji"`
+	Count   int     `json:"count"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// 被引用消息的摘要，原消息撤回或在入群之前（Unavailable）时 Content 为空
+type QuotedMessageDTO struct {
+	MsgID          int64  `json:"msg_id"`
+	SenderID       int64  `json:"sender_id"`
+	SenderNickname string `json:"sender_nickname"`
+	Kind           int16  `json:"kind"`
+	Content        string `json:"content"`
+	IsWithdrawn    bool   `json:"is_withdrawn"`
+	IsExpired      bool   `json:"is_expired"`
+	Unavailable    bool   `json:"unavailable"`
+}
+
+// 跳转到某条消息时返回的消息窗口，Messages 按时间倒序
+type MessageWindowDTO struct {
+	ThreadID      int64
+	Messages      []*MessageDTO
+	AnchorSeq     int64
+	HasMoreBefore bool
+	HasMoreAfter  bool
+}
+
+type MessageRevisionDTO struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
+	EditorID  int64     `json:"editor_id"`
+	Content   string    `json:"content"` // 编辑前的内容
+	EditedAt  time.Time `json:"edited_at"`
 }
 
 type UserInfoDTO struct {
@@ -30,13 +121,38 @@ type UserInfoDTO struct {
 }
 
 type ConversationDTO struct {
-	Type        string     `json:"type"`
-	ThreadID    int64      `json:"thread_id"`
-	LastMessage *Message   `json:"last_message"`
-	UnreadCount int        `json:"unread_count"`
-	UserInfo    *UserInfo  `json:"user_info"`
-	GroupInfo   *GroupInfo `json:"group_info"`
-	UpdateTime  time.Time  `json:"update_time"`
+	Type         string     `json:"type"`
+	ThreadID     int64      `json:"thread_id"`
+	LastMessage  *Message   `json:"last_message"`
+	UnreadCount  int        `json:"unread_count"`
+	MentionCount int        `json:"mention_count"` // 大于 0 时展示 "[有人@我]"
+	Draft        *DraftDTO  `json:"draft"`
+	UserInfo     *UserInfo  `json:"user_info"`
+	GroupInfo    *GroupInfo `json:"group_info"`
+	UpdateTime   time.Time  `json:"update_time"`
+	Pinned       bool       `json:"pinned"`
+	Muted        bool       `json:"muted"`
+	MuteUntil    *time.Time `json:"mute_until"`
+	MarkedUnread bool       `json:"marked_unread"`
+	Dissolved    bool       `json:"dissolved"` // 群已解散，只能查看
+}
+
+type UnreadSummaryDTO struct {
+	Total   int                `json:"total"`
+	Threads []*ThreadUnreadDTO `json:"threads"`
+}
+
+type ThreadUnreadDTO struct {
+	ThreadID     int64 `json:"thread_id"`
+	UnreadCount  int   `json:"unread_count"`
+	MarkedUnread bool  `json:"marked_unread"`
+}
+
+type DraftDTO struct {
+	ThreadID     int64     `json:"thread_id"`
+	Content      string    `json:"content"`
+	ReplyToMsgID *int64    `json:"reply_to_msg_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type Message struct {
@@ -58,3 +174,20 @@ type GroupInfo struct {
 	GroupName string     `json:"group_name"`
 	Avatar    string     `json:"avatar"`
 }
+
+// 定时消息，Status 为 pending / dispatching / sent / canceled / failed
+type ScheduledMessageDTO struct {
+	ID           int64      `json:"id"`
+	TargetID     int64      `json:"target_id,omitempty"`
+	GroupID      *uuid.UUID `json:"group_id,omitempty"`
+	Text         string     `json:"text"`
+	ReplyToMsgID *int64     `json:"reply_to_msg_id"`
+	Mentions     []int64    `json:"mentions"`
+	MentionAll   bool       `json:"mention_all"`
+	SendAt       time.Time  `json:"send_at"`
+	Status       string     `json:"status"`
+	MsgID        *int64     `json:"msg_id"` // 发送成功后的消息 ID
+	Error        string     `json:"error,omitempty"`
+	SentAt       *time.Time `json:"sent_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -3,8 +3,10 @@ package handler
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo"
 	"github.com/AdventureDe/LinkIM/message/service"
 	"github.com/google/uuid"
 
@@ -27,6 +29,8 @@ func (h *MessageHandler) SendMessageToSingle(c *gin.Context) {
 		TheOtherPersonId int64  `gorm:"column:the_other_person_id" json:"the_other_person_id"`
 		Text             string `gorm:"column:text" json:"text"`
 		Platform         int    `gorm:"column:platform" json:"platform"`
+		ReplyToMsgId     *int64 `json:"reply_to_msg_id"`
+		TTLSeconds       int64  `json:"ttl_seconds"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
@@ -38,7 +42,8 @@ func (h *MessageHandler) SendMessageToSingle(c *gin.Context) {
 	}
 	var lastMsgId *int64
 	var err error
-	lastMsgId, err = h.service.SendMessageToSingle(c.Request.Context(), input.UserId, input.TheOtherPersonId, input.Text)
+	lastMsgId, err = h.service.SendMessageToSingle(c.Request.Context(), input.UserId, input.TheOtherPersonId, input.Text,
+		&service.SendOptions{ReplyToMsgID: input.ReplyToMsgId, TTLSeconds: input.TTLSeconds})
 	if err != nil {
 		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
 		return
@@ -48,10 +53,14 @@ func (h *MessageHandler) SendMessageToSingle(c *gin.Context) {
 
 func (h *MessageHandler) SendMessageToGroup(c *gin.Context) {
 	var input struct {
-		UserId   int64     `json:"user_id"`
-		GroupId  uuid.UUID `json:"group_id"`
-		Text     string    `json:"text"`
-		Platform int       `json:"platform"`
+		UserId       int64     `json:"user_id"`
+		GroupId      uuid.UUID `json:"group_id"`
+		Text         string    `json:"text"`
+		Platform     int       `json:"platform"`
+		ReplyToMsgId *int64    `json:"reply_to_msg_id"`
+		Mentions     []int64   `json:"mentions"`
+		MentionAll   bool      `json:"mention_all"`
+		TTLSeconds   int64     `json:"ttl_seconds"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
@@ -63,7 +72,13 @@ func (h *MessageHandler) SendMessageToGroup(c *gin.Context) {
 	}
 	var lastMsgId *int64
 	var err error
-	lastMsgId, err = h.service.SendMessageToGroup(c.Request.Context(), input.UserId, input.GroupId, input.Text)
+	lastMsgId, err = h.service.SendMessageToGroup(c.Request.Context(), input.UserId, input.GroupId, input.Text,
+		&service.SendOptions{
+			ReplyToMsgID: input.ReplyToMsgId,
+			Mentions:     input.Mentions,
+			MentionAll:   input.MentionAll,
+			TTLSeconds:   input.TTLSeconds,
+		})
 	if err != nil {
 		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
 		return
@@ -71,6 +86,181 @@ func (h *MessageHandler) SendMessageToGroup(c *gin.Context) {
 	c.JSON(200, gin.H{"code": 0, "message": "send message ok", "lastMsgId": lastMsgId})
 }
 
+func (h *MessageHandler) AddReaction(c *gin.Context) {
+	h.updateReaction(c, true)
+}
+
+func (h *MessageHandler) RemoveReaction(c *gin.Context) {
+	h.updateReaction(c, false)
+}
+
+func (h *MessageHandler) updateReaction(c *gin.Context, add bool) {
+	var input struct {
+		UserID    int64  `json:"user_id"`
+		MessageID int64  `json:"message_id"`
+		Emoji     string `json:"emoji"`
+		Platform  int    `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	var err error
+	if add {
+		err = h.service.AddReaction(c.Request.Context(), input.UserID, input.MessageID, input.Emoji)
+	} else {
+		err = h.service.RemoveReaction(c.Request.Context(), input.UserID, input.MessageID, input.Emoji)
+	}
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "update reaction ok",
+	})
+}
+
+func (h *MessageHandler) ForwardMessages(c *gin.Context) {
+	var input struct {
+		UserID     int64                   `json:"user_id"`
+		MessageIDs []int64                 `json:"message_ids"`
+		Targets    []service.ForwardTarget `json:"targets"`
+		Merged     bool                    `json:"merged"`
+		Title      string                  `json:"title"`
+		Platform   int                     `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	results, err := h.service.ForwardMessages(c.Request.Context(), input.UserID, input.MessageIDs,
+		input.Targets, input.Merged, input.Title)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "forward messages ok",
+		"detail":  results,
+	})
+}
+
+func (h *MessageHandler) SearchMessages(c *gin.Context) {
+	var input struct {
+		UserId    int64  `form:"user_id"`
+		Keyword   string `form:"keyword"`
+		ThreadId  int64  `form:"thread_id"`
+		SenderId  int64  `form:"sender_id"`
+		Kind      int16  `form:"kind"`
+		StartTime int64  `form:"start_time"` // 秒级时间戳，0 表示不限
+		EndTime   int64  `form:"end_time"`
+		Cursor    int64  `form:"cursor"`
+		PageSize  int    `form:"page_size"`
+		Platform  int    `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	q := &repo.SearchQuery{
+		UserID:   input.UserId,
+		Keyword:  input.Keyword,
+		ThreadID: input.ThreadId,
+		SenderID: input.SenderId,
+		Kind:     input.Kind,
+		Cursor:   input.Cursor,
+		PageSize: input.PageSize,
+	}
+	if input.StartTime > 0 {
+		t := time.Unix(input.StartTime, 0)
+		q.StartTime = &t
+	}
+	if input.EndTime > 0 {
+		t := time.Unix(input.EndTime, 0)
+		q.EndTime = &t
+	}
+	result, err := h.service.SearchMessages(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "search messages ok",
+		"detail":  result,
+	})
+}
+
+func (h *MessageHandler) GetMessageWindow(c *gin.Context) {
+	var input struct {
+		UserId    int64  `form:"user_id"`
+		TargetId  int64  `form:"target_id"` // 单聊对方，与 group_id 二选一
+		GroupId   string `form:"group_id"`
+		MsgId     int64  `form:"msg_id"` // 锚点消息，优先于 seq
+		Seq       int64  `form:"seq"`
+		Direction string `form:"direction"` // before / after / around，默认 around
+		Limit     int    `form:"limit"`
+		Platform  int    `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	var groupID *uuid.UUID
+	if input.GroupId != "" {
+		id, err := uuid.Parse(input.GroupId)
+		if err != nil {
+			c.JSON(400, gin.H{"code": 1, "error": "invalid group_id"})
+			return
+		}
+		groupID = &id
+	}
+	window, err := h.service.GetMessageWindow(c.Request.Context(), input.UserId, input.TargetId, groupID,
+		input.MsgId, input.Seq, input.Direction, input.Limit)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get message window ok",
+		"detail":  window,
+	})
+}
+
+func (h *MessageHandler) GetGroupReplies(c *gin.Context) {
+	var input struct {
+		UserId    int64  `form:"user_id"`
+		GroupId   string `form:"group_id"` // gin 的 query 绑定不支持 uuid.UUID，先按字符串接收
+		MsgId     int64  `form:"msg_id"`
+		LastMsgId int64  `form:"last_msg_id"`
+		PageSize  int    `form:"page_size"`
+		Platform  int    `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	groupID, err := uuid.Parse(input.GroupId)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": "invalid group_id"})
+		return
+	}
+	replies, err := h.service.GetGroupReplies(c.Request.Context(), input.UserId, groupID,
+		input.MsgId, input.LastMsgId, input.PageSize)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get replies ok",
+		"detail":  replies,
+	})
+}
+
 func (h *MessageHandler) GetConversationMessagesSingle(c *gin.Context) {
 	var input struct {
 		UserId           int64 `json:"user_id"`
@@ -207,6 +397,27 @@ func (h *MessageHandler) WithdrawMessageGroup(c *gin.Context) {
 	})
 }
 
+func (h *MessageHandler) SetGroupRecallWindow(c *gin.Context) {
+	var input struct {
+		UserID   int64     `json:"user_id"`
+		GroupID  uuid.UUID `json:"group_id"`
+		Platform int       `json:"platform"`
+		Seconds  int64     `json:"seconds"` // <= 0 表示恢复全局配置
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetGroupRecallWindow(c.Request.Context(), input.UserID, input.GroupID, input.Seconds); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set recall window ok",
+	})
+}
+
 func (h *MessageHandler) UnWithdrawMessageSingle(c *gin.Context) {
 	var input struct {
 		UserID           int64  `json:"user_id"`
@@ -267,6 +478,86 @@ func (h *MessageHandler) UnWithdrawMessageGroup(c *gin.Context) {
 	})
 }
 
+func (h *MessageHandler) EditMessageSingle(c *gin.Context) {
+	var input struct {
+		UserID           int64  `json:"user_id"`
+		TheOtherPersonID int64  `json:"the_other_person_id"`
+		Platform         int    `json:"platform"`
+		MessageID        int64  `json:"message_id"`
+		NewText          string `json:"new_text"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if len(input.NewText) > 200 {
+		c.JSON(416, gin.H{"code": 1, "error": "文本长度超过200！"})
+		return
+	}
+	msg, err := h.service.EditMessageSingle(c.Request.Context(), input.UserID,
+		input.TheOtherPersonID, input.MessageID, input.NewText)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "edit message ok",
+		"detail":  msg,
+	})
+}
+
+func (h *MessageHandler) EditMessageGroup(c *gin.Context) {
+	var input struct {
+		UserID    int64     `json:"user_id"`
+		GroupID   uuid.UUID `json:"group_id"`
+		Platform  int       `json:"platform"`
+		MessageID int64     `json:"message_id"`
+		NewText   string    `json:"new_text"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if len(input.NewText) > 200 {
+		c.JSON(416, gin.H{"code": 1, "error": "文本长度超过200!"})
+		return
+	}
+	msg, err := h.service.EditMessageGroup(c.Request.Context(), input.UserID,
+		input.GroupID, input.MessageID, input.NewText)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "edit message ok",
+		"detail":  msg,
+	})
+}
+
+func (h *MessageHandler) GetMessageRevisions(c *gin.Context) {
+	var input struct {
+		UserID    int64 `form:"user_id"`
+		MessageID int64 `form:"message_id"`
+		Platform  int   `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	revisions, err := h.service.GetMessageRevisions(c.Request.Context(), input.UserID, input.MessageID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get message revisions ok",
+		"detail":  revisions,
+	})
+}
+
 func (h *MessageHandler) UpdateUnread(c *gin.Context) {
 	var input struct {
 		UserID   int64 `json:"user_id"`
@@ -287,6 +578,88 @@ func (h *MessageHandler) UpdateUnread(c *gin.Context) {
 	})
 }
 
+func (h *MessageHandler) DeleteMessagesForMe(c *gin.Context) {
+	var input struct {
+		UserID     int64   `json:"user_id"`
+		MessageIDs []int64 `json:"message_ids"`
+		Platform   int     `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.DeleteMessagesForMe(c.Request.Context(), input.UserID, input.MessageIDs); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "delete messages ok",
+	})
+}
+
+func (h *MessageHandler) ClearHistory(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.ClearHistory(c.Request.Context(), input.UserID, input.ThreadID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "clear history ok",
+	})
+}
+
+func (h *MessageHandler) DeleteConversation(c *gin.Context) {
+	var input struct {
+		UserID       int64 `json:"user_id"`
+		ThreadID     int64 `json:"thread_id"`
+		ClearHistory bool  `json:"clear_history"`
+		Platform     int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.DeleteConversation(c.Request.Context(), input.UserID, input.ThreadID, input.ClearHistory); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "delete conversation ok",
+	})
+}
+
+func (h *MessageHandler) SetDisappearingTimer(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Seconds  int64 `json:"seconds"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetDisappearingTimer(c.Request.Context(), input.UserID, input.ThreadID, input.Seconds); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "set disappearing timer ok",
+	})
+}
+
 func (h *MessageHandler) GetConversations(c *gin.Context) {
 	var input struct {
 		UserId   int64 `form:"user_id"`
@@ -308,9 +681,277 @@ func (h *MessageHandler) GetConversations(c *gin.Context) {
 		log.Printf("GetConversations error: %v", err)
 		c.JSON(502, gin.H{"code": 1, "error": "conversation is nil"})
 	}
+	c.JSON(200, gin.H{
+		"code":         0,
+		"message":      "get conversations ok",
+		"detail":       conversations,
+		"total_unread": service.TotalUnread(conversations),
+	})
+}
+
+func (h *MessageHandler) GetUnreadSummary(c *gin.Context) {
+	var input struct {
+		UserId   int64 `form:"user_id"`
+		Platform int   `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	summary, err := h.service.GetUnreadSummary(c.Request.Context(), input.UserId)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "get unread ok",
+		"detail":  summary,
+	})
+}
+
+func (h *MessageHandler) SaveDraft(c *gin.Context) {
+	var input struct {
+		UserID       int64  `json:"user_id"`
+		ThreadID     int64  `json:"thread_id"`
+		Content      string `json:"content"`
+		ReplyToMsgID *int64 `json:"reply_to_msg_id"`
+		Platform     int    `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	draft, err := h.service.SaveDraft(c.Request.Context(), input.UserID, input.ThreadID,
+		input.Content, input.ReplyToMsgID, input.Platform)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "save draft ok",
+		"detail":  draft,
+	})
+}
+
+func (h *MessageHandler) DeleteDraft(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.DeleteDraft(c.Request.Context(), input.UserID, input.ThreadID, input.Platform); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "delete draft ok",
+	})
+}
+
+func (h *MessageHandler) PinConversation(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Pinned   bool  `json:"pinned"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.PinConversation(c.Request.Context(), input.UserID, input.ThreadID, input.Pinned); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "pin conversation ok",
+	})
+}
+
+func (h *MessageHandler) MuteConversation(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Mute     bool  `json:"mute"`
+		Duration int64 `json:"duration"` // 单位秒，0 表示一直免打扰
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.MuteConversation(c.Request.Context(), input.UserID, input.ThreadID,
+		input.Mute, time.Duration(input.Duration)*time.Second); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "mute conversation ok",
+	})
+}
+
+func (h *MessageHandler) MarkConversationUnread(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ThreadID int64 `json:"thread_id"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.MarkConversationUnread(c.Request.Context(), input.UserID, input.ThreadID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "mark unread ok",
+	})
+}
+
+// 输入状态：state 为 typing / recording / stopped，target_id 和 group_id 二选一
+func (h *MessageHandler) SendTyping(c *gin.Context) {
+	var input struct {
+		UserID   int64      `json:"user_id"`
+		TargetID int64      `json:"target_id"`
+		GroupID  *uuid.UUID `json:"group_id"`
+		State    string     `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.SendTyping(c.Request.Context(), input.UserID, input.TargetID, input.GroupID, input.State); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "send typing ok",
+	})
+}
+
+// 定时消息：target_id 和 group_id 二选一，send_at 为秒级时间戳
+func (h *MessageHandler) ScheduleMessage(c *gin.Context) {
+	var input struct {
+		UserID       int64      `json:"user_id"`
+		TargetID     int64      `json:"target_id"`
+		GroupID      *uuid.UUID `json:"group_id"`
+		Text         string     `json:"text"`
+		SendAt       int64      `json:"send_at"`
+		ReplyToMsgID *int64     `json:"reply_to_msg_id"`
+		Mentions     []int64    `json:"mentions"`
+		MentionAll   bool       `json:"mention_all"`
+		Platform     int        `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if len(input.Text) > 200 {
+		c.JSON(416, gin.H{"code": 1, "error": "文本长度超过200!"})
+		return
+	}
+	scheduled, err := h.service.ScheduleMessage(c.Request.Context(), input.UserID, input.TargetID, input.GroupID,
+		input.Text, time.Unix(input.SendAt, 0), &service.SendOptions{
+			ReplyToMsgID: input.ReplyToMsgID,
+			Mentions:     input.Mentions,
+			MentionAll:   input.MentionAll,
+		})
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "schedule message ok",
+		"detail":  scheduled,
+	})
+}
+
+func (h *MessageHandler) UpdateScheduledMessage(c *gin.Context) {
+	var input struct {
+		UserID       int64   `json:"user_id"`
+		ID           int64   `json:"id"`
+		Text         string  `json:"text"`
+		SendAt       int64   `json:"send_at"`
+		ReplyToMsgID *int64  `json:"reply_to_msg_id"`
+		Mentions     []int64 `json:"mentions"`
+		MentionAll   bool    `json:"mention_all"`
+		Platform     int     `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if len(input.Text) > 200 {
+		c.JSON(416, gin.H{"code": 1, "error": "文本长度超过200!"})
+		return
+	}
+	scheduled, err := h.service.UpdateScheduledMessage(c.Request.Context(), input.UserID, input.ID, input.Text,
+		time.Unix(input.SendAt, 0), &service.SendOptions{
+			ReplyToMsgID: input.ReplyToMsgID,
+			Mentions:     input.Mentions,
+			MentionAll:   input.MentionAll,
+		})
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "update scheduled message ok",
+		"detail":  scheduled,
+	})
+}
+
+func (h *MessageHandler) CancelScheduledMessage(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id"`
+		ID       int64 `json:"id"`
+		Platform int   `json:"platform"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.CancelScheduledMessage(c.Request.Context(), input.UserID, input.ID); err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "cancel scheduled message ok",
+	})
+}
+
+func (h *MessageHandler) ListScheduledMessages(c *gin.Context) {
+	var input struct {
+		UserID   int64 `form:"user_id"`
+		Platform int   `form:"platform"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	list, err := h.service.ListScheduledMessages(c.Request.Context(), input.UserID)
+	if err != nil {
+		c.JSON(502, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
 	c.JSON(200, gin.H{
 		"code":    0,
-		"message": "get conversations ok",
-		"detail":  conversations,
+		"message": "list scheduled messages ok",
+		"detail":  list,
 	})
 }
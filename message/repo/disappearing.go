@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 一个会话里本轮过期的消息，供上层清缓存和通知客户端
+type ExpiredThread struct {
+	Thread        model.Thread
+	MsgIDs        []int64
+	LastMessageID *int64 // 更新后的最后一条消息，nil 表示会话里已经没有可见消息
+}
+
+// SetDisappearTimer 设置会话的阅后即焚计时，seconds <= 0 表示关闭；
+// 单聊双方都可以设置，群聊只有群主/管理员可以设置
+func (r *messageRepo) SetDisappearTimer(ctx context.Context, userID, threadID int64, seconds int64) (*model.Thread, error) {
+	db := r.db.WithContext(ctx)
+	var thread model.Thread
+	if err := db.Where("id = ?", threadID).First(&thread).Error; err != nil {
+		return nil, err
+	}
+	if thread.GroupID != nil {
+		role, err := r.getGroupRole(ctx, *thread.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role != grouppb.Role_ROLE_OWNER && role != grouppb.Role_ROLE_ADMIN {
+			return nil, errors.New("insufficient permissions")
+		}
+	} else if ok, _ := r.canReadThread(ctx, userID, &thread); !ok {
+		return nil, errors.New("insufficient permissions")
+	}
+
+	var timer *int64
+	if seconds > 0 {
+		timer = &seconds
+	}
+	if err := db.Model(&model.Thread{}).
+		Where("id = ?", thread.ID).
+		Update("disappear_seconds", timer).Error; err != nil {
+		return nil, err
+	}
+	thread.DisappearSeconds = timer
+	return &thread, nil
+}
+
+// ExpireMessages 清除到期的消息：保留消息行作为墓碑，清空内容、编辑历史和表情回应，
+// 并把最后一条消息指向它们的会话改指到剩下的最新消息。
+// FOR UPDATE SKIP LOCKED 保证多个实例同时运行时每条消息只处理一次
+func (r *messageRepo) ExpireMessages(ctx context.Context, limit int) ([]*ExpiredThread, error) {
+	var result []*ExpiredThread
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var messages []*model.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("expires_at <= ? AND is_expired = ?", time.Now(), false).
+			Order("expires_at ASC").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(messages))
+		byThread := make(map[int64]*ExpiredThread)
+		threadIDs := make([]int64, 0)
+		for _, m := range messages {
+			ids = append(ids, m.MsgID)
+			et, ok := byThread[m.ThreadID]
+			if !ok {
+				et = &ExpiredThread{}
+				byThread[m.ThreadID] = et
+				threadIDs = append(threadIDs, m.ThreadID)
+			}
+			et.MsgIDs = append(et.MsgIDs, m.MsgID)
+		}
+
+		if err := tx.Model(&model.Message{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"is_expired":    true,
+				"content":       "",
+				"search_tokens": "",
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&model.MessageRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&model.MessageReaction{}).Error; err != nil {
+			return err
+		}
+
+		var threads []model.Thread
+		if err := tx.Where("id IN ?", threadIDs).Find(&threads).Error; err != nil {
+			return err
+		}
+		for _, thread := range threads {
+			et := byThread[thread.ID]
+			et.Thread = thread
+
+			var lastMsg model.Message
+			err := tx.Where("thread_id = ? AND is_withdrawed = ? AND is_expired = ?", thread.ID, false, false).
+				Order("seq_id DESC").First(&lastMsg).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				et.LastMessageID = &lastMsg.MsgID
+			}
+			// 只改最后一条消息正好过期了的会话，UpdateColumn 不改 updated_at，会话不会因此跳到列表顶部
+			if err := tx.Model(&model.Conversation{}).
+				Where("thread_id = ? AND last_message_id IN ?", thread.ID, et.MsgIDs).
+				UpdateColumn("last_message_id", et.LastMessageID).Error; err != nil {
+				return err
+			}
+			if err := setLargeGroupLastMessage(tx, thread.ID, et.LastMessageID); err != nil {
+				return err
+			}
+			result = append(result, et)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 群还没有 thread（从来没有消息）时返回 0
+func findGroupThreadID(db *gorm.DB, groupID uuid.UUID) (int64, error) {
+	var thread model.Thread
+	err := db.Select("id").Where("group_id = ?", groupID).First(&thread).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return thread.ID, err
+}
+
+// RemoveGroupConversations 成员退群后把他们的群会话从列表里移除，未读和 @ 提醒一并清掉
+func (r *messageRepo) RemoveGroupConversations(ctx context.Context, groupID uuid.UUID, userIDs []int64) (int64, error) {
+	db := r.db.WithContext(ctx)
+	threadID, err := findGroupThreadID(db, groupID)
+	if err != nil || threadID == 0 || len(userIDs) == 0 {
+		return threadID, err
+	}
+	return threadID, db.Model(&model.Conversation{}).
+		Where("owner_id IN ? AND thread_id = ?", userIDs, threadID).
+		Updates(map[string]interface{}{
+			"is_deleted":    true,
+			"unread_count":  0,
+			"mention_count": 0,
+			"marked_unread": false,
+		}).Error
+}
+
+// TombstoneGroupThread 群解散后把 thread 标记为已解散，会话保留但清空未读，之后只会写入系统消息。
+// floors 是解散前每个成员的可见起点，群里的成员记录已经删除，之后靠它判断谁还能查看历史
+func (r *messageRepo) TombstoneGroupThread(ctx context.Context, groupID uuid.UUID, floors map[int64]int64) (int64, error) {
+	var threadID int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		thread, err := getOrCreateGroupThread(tx, groupID)
+		if err != nil {
+			return err
+		}
+		threadID = thread.ID
+		if thread.DissolvedAt != nil {
+			return nil
+		}
+		if err := tx.Model(thread).Update("dissolved_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := saveHistoryFloors(tx, thread.ID, floors); err != nil {
+			return err
+		}
+		return tx.Model(&model.Conversation{}).
+			Where("thread_id = ?", thread.ID).
+			Updates(map[string]interface{}{
+				"unread_count":  0,
+				"mention_count": 0,
+				"marked_unread": false,
+			}).Error
+	})
+	return threadID, err
+}
+
+// 把成员的可见起点写到各自的会话上，分批用一张临时表关联更新
+func saveHistoryFloors(tx *gorm.DB, threadID int64, floors map[int64]int64) error {
+	const batchSize = 500
+	values := make([]string, 0, batchSize)
+	args := make([]interface{}, 0, 2*batchSize+1)
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		err := tx.Exec(`UPDATE conversations c SET history_floor = f.floor_seq
+			FROM (VALUES `+strings.Join(values, ", ")+`) AS f(owner_id, floor_seq)
+			WHERE c.thread_id = ? AND c.owner_id = f.owner_id`, append(args, threadID)...).Error
+		values, args = values[:0], args[:0]
+		return err
+	}
+	for userID, floor := range floors {
+		values = append(values, "(?::bigint, ?::bigint)")
+		args = append(args, userID, floor)
+		if len(values) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// 已解散的群里用户还能看到的最早位置，不是解散前的成员或者会话已删除时返回 ErrNotGroupMember
+func dissolvedGroupFloor(db *gorm.DB, userID, threadID int64) (int64, error) {
+	var conv model.Conversation
+	err := db.Select("history_floor").
+		Where("owner_id = ? AND thread_id = ? AND is_deleted = ?", userID, threadID, false).
+		First(&conv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && conv.HistoryFloor == nil) {
+		return 0, ErrNotGroupMember
+	}
+	if err != nil {
+		return 0, err
+	}
+	return *conv.HistoryFloor, nil
+}
+
+// 用户还能搜索的已解散的群会话
+func dissolvedGroupScopes(db *gorm.DB, userID int64) ([]GroupScope, error) {
+	scopes := make([]GroupScope, 0)
+	err := db.Table("conversations AS c").
+		Select("c.thread_id, c.history_floor AS floor").
+		Joins("JOIN threads t ON t.id = c.thread_id").
+		Where("c.owner_id = ? AND c.is_deleted = ? AND c.history_floor IS NOT NULL AND t.dissolved_at IS NOT NULL",
+			userID, false).
+		Scan(&scopes).Error
+	return scopes, err
+}
+
+// EnsureGroupConversations 大群发消息不会给成员创建会话，新成员入群时直接创建，
+// 已读游标从当前位置开始；普通群由下一条消息创建，这里不处理
+func (r *messageRepo) EnsureGroupConversations(ctx context.Context, groupID uuid.UUID, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var thread model.Thread
+	err := r.db.WithContext(ctx).Where("group_id = ?", groupID).First(&thread).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !thread.LargeGroup || thread.LastMessageID == nil {
+		return nil
+	}
+	return upsertReadCursor(r.db.WithContext(ctx), userIDs, thread.ID, *thread.LastMessageID, thread.LastSeq)
+}
@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 大群发消息：只写消息本身、Thread 和发送者的会话，
+// 成员的会话在发消息、被 @ 或者进入会话时才创建，未读数按已读游标在读取时计算
+func sendToLargeGroup(tx *gorm.DB, thread *model.Thread, msg *model.Message, senderIsMember bool, memberIDs []int64,
+	opts *SendOptions) error {
+	if !thread.LargeGroup {
+		// 刚切换成大群：按现有的未读数初始化已读游标
+		if err := tx.Model(&model.Conversation{}).
+			Where("thread_id = ?", thread.ID).
+			UpdateColumn("read_seq", gorm.Expr("GREATEST(? - unread_count, 0)", msg.SeqID-1)).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(thread).Updates(map[string]interface{}{
+		"large_group":     true,
+		"last_message_id": msg.MsgID,
+		"last_seq":        gorm.Expr("GREATEST(last_seq, ?)", msg.SeqID),
+	}).Error; err != nil {
+		return err
+	}
+	thread.LargeGroup = true
+	thread.LastMessageID = &msg.MsgID
+
+	// 发送者自己发的消息直接算已读
+	if senderIsMember {
+		if err := upsertReadCursor(tx, []int64{msg.SenderID}, thread.ID, msg.MsgID, msg.SeqID); err != nil {
+			return err
+		}
+	}
+	return saveLargeGroupMentions(tx, msg, memberIDs, opts)
+}
+
+// 创建或更新会话的已读游标，游标只会前进
+func upsertReadCursor(tx *gorm.DB, ownerIDs []int64, threadID, lastMsgID, readSeq int64) error {
+	convs := make([]model.Conversation, 0, len(ownerIDs))
+	for _, id := range ownerIDs {
+		convs = append(convs, model.Conversation{
+			OwnerID:       id,
+			ThreadID:      threadID,
+			LastMessageID: &lastMsgID,
+			ReadSeq:       readSeq,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "owner_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_message_id": lastMsgID,
+			"read_seq":        gorm.Expr("GREATEST(conversations.read_seq, ?)", readSeq),
+			"is_deleted":      false,
+		}),
+	}).Create(&convs).Error
+}
+
+// 大群只给直接 @ 到的成员累加提醒数，@所有人在读取时按已读游标计算
+func saveLargeGroupMentions(tx *gorm.DB, msg *model.Message, memberIDs []int64, opts *SendOptions) error {
+	if opts == nil || len(opts.Mentions) == 0 {
+		return nil
+	}
+	members := make(map[int64]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		members[id] = true
+	}
+	mentions := make([]model.MessageMention, 0, len(opts.Mentions))
+	convs := make([]model.Conversation, 0, len(opts.Mentions))
+	for _, uid := range opts.Mentions {
+		if !members[uid] {
+			continue
+		}
+		members[uid] = false // 去重
+		mentions = append(mentions, model.MessageMention{MessageID: msg.MsgID, UserID: uid})
+		// 还没有会话的成员从这条消息开始算未读
+		convs = append(convs, model.Conversation{
+			OwnerID:       uid,
+			ThreadID:      msg.ThreadID,
+			LastMessageID: &msg.MsgID,
+			ReadSeq:       msg.SeqID - 1,
+			MentionCount:  1,
+		})
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+	if err := tx.Create(&mentions).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "owner_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"mention_count": gorm.Expr("conversations.mention_count + 1"),
+			"is_deleted":    false,
+		}),
+	}).Create(&convs).Error
+}
+
+// 大群切回普通群：按已读游标回填未读数和最后一条消息，之后继续逐个成员累加
+func leaveLargeGroupMode(tx *gorm.DB, thread *model.Thread) error {
+	if err := tx.Model(&model.Conversation{}).
+		Where("thread_id = ?", thread.ID).
+		Updates(map[string]interface{}{
+			"unread_count":    gorm.Expr("GREATEST(? - GREATEST(read_seq, cleared_seq), 0)", thread.LastSeq),
+			"last_message_id": thread.LastMessageID,
+		}).Error; err != nil {
+		return err
+	}
+	thread.LargeGroup = false
+	return tx.Model(thread).Update("large_group", false).Error
+}
+
+// 大群会话按已读游标计算出来的未读数
+type largeGroupCount struct {
+	ThreadID   int64
+	Unread     int
+	MentionAll int   // 未读消息中 @所有人 的数量
+	LastSeq    int64 // 和计数同一条语句读出的 Thread.LastSeq
+}
+
+// 用户所有大群会话的未读数；没有未读的会话也会返回，计数为 0，已解散的群不算
+func largeGroupCounts(db *gorm.DB, userID int64, threadIDs ...int64) (map[int64]*largeGroupCount, error) {
+	filter := ""
+	args := []interface{}{userID}
+	if len(threadIDs) > 0 {
+		filter = " AND c.thread_id IN ?"
+		args = append(args, threadIDs)
+	}
+	var rows []*largeGroupCount
+	if err := db.Raw(`
+		SELECT c.thread_id,
+			COUNT(m.id) AS unread,
+			COUNT(m.id) FILTER (WHERE m.mention_all) AS mention_all,
+			t.last_seq
+		FROM conversations c
+		JOIN threads t ON t.id = c.thread_id AND t.large_group AND t.dissolved_at IS NULL
+		LEFT JOIN messages m ON m.thread_id = c.thread_id
+			AND m.seq_id > GREATEST(c.read_seq, c.cleared_seq)
+			AND m.sender_id <> c.owner_id
+			AND NOT m.is_withdrawed
+			AND NOT m.is_expired
+		WHERE c.owner_id = ? AND c.is_deleted = false`+filter+`
+		GROUP BY c.thread_id, t.last_seq`, args...).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]*largeGroupCount, len(rows))
+	for _, row := range rows {
+		counts[row.ThreadID] = row
+	}
+	return counts, nil
+}
+
+// 大群的最后一条消息记在 Thread 上，撤回、恢复和过期时跟会话一起修正
+func setLargeGroupLastMessage(tx *gorm.DB, threadID int64, lastMsgID *int64) error {
+	return tx.Model(&model.Thread{}).
+		Where("id = ? AND large_group = ?", threadID, true).
+		UpdateColumn("last_message_id", lastMsgID).Error
+}
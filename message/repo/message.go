@@ -36,7 +36,84 @@ type ConversationGroupMessages struct {
 type MessageWithUser struct {
 	Message       model.Message
 	User          UserInfo
-	GroupNickname string `json:"group_nickname"`
+	GroupNickname string         `json:"group_nickname"`
+	WithdrawnBy   *UserInfo      `json:"withdrawn_by"` // 撤回操作人，用于展示 "X recalled a message"
+	ReplyTo       *QuotedMessage `json:"reply_to"`     // 被引用的消息摘要
+	ReplyCount    int            `json:"reply_count"`  // 群聊中回复这条消息的数量
+	Mentions      []int64        `json:"mentions"`     // 群聊中 @ 到的用户
+	Reactions     []*Reaction    `json:"reactions"`    // 按表情聚合的回应
+}
+
+// 同一个表情的回应汇总
+type Reaction struct {
+	Emoji   string  `json:"emoji"`
+	Count   int     `json:"count"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// 被引用消息的摘要，原消息撤回或过期后只保留标记
+type QuotedMessage struct {
+	MsgID       int64    `json:"msg_id"`
+	Sender      UserInfo `json:"sender"`
+	Kind        int16    `json:"kind"`
+	Content     string   `json:"content"`
+	IsWithdrawn bool     `json:"is_withdrawn"`
+	IsExpired   bool     `json:"is_expired"`
+	Unavailable bool     `json:"unavailable"` // 被引用的消息在入群之前，对当前用户不可见
+}
+
+// 引用摘要最多保留的字符数
+const quoteSnippetLen = 100
+
+// 发送消息的可选参数
+type SendOptions struct {
+	Kind          int16 // 为 0 时按文本消息处理
+	ForwardedFrom *int64
+	ReplyToMsgID  *int64
+	Mentions      []int64 // 群聊中 @ 的用户，不在群里的会被忽略
+	MentionAll    bool
+	TTLSeconds    int64 // 单条消息的过期时间，为 0 时使用会话的阅后即焚设置
+}
+
+func (o *SendOptions) kind() int16 {
+	if o == nil || o.Kind == 0 {
+		return model.KindText
+	}
+	return o.Kind
+}
+
+func (o *SendOptions) forwardedFrom() *int64 {
+	if o == nil {
+		return nil
+	}
+	return o.ForwardedFrom
+}
+
+// 消息的过期时间：单条消息的 TTL 优先，其次是会话的阅后即焚设置
+func (o *SendOptions) expiresAt(thread *model.Thread) *time.Time {
+	ttl := int64(0)
+	if o != nil && o.TTLSeconds > 0 {
+		ttl = o.TTLSeconds
+	} else if thread.DisappearSeconds != nil {
+		ttl = *thread.DisappearSeconds
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	t := time.Now().Add(time.Duration(ttl) * time.Second)
+	return &t
+}
+
+// 引用的消息不存在或不属于当前会话
+var ErrInvalidReplyTarget = errors.New("invalid reply target")
+
+// 用户对会话的个性化设置：置顶、免打扰、标记未读
+type ConversationSettings struct {
+	Pinned       bool       `json:"pinned"`
+	PinnedAt     *time.Time `json:"pinned_at"`
+	Muted        bool       `json:"muted"` // 已经考虑过 MuteUntil 是否到期
+	MuteUntil    *time.Time `json:"mute_until"`
+	MarkedUnread bool       `json:"marked_unread"`
 }
 
 // 单聊会话信息
@@ -46,25 +123,33 @@ type SingleConversation struct {
 	LastMessage *model.Message
 	UnreadCount int
 	UpdateTime  time.Time
+	ConversationSettings
 }
 
 // 群聊会话信息
 type GroupConversation struct {
-	ThreadID    int64
-	GroupID     uuid.UUID
-	LastMessage *model.Message
-	UnreadCount int
-	UpdateTime  time.Time
+	ThreadID     int64
+	GroupID      uuid.UUID
+	LastMessage  *model.Message
+	UnreadCount  int
+	MentionCount int
+	UpdateTime   time.Time
+	Dissolved    bool // 群已解散，会话只读
+	ConversationSettings
 }
 
 // 最终返回给前端的结构（包含用户信息）
 type ConversationWithUser struct {
-	ThreadID    int64          `json:"thread_id"`
-	LastMessage *model.Message `json:"last_message"`
-	UnreadCount int            `json:"unread_count"`
-	UserInfo    *UserInfo      `json:"user_info"`
-	GroupInfo   *GroupInfo     `json:"group_info"`
-	UpdateTime  time.Time      `json:"update_time"`
+	ThreadID     int64          `json:"thread_id"`
+	LastMessage  *model.Message `json:"last_message"`
+	UnreadCount  int            `json:"unread_count"`
+	MentionCount int            `json:"mention_count"`
+	UserInfo     *UserInfo      `json:"user_info"`
+	GroupInfo    *GroupInfo     `json:"group_info"`
+	UpdateTime   time.Time      `json:"update_time"`
+	Draft        *model.Draft   `json:"draft"`
+	Dissolved    bool           `json:"dissolved"`
+	ConversationSettings
 }
 
 type UserInfo struct {
@@ -81,40 +166,98 @@ type GroupInfo struct {
 
 type MessageRepo interface {
 	SendMessageToSingle(ctx context.Context, message_id, seq_id, senderid, targetid int64,
-		text string) (lastMsgId *int64, err error)
+		text string, opts *SendOptions) (*model.Message, error)
 	SendMessageToGroup(ctx context.Context, message_id, seq_id, senderID int64, groupID uuid.UUID,
-		text string) (lastMsgId *int64, err error)
+		text string, opts *SendOptions) (*model.Message, []int64, error)
+	AddReaction(ctx context.Context, userID, messageID int64, emoji string) (*model.Message, error)
+	RemoveReaction(ctx context.Context, userID, messageID int64, emoji string) (*model.Message, error)
+	GetForwardableMessages(ctx context.Context, userID int64, messageIDs []int64) ([]*model.Message, error)
+	GetUserInfos(ctx context.Context, userIDs []int64) (map[int64]UserInfo, error)
+	CanMentionAll(ctx context.Context, groupID uuid.UUID, userID int64) (bool, error)
+	CheckReplyTarget(ctx context.Context, replyToMsgID, senderID, targetID int64, groupID *uuid.UUID) error
+	GetGroupReplies(ctx context.Context, userID int64, groupID uuid.UUID, rootMsgID, lastMsgID int64,
+		pageSize int) (*ConversationGroupMessages, error)
 	GetConversationMessagesSingle(ctx context.Context, senderID, targetID int64,
 		lastMsgID int64, pageSize int) (*ConversationMessages, error)
 	GetConversationMessagesGroup(ctx context.Context, senderID int64, groupID uuid.UUID,
 		lastMsgID int64, pageSize int) (*ConversationGroupMessages, error)
-	WithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64, messageID int64) (int64, error)
+	WithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64, messageID int64,
+		window time.Duration) (int64, error)
 	UnWithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64,
-		messageID int64, newText string) (lastMessageID int64, err error)
-	WithdrawMessageGroup(ctx context.Context, senderID int64, groupID uuid.UUID, messageID int64) (int64, error)
+		messageID int64, newText string, window time.Duration) (lastMessageID int64, err error)
+	WithdrawMessageGroup(ctx context.Context, operatorID int64, groupID uuid.UUID, messageID int64,
+		defaultWindow time.Duration) (int64, error)
+	SetGroupRecallWindow(ctx context.Context, operatorID int64, groupID uuid.UUID, seconds int64) error
 	UnWithdrawMessageGroup(ctx context.Context, senderID int64, groupID uuid.UUID, messageID int64,
-		newText string) (lastMessageID int64, err error)
+		newText string, window time.Duration) (lastMessageID int64, err error)
+	EditMessageSingle(ctx context.Context, editorID, targetID, messageID int64, newText string,
+		window time.Duration) (*model.Message, error)
+	EditMessageGroup(ctx context.Context, editorID int64, groupID uuid.UUID, messageID int64, newText string,
+		window time.Duration, allowAdmin bool) (*model.Message, error)
+	GetMessageRevisions(ctx context.Context, userID, messageID int64) ([]*model.MessageRevision, error)
+	GetGroupMemberIDs(ctx context.Context, groupID uuid.UUID) ([]int64, error)
+	CheckGroupMember(ctx context.Context, groupID uuid.UUID, userID int64) error
+	CheckGroupReadable(ctx context.Context, groupID uuid.UUID, userID int64) error
+	CheckCanSpeak(ctx context.Context, groupID uuid.UUID, userID int64) error
+	GetBlockedBy(ctx context.Context, userID int64, candidateIDs []int64) (map[int64]bool, error)
+	HideMessages(ctx context.Context, userID int64, messageIDs []int64) ([]*model.Thread, error)
+	ClearHistory(ctx context.Context, userID, threadID int64) (*model.Thread, error)
+	DeleteConversation(ctx context.Context, userID, threadID int64, clearHistory bool) (*model.Thread, error)
 	UpdateUnread(ctx context.Context, userID, threadID int64) error
+	SetPinned(ctx context.Context, userID, threadID int64, pinned bool) error
+	SetMute(ctx context.Context, userID, threadID int64, mute bool, until *time.Time) error
+	MarkUnread(ctx context.Context, userID, threadID int64) error
+	GetUnreadStates(ctx context.Context, userID int64, threadIDs ...int64) ([]*UnreadState, error)
 	GetSingleConversationsFromDB(ctx context.Context, userID int64) ([]*SingleConversation, error) //辅助函数
 	GetGroupConversationsFromDB(ctx context.Context, userID int64) ([]*GroupConversation, error)   //辅助函数
 	GetConversations(ctx context.Context, userID int64) ([]*ConversationWithUser, error)
+	SearchMessages(ctx context.Context, q *SearchQuery) ([]*SearchResult, bool, error)
+	SaveDraft(ctx context.Context, userID, threadID int64, content string, replyToMsgID *int64) (*model.Draft, error)
+	DeleteDraft(ctx context.Context, userID, threadID int64) (bool, error)
+	GetDraftThreadIDs(ctx context.Context, userID int64) ([]int64, error)
+	GetMessageWindow(ctx context.Context, userID, targetID int64, groupID *uuid.UUID, anchorMsgID, anchorSeq int64,
+		direction string, limit int) (*MessageWindow, error)
+	MessageExists(ctx context.Context, msgID int64) (bool, error)
+	// 阅后即焚
+	SetDisappearTimer(ctx context.Context, userID, threadID int64, seconds int64) (*model.Thread, error)
+	ExpireMessages(ctx context.Context, limit int) ([]*ExpiredThread, error)
+	// 定时消息
+	CreateScheduledMessage(ctx context.Context, sm *model.ScheduledMessage) error
+	GetScheduledMessage(ctx context.Context, userID, id int64) (*model.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, sm *model.ScheduledMessage) error
+	CancelScheduledMessage(ctx context.Context, userID, id int64) error
+	ListScheduledMessages(ctx context.Context, userID int64) ([]*model.ScheduledMessage, error)
+	ClaimDueScheduledMessages(ctx context.Context, limit int, newMsgID func() int64) ([]*model.ScheduledMessage, error)
+	FinishScheduledMessage(ctx context.Context, id int64, sendErr error) error
+	// 入群、退群、解散
+	RemoveGroupConversations(ctx context.Context, groupID uuid.UUID, userIDs []int64) (threadID int64, err error)
+	TombstoneGroupThread(ctx context.Context, groupID uuid.UUID, floors map[int64]int64) (threadID int64, err error)
+	EnsureGroupConversations(ctx context.Context, groupID uuid.UUID, userIDs []int64) error
 }
 
 type messageRepo struct {
 	db          *gorm.DB
 	userClient  userpb.UserServiceClient
 	groupClient grouppb.GroupServiceClient
+	search      MessageSearchIndex
 }
 
-func NewMessageRepo(db *gorm.DB, m *messageService) MessageRepo {
+// search 为 nil 时使用 Postgres 全文检索
+func NewMessageRepo(db *gorm.DB, m *messageService, search MessageSearchIndex) MessageRepo {
+	if search == nil {
+		search = NewPostgresSearchIndex(db)
+	}
 	return &messageRepo{
 		db:          db,
 		userClient:  m.userClient,
 		groupClient: m.groupClient,
+		search:      search,
 	}
 }
 
-func (r *messageRepo) SendMessageToSingle(ctx context.Context, message_id, seq_id, senderID, targetID int64, text string) (lastMsgId *int64, err error) {
+// SendMessageToSingle 持久化单聊消息，返回写入的消息（包含 ThreadID）
+func (r *messageRepo) SendMessageToSingle(ctx context.Context, message_id, seq_id, senderID, targetID int64, text string,
+	opts *SendOptions) (persisted *model.Message, err error) {
 	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. 查找或创建 thread (单聊)
 		var thread model.Thread
@@ -139,12 +282,15 @@ func (r *messageRepo) SendMessageToSingle(ctx context.Context, message_id, seq_i
 
 		// 2. 插入消息
 		msg := model.Message{
-			MsgID:    message_id,
-			SeqID:    seq_id,
-			ThreadID: thread.ID,
-			SenderID: senderID,
-			Kind:     1, // text
-			Content:  text,
+			MsgID:         message_id,
+			SeqID:         seq_id,
+			ThreadID:      thread.ID,
+			SenderID:      senderID,
+			Kind:          opts.kind(),
+			Content:       text,
+			ReplyToMsgID:  replyTarget(tx, thread.ID, opts),
+			ForwardedFrom: opts.forwardedFrom(),
+			ExpiresAt:     opts.expiresAt(&thread),
 		}
 		if err := tx.Create(&msg).Error; err != nil {
 			return err
@@ -169,14 +315,12 @@ func (r *messageRepo) SendMessageToSingle(ctx context.Context, message_id, seq_i
 		if err := tx.Create(&status).Error; err != nil {
 			return err
 		}
-		var temp int64
-		temp, err = GetLastMessageID(tx, ctx)
-		if err != nil {
-			return err
-		}
-		lastMsgId = &temp
+		persisted = &msg
 		return nil
 	})
+	if err == nil {
+		r.indexMessage(ctx, persisted)
+	}
 	return
 }
 
@@ -196,8 +340,9 @@ func upsertConversation(tx *gorm.DB, ownerID, threadID, lastMsgID int64, unreadD
 		return err
 	}
 
-	// 已存在则更新
+	// 已存在则更新；被删除的会话收到新消息时重新出现
 	conv.LastMessageID = &lastMsgID
+	conv.IsDeleted = false
 	if unreadDelta > 0 {
 		conv.UnreadCount += unreadDelta
 	}
@@ -220,6 +365,7 @@ func upsertConversationsBatch(tx *gorm.DB, ownerIDs []int64, threadID, lastMsgID
 		DoUpdates: clause.Assignments(map[string]interface{}{
 			"last_message_id": lastMsgID,
 			"unread_count":    gorm.Expr("conversations.unread_count + ?", unreadDelta),
+			"is_deleted":      false,
 		}),
 	}).Create(&convs).Error
 }
@@ -229,7 +375,31 @@ func GetLastMessageID(tx *gorm.DB, ctx context.Context) (lastMessageID int64, er
 	return
 }
 
-// SendMessageToGroup 负责发送群消息
+// 大群发消息只需要确认被 @ 的用户是群成员，不拉取完整的成员列表
+func (r *messageRepo) mentionedMembers(ctx context.Context, groupID uuid.UUID, senderID int64, opts *SendOptions) ([]int64, error) {
+	if opts == nil || len(opts.Mentions) == 0 {
+		return nil, nil
+	}
+	seen := make(map[int64]bool, len(opts.Mentions))
+	memberIDs := make([]int64, 0, len(opts.Mentions))
+	for _, uid := range opts.Mentions {
+		if uid == senderID || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		member, err := r.getGroupMember(ctx, groupID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if member.IsMember {
+			memberIDs = append(memberIDs, uid)
+		}
+	}
+	return memberIDs, nil
+}
+
+// SendMessageToGroup 负责发送群消息，返回写入的消息和需要推送的成员（不含发送者）；
+// 大群不拉取成员列表，返回的成员为 nil，由调用方自己决定怎么推送
 // 设计原则：
 // 1. 远程调用放事务外，避免长事务
 // 2. 数据写入保证强一致性
@@ -241,26 +411,52 @@ func (r *messageRepo) SendMessageToGroup(
 	senderID int64,
 	groupID uuid.UUID,
 	text string,
-) (*int64, error) {
+	opts *SendOptions,
+) (*model.Message, []int64, error) {
 
 	// ====== 第一阶段：事务外调用远程服务 ======
-	// 获取群成员（避免在事务内调用 gRPC）
-	res, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
-		GroupId: groupID.String(),
-	})
+	// 发送者的成员状态带缓存，同时告诉我们是不是大群；
+	// 发送者已经不在群里（例如退群的系统消息）时按 Thread 上记录的模式处理
+	sender, err := r.getGroupMember(ctx, groupID, senderID)
 	if err != nil {
-		return nil, fmt.Errorf("list group members failed: %w", err)
+		return nil, nil, err
+	}
+	senderIsMember := sender.IsMember
+	largeGroup := sender.LargeGroup
+	if !senderIsMember {
+		var threads []*model.Thread
+		if err := r.db.WithContext(ctx).Select("large_group").
+			Where("group_id = ?", groupID).Limit(1).
+			Find(&threads).Error; err != nil {
+			return nil, nil, err
+		}
+		largeGroup = len(threads) > 0 && threads[0].LargeGroup
 	}
 
-	// 过滤发送者本人，生成需要更新未读的用户列表
-	memberIDs := make([]int64, 0, len(res.Members))
-	for _, m := range res.Members {
-		if m.UserId != senderID {
-			memberIDs = append(memberIDs, m.UserId)
+	// 普通群要给每个成员写会话，需要完整的成员列表（避免在事务内调用 gRPC）；
+	// 大群只校验被 @ 的用户
+	var memberIDs []int64
+	if largeGroup {
+		if memberIDs, err = r.mentionedMembers(ctx, groupID, senderID, opts); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		res, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
+			GroupId: groupID.String(),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("list group members failed: %w", err)
+		}
+		// 过滤发送者本人，生成需要更新未读的用户列表
+		memberIDs = make([]int64, 0, len(res.Members))
+		for _, m := range res.Members {
+			if m.UserId != senderID {
+				memberIDs = append(memberIDs, m.UserId)
+			}
 		}
 	}
 
-	var lastMsgID int64
+	var persisted model.Message
 
 	// ====== 第二阶段：事务内保证强一致性 ======
 	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -273,20 +469,56 @@ func (r *messageRepo) SendMessageToGroup(
 
 		// 2️⃣ 创建消息记录
 		msg := model.Message{
-			MsgID:    messageID,
-			SeqID:    seq_id,
-			ThreadID: thread.ID,
-			SenderID: senderID,
-			Kind:     1, // 1 = 文本消息
-			Content:  text,
+			MsgID:         messageID,
+			SeqID:         seq_id,
+			ThreadID:      thread.ID,
+			SenderID:      senderID,
+			Kind:          opts.kind(),
+			Content:       text,
+			ReplyToMsgID:  replyTarget(tx, thread.ID, opts),
+			ForwardedFrom: opts.forwardedFrom(),
+			MentionAll:    opts != nil && opts.MentionAll,
+			ExpiresAt:     opts.expiresAt(thread),
 		}
 		if err := tx.Create(&msg).Error; err != nil {
 			return err
 		}
 
-		// 3️⃣ 更新发送者会话（未读数=0）
-		if err := upsertConversation(tx, senderID, thread.ID, msg.MsgID, 0); err != nil {
-			return err
+		// 群已解散：只有系统消息会走到这里，更新所有还留着的会话，不再计未读
+		if thread.DissolvedAt != nil {
+			if err := tx.Model(&model.Conversation{}).
+				Where("thread_id = ?", thread.ID).
+				UpdateColumn("last_message_id", msg.MsgID).Error; err != nil {
+				return err
+			}
+			if err := setLargeGroupLastMessage(tx, thread.ID, &msg.MsgID); err != nil {
+				return err
+			}
+			persisted = msg
+			persisted.Thread = *thread
+			return nil
+		}
+
+		// 大群不逐个成员写会话和未读状态
+		if largeGroup {
+			if err := sendToLargeGroup(tx, thread, &msg, senderIsMember, memberIDs, opts); err != nil {
+				return err
+			}
+			persisted = msg
+			persisted.Thread = *thread
+			return nil
+		}
+		if thread.LargeGroup {
+			if err := leaveLargeGroupMode(tx, thread); err != nil {
+				return err
+			}
+		}
+
+		// 3️⃣ 更新发送者会话（未读数=0）；发送者已经不在群里（例如退群的系统消息）时跳过
+		if senderIsMember {
+			if err := upsertConversation(tx, senderID, thread.ID, msg.MsgID, 0); err != nil {
+				return err
+			}
 		}
 
 		// 4️⃣ 批量更新群成员会话（未读数+1）
@@ -296,6 +528,11 @@ func (r *messageRepo) SendMessageToGroup(
 			}
 		}
 
+		// 4️⃣.1 记录 @ 并累加被 @ 成员的提醒数
+		if err := saveMentions(tx, &msg, memberIDs, opts); err != nil {
+			return err
+		}
+
 		// 5️⃣ 批量创建未读消息状态
 		if len(memberIDs) > 0 {
 			statuses := make([]model.MessageStatus, 0, len(memberIDs))
@@ -312,17 +549,172 @@ func (r *messageRepo) SendMessageToGroup(
 			}
 		}
 
-		// 6️⃣ 直接使用当前消息ID作为返回值
-		lastMsgID = msg.MsgID
+		// 6️⃣ 直接返回当前消息
+		persisted = msg
+		persisted.Thread = *thread
 
 		return nil
 	})
 
 	if err != nil {
+		return nil, nil, err
+	}
+
+	r.indexMessage(ctx, &persisted)
+	if largeGroup {
+		return &persisted, nil, nil
+	}
+	return &persisted, memberIDs, nil
+}
+
+// 只记录群成员中的 @，@所有人时所有接收者的提醒数都 +1
+func saveMentions(tx *gorm.DB, msg *model.Message, memberIDs []int64, opts *SendOptions) error {
+	if opts == nil || (len(opts.Mentions) == 0 && !opts.MentionAll) {
+		return nil
+	}
+	members := make(map[int64]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		members[id] = true
+	}
+	mentions := make([]model.MessageMention, 0, len(opts.Mentions))
+	mentioned := make([]int64, 0, len(opts.Mentions))
+	for _, uid := range opts.Mentions {
+		if members[uid] {
+			members[uid] = false // 去重
+			mentions = append(mentions, model.MessageMention{MessageID: msg.MsgID, UserID: uid})
+			mentioned = append(mentioned, uid)
+		}
+	}
+	if len(mentions) > 0 {
+		if err := tx.Create(&mentions).Error; err != nil {
+			return err
+		}
+	}
+
+	if msg.MentionAll {
+		mentioned = memberIDs
+	}
+	if len(mentioned) == 0 {
+		return nil
+	}
+	return tx.Model(&model.Conversation{}).
+		Where("owner_id IN ? AND thread_id = ?", mentioned, msg.ThreadID).
+		UpdateColumn("mention_count", gorm.Expr("mention_count + 1")).Error
+}
+
+// 批量读取消息中 @ 到的用户
+func loadMentions(db *gorm.DB, messages []*model.Message) (map[int64][]int64, error) {
+	result := make(map[int64][]int64)
+	if len(messages) == 0 {
+		return result, nil
+	}
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.MsgID)
+	}
+	var rows []model.MessageMention
+	if err := db.Where("message_id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.MessageID] = append(result[row.MessageID], row.UserID)
+	}
+	return result, nil
+}
+
+// CanMentionAll 只有群主和管理员可以 @所有人
+func (r *messageRepo) CanMentionAll(ctx context.Context, groupID uuid.UUID, userID int64) (bool, error) {
+	role, err := r.getGroupRole(ctx, groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	return role == grouppb.Role_ROLE_OWNER || role == grouppb.Role_ROLE_ADMIN, nil
+}
+
+// 持久化时再确认一次引用目标在同一会话内，不合法的引用直接丢弃，避免消息反复重试
+func replyTarget(tx *gorm.DB, threadID int64, opts *SendOptions) *int64 {
+	if opts == nil || opts.ReplyToMsgID == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.Message{}).
+		Where("id = ? AND thread_id = ?", *opts.ReplyToMsgID, threadID).
+		Count(&count).Error; err != nil || count == 0 {
+		return nil
+	}
+	return opts.ReplyToMsgID
+}
+
+// CheckReplyTarget 发送前校验引用的消息属于当前会话
+func (r *messageRepo) CheckReplyTarget(ctx context.Context, replyToMsgID, senderID, targetID int64, groupID *uuid.UUID) error {
+	db := r.db.WithContext(ctx)
+	var thread model.Thread
+	if err := db.Where("id = (?)",
+		db.Model(&model.Message{}).Select("thread_id").Where("id = ? AND is_expired = ?", replyToMsgID, false),
+	).First(&thread).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidReplyTarget
+		}
+		return err
+	}
+	if groupID != nil {
+		if thread.GroupID == nil || *thread.GroupID != *groupID {
+			return ErrInvalidReplyTarget
+		}
+		return nil
+	}
+	if thread.PeerA == nil || thread.PeerB == nil {
+		return ErrInvalidReplyTarget
+	}
+	a, b := *thread.PeerA, *thread.PeerB
+	if !(a == senderID && b == targetID) && !(a == targetID && b == senderID) {
+		return ErrInvalidReplyTarget
+	}
+	return nil
+}
+
+// 批量读取被引用的消息（不受清空记录和 "仅自己删除" 影响，撤回的消息只保留撤回标记）
+func loadQuotedMessages(db *gorm.DB, messages []*model.Message) (map[int64]*model.Message, error) {
+	ids := make([]int64, 0)
+	for _, m := range messages {
+		if m.ReplyToMsgID != nil {
+			ids = append(ids, *m.ReplyToMsgID)
+		}
+	}
+	quoted := make(map[int64]*model.Message, len(ids))
+	if len(ids) == 0 {
+		return quoted, nil
+	}
+	var rows []*model.Message
+	if err := db.Model(&model.Message{}).Where("id IN ?", ids).Find(&rows).Error; err != nil {
 		return nil, err
 	}
+	for _, m := range rows {
+		quoted[m.MsgID] = m
+	}
+	return quoted, nil
+}
 
-	return &lastMsgID, nil
+// seq 不大于 floor 的消息对查看者不可见，只返回 ID 和不可见标记
+func toQuotedMessage(m *model.Message, sender UserInfo, floor int64) *QuotedMessage {
+	if m.SeqID <= floor {
+		return &QuotedMessage{MsgID: m.MsgID, Unavailable: true}
+	}
+	q := &QuotedMessage{
+		MsgID:       m.MsgID,
+		Sender:      sender,
+		Kind:        m.Kind,
+		IsWithdrawn: m.IsWithdrawed,
+		IsExpired:   m.IsExpired,
+	}
+	if !m.IsWithdrawed && !m.IsExpired {
+		content := []rune(m.Content)
+		if len(content) > quoteSnippetLen {
+			content = content[:quoteSnippetLen]
+		}
+		q.Content = string(content)
+	}
+	return q
 }
 
 // 获取或创建 Thread
@@ -345,7 +737,9 @@ func getOrCreateGroupThread(tx *gorm.DB, groupID uuid.UUID) (*model.Thread, erro
 }
 
 // 删除两边的消息 单聊 撤回
-func (r *messageRepo) WithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64, messageID int64) (lastMessageID int64, err error) {
+// 单聊只有发送者本人可以撤回，并受 window 时间窗口限制
+func (r *messageRepo) WithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64, messageID int64,
+	window time.Duration) (lastMessageID int64, err error) {
 	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var thread model.Thread
 		// 1. 找到对应的单聊线程
@@ -355,28 +749,29 @@ func (r *messageRepo) WithdrawMessageSingle(ctx context.Context, senderID int64,
 			return err
 		}
 
-		// 2. 查找要撤回的消息实体 (注意：主键字段名是 msg_id)
+		// 2. 查找要撤回的消息实体
 		var message model.Message
 		if err := tx.Where("thread_id = ? AND sender_id = ? AND id = ?", thread.ID, senderID, messageID).
 			First(&message).Error; err != nil {
 			return err
 		}
+		if message.IsWithdrawed {
+			return errors.New("消息已撤回")
+		}
 
 		// 3. 时间限制查验
-		if time.Since(message.CreatedAt) > 3*time.Minute {
+		if window > 0 && time.Since(message.CreatedAt) > window {
 			return errors.New("超过撤回时间限制")
 		}
 
-		// 4. 逻辑撤回：更新 Message 表
-		if err := tx.Model(&model.Message{}).
-			Where("msg_id = ?", messageID). // 修正字段名
-			Update("is_withdrawed", true).Error; err != nil {
+		// 4. 逻辑撤回：更新 Message 表，记录撤回人用于展示撤回提示
+		if err := markWithdrawn(tx, messageID, senderID); err != nil {
 			return err
 		}
 
 		// 5. 寻找撤回后的“最新一条有效消息”
 		var lastMsg model.Message
-		err := tx.Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
+		err := tx.Where("thread_id = ? AND is_withdrawed = ? AND is_expired = ?", thread.ID, false, false).
 			Order("seq_id DESC"). // 【核心精髓】利用严格连续的 SeqID 倒序，找出来的绝对是真实的最后一条！
 			First(&lastMsg).Error
 
@@ -406,9 +801,54 @@ func (r *messageRepo) WithdrawMessageSingle(ctx context.Context, senderID int64,
 
 		return nil
 	})
+	if err == nil {
+		r.removeFromIndex(ctx, messageID)
+	}
 	return
 }
-func (r *messageRepo) UnWithdrawMessageSingle(ctx context.Context, senderID, targetID, messageID int64, newtext string) (lastMessageID int64, err error) {
+
+// 标记消息已撤回
+func markWithdrawn(tx *gorm.DB, messageID, operatorID int64) error {
+	return tx.Model(&model.Message{}).
+		Where("id = ?", messageID).
+		Updates(map[string]interface{}{
+			"is_withdrawed": true,
+			"withdrawn_by":  operatorID,
+			"withdrawn_at":  time.Now(),
+		}).Error
+}
+
+// 恢复撤回的消息：只能恢复自己撤回、未过期的消息，内容有变化时按编辑处理，旧内容写入编辑历史
+func restoreWithdrawn(tx *gorm.DB, message *model.Message, senderID int64, newText string, window time.Duration) error {
+	if !message.IsWithdrawed {
+		return errors.New("消息未撤回")
+	}
+	if message.IsExpired {
+		return gorm.ErrRecordNotFound
+	}
+	// 群主/管理员撤回的不能由发送者恢复
+	if message.WithdrawnBy == nil || *message.WithdrawnBy != senderID {
+		return errors.New("insufficient permissions")
+	}
+	if err := tx.Model(&model.Message{}).Where("id = ?", message.MsgID).
+		Updates(map[string]interface{}{
+			"is_withdrawed": false,
+			"withdrawn_by":  nil,
+			"withdrawn_at":  nil,
+		}).Error; err != nil {
+		return err
+	}
+	message.IsWithdrawed = false
+	message.WithdrawnBy = nil
+	message.WithdrawnAt = nil
+	if message.Content == newText {
+		return nil
+	}
+	return applyEdit(tx, message, senderID, newText, window)
+}
+
+func (r *messageRepo) UnWithdrawMessageSingle(ctx context.Context, senderID, targetID, messageID int64, newtext string,
+	window time.Duration) (lastMessageID int64, err error) {
 	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var thread model.Thread
 		if err := tx.Where("(peer_a = ? AND peer_b = ?) OR (peer_a = ? AND peer_b = ?)",
@@ -417,21 +857,17 @@ func (r *messageRepo) UnWithdrawMessageSingle(ctx context.Context, senderID, tar
 		}
 
 		var message model.Message
-		if err := tx.Where("thread_id = ? AND sender_id = ? AND id = ?", thread.ID, senderID, messageID).
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("thread_id = ? AND sender_id = ? AND id = ?", thread.ID, senderID, messageID).
 			First(&message).Error; err != nil {
 			return err
 		}
-
-		if err := tx.Model(&model.Message{}).Where("id = ?", messageID).
-			Updates(map[string]interface{}{ //批量更新消息一次完成
-				"content":       newtext,
-				"is_withdrawed": false,
-			}).Error; err != nil {
+		if err := restoreWithdrawn(tx, &message, senderID, newtext, window); err != nil {
 			return err
 		}
 
 		var lastMsg model.Message
-		err := tx.Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
+		err := tx.Where("thread_id = ? AND is_withdrawed = ? AND is_expired = ?", thread.ID, false, false).
 			Order("seq_id DESC").First(&lastMsg).Error
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return err
@@ -450,12 +886,20 @@ func (r *messageRepo) UnWithdrawMessageSingle(ctx context.Context, senderID, tar
 		}
 		return nil
 	})
+	if err == nil {
+		r.reindexMessage(ctx, messageID)
+	}
 	return
 }
 
 // 撤回 群聊
-func (r *messageRepo) WithdrawMessageGroup(ctx context.Context, senderID int64, groupID uuid.UUID,
-	messageID int64) (lastMessageID int64, err error) {
+// 群主/管理员可以随时撤回任何成员的消息；普通成员只能撤回自己的消息，
+// 时间窗口优先使用群自定义的 RecallWindowSeconds，否则使用全局配置 defaultWindow
+func (r *messageRepo) WithdrawMessageGroup(ctx context.Context, operatorID int64, groupID uuid.UUID,
+	messageID int64, defaultWindow time.Duration) (lastMessageID int64, err error) {
+	if _, err := r.checkGroupWritable(ctx, groupID, operatorID); err != nil {
+		return 0, err
+	}
 	// 事务外先查群成员，避免长事务阻塞
 	res, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
 		GroupId: groupID.String(),
@@ -464,9 +908,17 @@ func (r *messageRepo) WithdrawMessageGroup(ctx context.Context, senderID int64,
 		return 0, fmt.Errorf("failed to list group members: %w", err)
 	}
 	var mem []int64 // 所有的成员
+	operatorRole := grouppb.Role_ROLE_UNSPECIFIED
 	for _, m := range res.Members {
 		mem = append(mem, m.UserId)
+		if m.UserId == operatorID {
+			operatorRole = m.Role
+		}
 	}
+	if operatorRole == grouppb.Role_ROLE_UNSPECIFIED {
+		return 0, errors.New("operator not in group")
+	}
+	isAdmin := operatorRole == grouppb.Role_ROLE_OWNER || operatorRole == grouppb.Role_ROLE_ADMIN
 
 	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 找 thread
@@ -477,48 +929,91 @@ func (r *messageRepo) WithdrawMessageGroup(ctx context.Context, senderID int64,
 
 		// 查找要撤回的消息
 		var message model.Message
-		if err := tx.Where("thread_id = ? AND sender_id = ? AND id = ?",
-			thread.ID, senderID, messageID).First(&message).Error; err != nil {
+		if err := tx.Where("thread_id = ? AND id = ?", thread.ID, messageID).
+			First(&message).Error; err != nil {
 			return err
 		}
+		if message.IsWithdrawed {
+			return errors.New("消息已撤回")
+		}
 
-		// 时间限制
-		if time.Since(message.CreatedAt) > 3*time.Minute {
-			return errors.New("超过撤回时间限制")
+		// 权限与时间限制：管理员不受限制
+		if !isAdmin {
+			if message.SenderID != operatorID {
+				return errors.New("insufficient permissions")
+			}
+			window := defaultWindow
+			if thread.RecallWindowSeconds != nil {
+				window = time.Duration(*thread.RecallWindowSeconds) * time.Second
+			}
+			if window > 0 && time.Since(message.CreatedAt) > window {
+				return errors.New("超过撤回时间限制")
+			}
 		}
 
 		// 逻辑撤回
-		if err := tx.Model(&model.Message{}).
-			Where("id = ?", messageID).
-			Update("is_withdrawed", true).Error; err != nil {
+		if err := markWithdrawn(tx, messageID, operatorID); err != nil {
 			return err
 		}
 
 		// 找 thread 下的最后一条未撤回消息
 		var lastMsg model.Message
-		err := tx.Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
-			Order("id DESC").First(&lastMsg).Error
+		err := tx.Where("thread_id = ? AND is_withdrawed = ? AND is_expired = ?", thread.ID, false, false).
+			Order("seq_id DESC").First(&lastMsg).Error
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return err
 		}
-		lastMessageID = lastMsg.MsgID
+		// 没有可见消息时置为 NULL，不能继续指向撤回的消息
+		var last *int64
+		if err == nil {
+			lastMessageID = lastMsg.MsgID
+			last = &lastMsg.MsgID
+		}
 
 		// 一次性更新所有成员的会话
 		if len(mem) > 0 {
 			err = tx.Model(&model.Conversation{}).
 				Where("owner_id IN ? AND thread_id = ?", mem, thread.ID).
-				Update("last_message_id", lastMsg.MsgID).Error
+				Update("last_message_id", last).Error
 			if err != nil {
 				return err
 			}
 		}
-		return nil
+		return setLargeGroupLastMessage(tx, thread.ID, last)
 	})
+	if err == nil {
+		r.removeFromIndex(ctx, messageID)
+	}
 	return
 }
 
+// 设置群聊撤回时间窗口，只有群主/管理员可以操作；seconds <= 0 表示恢复全局配置
+func (r *messageRepo) SetGroupRecallWindow(ctx context.Context, operatorID int64, groupID uuid.UUID, seconds int64) error {
+	member, err := r.checkGroupWritable(ctx, groupID, operatorID)
+	if err != nil {
+		return err
+	}
+	if member.Role != grouppb.Role_ROLE_OWNER && member.Role != grouppb.Role_ROLE_ADMIN {
+		return errors.New("insufficient permissions")
+	}
+
+	var window *int64
+	if seconds > 0 {
+		window = &seconds
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		thread, err := getOrCreateGroupThread(tx, groupID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Thread{}).
+			Where("id = ?", thread.ID).
+			Update("recall_window_seconds", window).Error
+	})
+}
+
 func (r *messageRepo) UnWithdrawMessageGroup(ctx context.Context, senderID int64, groupID uuid.UUID, messageID int64,
-	newText string) (lastMessageID int64, err error) {
+	newText string, window time.Duration) (lastMessageID int64, err error) {
 	// 事务外先查群成员，避免长事务阻塞
 	res, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
 		GroupId: groupID.String(),
@@ -536,26 +1031,19 @@ func (r *messageRepo) UnWithdrawMessageGroup(ctx context.Context, senderID int64
 			First(&thread).Error; err != nil {
 			return err
 		}
-		//检查是否存在改条记录
-		var dummy int
-		if err := tx.Model(&model.Message{}).
+		var message model.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("id = ? AND thread_id = ? AND sender_id = ?", messageID, thread.ID, senderID).
-			Select("1").First(&dummy).Error; err != nil {
+			First(&message).Error; err != nil {
 			return err
 		}
-
-		// 批量更新  使用Updates
-		if err := tx.Model(&model.Message{}).Where("id = ?", messageID).
-			Updates(map[string]interface{}{
-				"content":       newText,
-				"is_withdrawed": false,
-			}).Error; err != nil {
+		if err := restoreWithdrawn(tx, &message, senderID, newText, window); err != nil {
 			return err
 		}
 
 		var lastMsg model.Message
-		if err := tx.Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
-			Order("id DESC").First(&lastMsg).Error; err == nil {
+		if err := tx.Where("thread_id = ? AND is_withdrawed = ? AND is_expired = ?", thread.ID, false, false).
+			Order("seq_id DESC").First(&lastMsg).Error; err == nil {
 			lastMessageID = lastMsg.MsgID
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return err
@@ -566,78 +1054,239 @@ func (r *messageRepo) UnWithdrawMessageGroup(ctx context.Context, senderID int64
 			UpdateColumn("last_message_id", lastMessageID).Error; err != nil {
 			return err
 		}
-		return nil
+		return setLargeGroupLastMessage(tx, thread.ID, &lastMessageID)
 	})
+	if err == nil {
+		r.reindexMessage(ctx, messageID)
+	}
 	return
 }
 
-func (r *messageRepo) GetConversationMessagesSingle(
-	ctx context.Context,
-	senderID, targetID int64,
-	lastMsgID int64,
-	pageSize int,
-) (*ConversationMessages, error) {
-	db := r.db.WithContext(ctx)
-
-	// 1. 查找单聊 thread
-	var thread model.Thread
-	if err := db.Where(
-		"(peer_a = ? AND peer_b = ?) OR (peer_a = ? AND peer_b = ?)",
-		senderID, targetID, targetID, senderID,
-	).First(&thread).Error; err != nil {
-		return nil, err
-	}
-
-	// 2. 游标转换：把前端传来的雪花 ID (lastMsgID) 翻译成内部的连续序号 (SeqID)
-	var cursorSeqID int64
-	if lastMsgID > 0 {
-		// 去数据库里查这条消息对应的 seq_id
-		if err := db.Model(&model.Message{}).
-			Where("thread_id = ? AND id = ?", thread.ID, lastMsgID).
-			Pluck("seq_id", &cursorSeqID).Error; err != nil {
-			return nil, fmt.Errorf("invalid cursor message id: %w", err)
+// 编辑 单聊：只有发送者本人可以编辑，编辑前的内容写入 MessageRevision
+func (r *messageRepo) EditMessageSingle(ctx context.Context, editorID, targetID, messageID int64, newText string,
+	window time.Duration) (edited *model.Message, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var thread model.Thread
+		if err := tx.Where("(peer_a = ? AND peer_b = ?) OR (peer_a = ? AND peer_b = ?)",
+			editorID, targetID, targetID, editorID).First(&thread).Error; err != nil {
+			return err
 		}
-	}
 
-	// 3. 查询消息（使用 SeqID 进行严格时序分页）
-	messages := make([]*model.Message, 0, pageSize+1)
-	query := db.Model(&model.Message{}).
-		Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
-		Order("seq_id DESC"). // 【修改】必须用 seq_id 倒序，保证时序绝对正确
-		Limit(pageSize + 1)
+		var message model.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("thread_id = ? AND sender_id = ? AND id = ?", thread.ID, editorID, messageID).
+			First(&message).Error; err != nil {
+			return err
+		}
 
-	if cursorSeqID > 0 {
-		// 【修改】使用 seq_id < ?
-		// 注意：这里用 `<` 而不是原来的 `<=`，因为正常的下拉刷新不应该把刚才那条基准消息再查出来一遍
-		query = query.Where("seq_id < ?", cursorSeqID)
+		if err := applyEdit(tx, &message, editorID, newText, window); err != nil {
+			return err
+		}
+		edited = &message
+		return nil
+	})
+	if err == nil {
+		r.indexMessage(ctx, edited)
 	}
+	return
+}
 
-	if err := query.Find(&messages).Error; err != nil {
+// 编辑 群聊：allowAdmin 为 true 时群主/管理员也可以编辑他人的消息
+func (r *messageRepo) EditMessageGroup(ctx context.Context, editorID int64, groupID uuid.UUID, messageID int64,
+	newText string, window time.Duration, allowAdmin bool) (edited *model.Message, err error) {
+	// 事务外先查编辑者的群角色，避免长事务阻塞
+	member, err := r.checkGroupWritable(ctx, groupID, editorID)
+	if err != nil {
 		return nil, err
 	}
+	isAdmin := allowAdmin && (member.Role == grouppb.Role_ROLE_OWNER || member.Role == grouppb.Role_ROLE_ADMIN)
 
-	hasMore := false
-	if len(messages) > pageSize {
-		hasMore = true
-		messages = messages[:pageSize] // 丢弃最后一条，保持 pageSize
-	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var thread model.Thread
+		if err := tx.Where("group_id = ?", groupID).First(&thread).Error; err != nil {
+			return err
+		}
 
-	// 4. 获取未读计数
-	var unreadCount int
-	_ = db.Model(&model.Conversation{}).
-		Where("owner_id = ? AND thread_id = ?", senderID, thread.ID).
-		Pluck("unread_count", &unreadCount).Error
+		var message model.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("thread_id = ? AND id = ?", thread.ID, messageID).
+			First(&message).Error; err != nil {
+			return err
+		}
+		if message.SenderID != editorID && !isAdmin {
+			return errors.New("insufficient permissions")
+		}
 
-	// 5. 调用 user-service 获取用户信息
-	userIDs := []int64{senderID, targetID}
-	userResp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
-		UserIds: userIDs,
+		// 管理员编辑他人消息不受时间窗口限制
+		if message.SenderID != editorID {
+			window = 0
+		}
+		if err := applyEdit(tx, &message, editorID, newText, window); err != nil {
+			return err
+		}
+		edited = &message
+		return nil
 	})
+	if err == nil {
+		r.indexMessage(ctx, edited)
+	}
+	return
+}
+
+// 保存旧内容到编辑历史，再更新消息正文
+func applyEdit(tx *gorm.DB, message *model.Message, editorID int64, newText string, window time.Duration) error {
+	if message.IsWithdrawed {
+		return errors.New("消息已撤回，无法编辑")
+	}
+	if message.IsExpired {
+		return gorm.ErrRecordNotFound
+	}
+	if message.Kind == model.KindSystem {
+		return errors.New("系统消息无法编辑")
+	}
+	if window > 0 && time.Since(message.CreatedAt) > window {
+		return errors.New("超过编辑时间限制")
+	}
+	if message.Content == newText {
+		return errors.New("消息内容未变化")
+	}
+
+	revision := model.MessageRevision{
+		MessageID: message.MsgID,
+		EditorID:  editorID,
+		Content:   message.Content,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&model.Message{}).Where("id = ?", message.MsgID).
+		Updates(map[string]interface{}{
+			"content":   newText,
+			"edited_at": now,
+		}).Error; err != nil {
+		return err
+	}
+	message.Content = newText
+	message.EditedAt = &now
+	return nil
+}
+
+// 获取消息的编辑历史（按时间倒序），只有会话参与者可以查看
+func (r *messageRepo) GetMessageRevisions(ctx context.Context, userID, messageID int64) ([]*model.MessageRevision, error) {
+	if _, err := r.getReadableMessage(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*model.MessageRevision, 0)
+	if err := r.db.WithContext(ctx).Where("message_id = ?", messageID).
+		Order("edited_at DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// 添加表情回应，重复回应同一个表情不报错；不影响会话的最后一条消息和未读数
+func (r *messageRepo) AddReaction(ctx context.Context, userID, messageID int64, emoji string) (*model.Message, error) {
+	db := r.db.WithContext(ctx)
+	message, err := r.getReadableMessage(ctx, userID, messageID)
 	if err != nil {
-		return nil, fmt.Errorf("fail to get UserInfos: %w", err)
+		return nil, err
+	}
+	if message.IsWithdrawed {
+		return nil, errors.New("消息已撤回")
+	}
+	reaction := model.MessageReaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction).Error; err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// 取消表情回应
+func (r *messageRepo) RemoveReaction(ctx context.Context, userID, messageID int64, emoji string) (*model.Message, error) {
+	db := r.db.WithContext(ctx)
+	message, err := r.getReadableMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&model.MessageReaction{}).Error; err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// 读取要转发的消息，按发送时间排序；每条消息都必须对用户可见，且没有被撤回
+func (r *messageRepo) GetForwardableMessages(ctx context.Context, userID int64, messageIDs []int64) ([]*model.Message, error) {
+	if len(messageIDs) == 0 {
+		return nil, errors.New("message ids cannot be empty")
+	}
+	db := r.db.WithContext(ctx)
+
+	var messages []*model.Message
+	if err := db.Preload("Thread").
+		Where("id IN ?", messageIDs).
+		Where("id NOT IN (?)", db.Model(&model.HiddenMessage{}).Select("message_id").Where("user_id = ?", userID)).
+		Order("created_at ASC, seq_id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	if len(messages) != len(uniqueIDs(messageIDs)) {
+		return nil, errors.New("message not found")
 	}
 
-	// 6. 建立 userMap
+	checked := make(map[int64]bool)
+	for _, m := range messages {
+		if m.IsWithdrawed {
+			return nil, errors.New("消息已撤回，无法转发")
+		}
+		if m.IsExpired {
+			return nil, errors.New("message not found")
+		}
+		if m.Kind == model.KindSystem {
+			return nil, errors.New("系统消息无法转发")
+		}
+		if checked[m.ThreadID] {
+			continue
+		}
+		floor, err := r.readFloor(ctx, userID, &m.Thread)
+		if err != nil {
+			return nil, err
+		}
+		conv, err := getOwnerConversation(db, userID, m.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		floor = max(floor, conv.ClearedSeq)
+		for _, other := range messages {
+			if other.ThreadID == m.ThreadID && other.SeqID <= floor {
+				return nil, errors.New("message not found")
+			}
+		}
+		checked[m.ThreadID] = true
+	}
+	return messages, nil
+}
+
+func uniqueIDs(ids []int64) map[int64]struct{} {
+	set := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// GetUserInfos 批量获取用户昵称和头像
+func (r *messageRepo) GetUserInfos(ctx context.Context, userIDs []int64) (map[int64]UserInfo, error) {
+	userResp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get user infos: %w", err)
+	}
 	userMap := make(map[int64]UserInfo, len(userResp.Users))
 	for _, u := range userResp.Users {
 		userMap[u.UserId] = UserInfo{
@@ -646,22 +1295,297 @@ func (r *messageRepo) GetConversationMessagesSingle(
 			Avatar:   u.Avatar,
 		}
 	}
+	return userMap, nil
+}
 
-	// 7. 组装返回数据
-	messageWithUserInfos := make([]*MessageWithUser, 0, len(messages))
+// 读取消息（带 Thread），并确认用户是会话参与者
+func (r *messageRepo) getReadableMessage(ctx context.Context, userID, messageID int64) (*model.Message, error) {
+	var message model.Message
+	if err := r.db.WithContext(ctx).Preload("Thread").
+		Where("id = ? AND is_expired = ?", messageID, false).
+		First(&message).Error; err != nil {
+		return nil, err
+	}
+	floor, err := r.readFloor(ctx, userID, &message.Thread)
+	if err != nil {
+		return nil, err
+	}
+	if message.SeqID <= floor {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &message, nil
+}
+
+// 批量读取表情回应并按表情聚合，表情按第一次出现的时间排序
+func loadReactions(db *gorm.DB, messages []*model.Message) (map[int64][]*Reaction, error) {
+	result := make(map[int64][]*Reaction)
+	if len(messages) == 0 {
+		return result, nil
+	}
+	ids := make([]int64, 0, len(messages))
 	for _, m := range messages {
-		mwu := &MessageWithUser{
-			Message: *m,
-			User:    userMap[m.SenderID], // 直接 O(1) 查
+		ids = append(ids, m.MsgID)
+	}
+	var rows []model.MessageReaction
+	if err := db.Where("message_id IN ?", ids).Order("created_at ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[int64]map[string]*Reaction)
+	for _, row := range rows {
+		byEmoji, ok := index[row.MessageID]
+		if !ok {
+			byEmoji = make(map[string]*Reaction)
+			index[row.MessageID] = byEmoji
 		}
-		messageWithUserInfos = append(messageWithUserInfos, mwu)
+		reaction, ok := byEmoji[row.Emoji]
+		if !ok {
+			reaction = &Reaction{Emoji: row.Emoji}
+			byEmoji[row.Emoji] = reaction
+			result[row.MessageID] = append(result[row.MessageID], reaction)
+		}
+		reaction.Count++
+		reaction.UserIDs = append(reaction.UserIDs, row.UserID)
+	}
+	return result, nil
+}
+
+// 判断用户是否是会话参与者：单聊看 peer，群聊看群成员，已解散的群看解散前的成员
+func (r *messageRepo) canReadThread(ctx context.Context, userID int64, thread *model.Thread) (bool, error) {
+	if thread.GroupID == nil {
+		return (thread.PeerA != nil && *thread.PeerA == userID) ||
+			(thread.PeerB != nil && *thread.PeerB == userID), nil
+	}
+	_, err := r.readFloor(ctx, userID, thread)
+	if errors.Is(err, ErrNotGroupMember) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 不是群成员，或者被移出了群
+var ErrNotGroupMember = errors.New("not a group member")
+
+// 禁言中不能在群里发言
+var (
+	ErrGroupMuted  = errors.New("group is muted")
+	ErrMemberMuted = errors.New("member is muted")
+)
+
+// 群已归档，只能查看历史
+var ErrGroupArchived = errors.New("group is archived")
+
+// 确认用户是群成员并且群没有归档，返回成员信息
+func (r *messageRepo) checkGroupWritable(ctx context.Context, groupID uuid.UUID, userID int64) (*grouppb.GetMemberRoleResponse, error) {
+	member, err := r.getGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member.IsMember {
+		return nil, ErrNotGroupMember
+	}
+	if member.Archived {
+		return nil, ErrGroupArchived
+	}
+	return member, nil
+}
+
+// CheckCanSpeak 确认用户可以在群里发言：是群成员、群没有归档、没有被单独禁言，
+// 全员禁言时只有群主和管理员可以发言
+func (r *messageRepo) CheckCanSpeak(ctx context.Context, groupID uuid.UUID, userID int64) error {
+	member, err := r.checkGroupWritable(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member.MutedUntil > time.Now().Unix() {
+		return ErrMemberMuted
+	}
+	if member.GroupMuted && member.Role != grouppb.Role_ROLE_OWNER && member.Role != grouppb.Role_ROLE_ADMIN {
+		return ErrGroupMuted
+	}
+	return nil
+}
+
+// CheckGroupMember 确认用户当前是群成员，群服务那边带缓存，成员被移出时缓存会立即失效
+func (r *messageRepo) CheckGroupMember(ctx context.Context, groupID uuid.UUID, userID int64) error {
+	res, err := r.groupClient.IsMember(ctx, &grouppb.IsMemberRequest{
+		GroupId: groupID.String(),
+		UserId:  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check group member: %w", err)
+	}
+	if !res.IsMember {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+// CheckGroupReadable 确认用户可以查看群消息：是群成员，或者是已解散的群解散前的成员
+func (r *messageRepo) CheckGroupReadable(ctx context.Context, groupID uuid.UUID, userID int64) error {
+	var thread model.Thread
+	err := r.db.WithContext(ctx).Where("group_id = ?", groupID).First(&thread).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.CheckGroupMember(ctx, groupID, userID)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.readFloor(ctx, userID, &thread)
+	return err
+}
+
+// 查询用户在群里的角色和入群 seq
+func (r *messageRepo) getGroupMember(ctx context.Context, groupID uuid.UUID, userID int64) (*grouppb.GetMemberRoleResponse, error) {
+	res, err := r.groupClient.GetMemberRole(ctx, &grouppb.GetMemberRoleRequest{
+		GroupId: groupID.String(),
+		UserId:  userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group member role: %w", err)
+	}
+	return res, nil
+}
+
+// 查询用户在群里的角色，不在群里返回 ROLE_UNSPECIFIED
+func (r *messageRepo) getGroupRole(ctx context.Context, groupID uuid.UUID, userID int64) (grouppb.Role, error) {
+	member, err := r.getGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return grouppb.Role_ROLE_UNSPECIFIED, err
+	}
+	if !member.IsMember {
+		return grouppb.Role_ROLE_UNSPECIFIED, nil
+	}
+	return member.Role, nil
+}
+
+// 用户在会话里能看到的最早位置：seq 不大于返回值的消息对其不可见。
+// 单聊要求是参与者；群聊要求是当前成员，且没有开放全部历史时只能看到入群之后的消息；
+// 已解散的群成员记录都删掉了，解散前的成员按解散时记下的位置只读查看
+func (r *messageRepo) readFloor(ctx context.Context, userID int64, thread *model.Thread) (int64, error) {
+	if thread.GroupID == nil {
+		if (thread.PeerA == nil || *thread.PeerA != userID) && (thread.PeerB == nil || *thread.PeerB != userID) {
+			return 0, errors.New("insufficient permissions")
+		}
+		return 0, nil
+	}
+	member, err := r.getGroupMember(ctx, *thread.GroupID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !member.IsMember {
+		if thread.DissolvedAt != nil {
+			return dissolvedGroupFloor(r.db.WithContext(ctx), userID, thread.ID)
+		}
+		return 0, ErrNotGroupMember
+	}
+	if member.FullHistory {
+		return 0, nil
+	}
+	return member.JoinSeq, nil
+}
+
+// 获取群成员 ID 列表，用于推送等需要扇出的场景
+func (r *messageRepo) GetGroupMemberIDs(ctx context.Context, groupID uuid.UUID) ([]int64, error) {
+	res, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
+		GroupId: groupID.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	ids := make([]int64, 0, len(res.Members))
+	for _, m := range res.Members {
+		ids = append(ids, m.UserId)
+	}
+	return ids, nil
+}
+
+// 返回 candidateIDs 中把 userID 拉黑了的用户
+func (r *messageRepo) GetBlockedBy(ctx context.Context, userID int64, candidateIDs []int64) (map[int64]bool, error) {
+	blocked := make(map[int64]bool)
+	if len(candidateIDs) == 0 {
+		return blocked, nil
+	}
+	res, err := r.userClient.GetBlockedBy(ctx, &userpb.GetBlockedByRequest{
+		UserId:       userID,
+		CandidateIds: candidateIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blacklist: %w", err)
+	}
+	for _, id := range res.UserIds {
+		blocked[id] = true
+	}
+	return blocked, nil
+}
+
+func (r *messageRepo) GetConversationMessagesSingle(
+	ctx context.Context,
+	senderID, targetID int64,
+	lastMsgID int64,
+	pageSize int,
+) (*ConversationMessages, error) {
+	db := r.db.WithContext(ctx)
+
+	// 1. 查找单聊 thread
+	var thread model.Thread
+	if err := db.Where(
+		"(peer_a = ? AND peer_b = ?) OR (peer_a = ? AND peer_b = ?)",
+		senderID, targetID, targetID, senderID,
+	).First(&thread).Error; err != nil {
+		return nil, err
+	}
+
+	// 2. 游标转换：把前端传来的雪花 ID (lastMsgID) 翻译成内部的连续序号 (SeqID)
+	var cursorSeqID int64
+	if lastMsgID > 0 {
+		// 去数据库里查这条消息对应的 seq_id
+		if err := db.Model(&model.Message{}).
+			Where("thread_id = ? AND id = ?", thread.ID, lastMsgID).
+			Pluck("seq_id", &cursorSeqID).Error; err != nil {
+			return nil, fmt.Errorf("invalid cursor message id: %w", err)
+		}
+	}
+
+	// 3. 读取当前用户的会话条目：未读数 + 清空记录水位线
+	conv, err := getOwnerConversation(db, senderID, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 查询消息（使用 SeqID 进行严格时序分页）
+	// 已撤回的消息也要返回，由上层展示为撤回提示
+	messages := make([]*model.Message, 0, pageSize+1)
+	query := visibleMessages(db, senderID, thread.ID, conv.ClearedSeq).
+		Order("seq_id DESC"). // 【修改】必须用 seq_id 倒序，保证时序绝对正确
+		Limit(pageSize + 1)
+
+	if cursorSeqID > 0 {
+		// 【修改】使用 seq_id < ?
+		// 注意：这里用 `<` 而不是原来的 `<=`，因为正常的下拉刷新不应该把刚才那条基准消息再查出来一遍
+		query = query.Where("seq_id < ?", cursorSeqID)
+	}
+
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := false
+	if len(messages) > pageSize {
+		hasMore = true
+		messages = messages[:pageSize] // 丢弃最后一条，保持 pageSize
+	}
+
+	// 5. 补充用户信息、引用和表情回应
+	messageWithUserInfos, err := r.buildSingleMessages(ctx, db, senderID, targetID, messages)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ConversationMessages{
 		Thread:   &thread,
 		Messages: messageWithUserInfos,
 		HasMore:  hasMore,
-		Unread:   unreadCount,
+		Unread:   conv.UnreadCount,
 	}, nil
 }
 
@@ -674,11 +1598,15 @@ func (r *messageRepo) GetConversationMessagesGroup(
 ) (*ConversationGroupMessages, error) {
 	db := r.db.WithContext(ctx)
 
-	// 1. 获取群聊 thread
+	// 1. 获取群聊 thread，并确认是群成员
 	var thread model.Thread
 	if err := db.Where("group_id = ?", groupID).First(&thread).Error; err != nil {
 		return nil, err
 	}
+	floor, err := r.readFloor(ctx, senderID, &thread)
+	if err != nil {
+		return nil, err
+	}
 
 	// 2. 游标转换：将外部传递的雪花 MsgID 转换为内部严格连续的 SeqID
 	var cursorSeqID int64
@@ -690,108 +1618,623 @@ func (r *messageRepo) GetConversationMessagesGroup(
 		}
 	}
 
-	// 3. 查询消息（分页）：使用 SeqID 保证绝对时序
-	messages := make([]*model.Message, 0, pageSize+1)
-	query := db.Model(&model.Message{}).
-		Where("thread_id = ? AND is_withdrawed = ?", thread.ID, false).
-		Order("seq_id DESC"). // 【修改】使用 seq_id 倒序
-		Limit(pageSize + 1)
+	// 3. 读取当前用户的会话条目：未读数 + 清空记录水位线
+	conv, err := getOwnerConversation(db, senderID, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 查询消息（分页）：使用 SeqID 保证绝对时序，入群之前的消息不可见
+	// 已撤回的消息也要返回，由上层展示为撤回提示
+	messages := make([]*model.Message, 0, pageSize+1)
+	query := visibleMessages(db, senderID, thread.ID, max(conv.ClearedSeq, floor)).
+		Order("seq_id DESC"). // 【修改】使用 seq_id 倒序
+		Limit(pageSize + 1)
+
+	if cursorSeqID > 0 {
+		// 【修改】使用 seq_id < ? 进行游标分页，完美规避重复数据
+		query = query.Where("seq_id < ?", cursorSeqID)
+	}
+
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := false
+	if len(messages) > pageSize {
+		hasMore = true
+		messages = messages[:pageSize] // 丢弃最后一条，保持 pageSize
+	}
+
+	// 5. 补充用户信息、群昵称和引用
+	messageWithUserInfos, err := r.buildGroupMessages(ctx, db, groupID, floor, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConversationGroupMessages{
+		Thread:   &thread,
+		Messages: messageWithUserInfos,
+		HasMore:  hasMore,
+		Unread:   conv.UnreadCount,
+	}, nil
+}
+
+// 补充单聊消息的发送者信息、撤回人、引用摘要和表情回应
+func (r *messageRepo) buildSingleMessages(ctx context.Context, db *gorm.DB, senderID, targetID int64,
+	messages []*model.Message) ([]*MessageWithUser, error) {
+	// 1. 调用 user-service 获取用户信息
+	userIDs := []int64{senderID, targetID}
+	userResp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get UserInfos: %w", err)
+	}
+
+	// 2. 建立 userMap
+	userMap := make(map[int64]UserInfo, len(userResp.Users))
+	for _, u := range userResp.Users {
+		userMap[u.UserId] = UserInfo{
+			UserID:   u.UserId,
+			Nickname: u.Nickname,
+			Avatar:   u.Avatar,
+		}
+	}
+
+	// 3. 读取被引用的消息（单聊中引用的发送者只会是双方之一）和表情回应
+	quoted, err := loadQuotedMessages(db, messages)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := loadReactions(db, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 组装返回数据
+	messageWithUserInfos := make([]*MessageWithUser, 0, len(messages))
+	for _, m := range messages {
+		mwu := &MessageWithUser{
+			Message: *m,
+			User:    userMap[m.SenderID], // 直接 O(1) 查
+		}
+		if m.IsWithdrawed && m.WithdrawnBy != nil {
+			u := userMap[*m.WithdrawnBy]
+			mwu.WithdrawnBy = &u
+		}
+		if m.ReplyToMsgID != nil {
+			if q, ok := quoted[*m.ReplyToMsgID]; ok {
+				mwu.ReplyTo = toQuotedMessage(q, userMap[q.SenderID], 0)
+			}
+		}
+		mwu.Reactions = reactions[m.MsgID]
+		messageWithUserInfos = append(messageWithUserInfos, mwu)
+	}
+	return messageWithUserInfos, nil
+}
+
+// 补充群消息的发送者信息、群昵称、撤回人、引用摘要和回复数；
+// floor 是查看者的入群位置，引用入群之前的消息时不返回内容
+func (r *messageRepo) buildGroupMessages(ctx context.Context, db *gorm.DB, groupID uuid.UUID, floor int64,
+	messages []*model.Message) ([]*MessageWithUser, error) {
+	// 1. 读取被引用的消息
+	quoted, err := loadQuotedMessages(db, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 收集并去重 senderIDs (优化网络开销)
+	senderIDMap := make(map[int64]struct{})
+	for _, m := range messages {
+		senderIDMap[m.SenderID] = struct{}{}
+		if m.WithdrawnBy != nil {
+			senderIDMap[*m.WithdrawnBy] = struct{}{}
+		}
+	}
+	for _, q := range quoted {
+		if q.SeqID > floor {
+			senderIDMap[q.SenderID] = struct{}{}
+		}
+	}
+	uniqueSenderIDs := make([]int64, 0, len(senderIDMap))
+	for id := range senderIDMap {
+		uniqueSenderIDs = append(uniqueSenderIDs, id)
+	}
+
+	// 3. 调用 user-service 获取用户信息
+	userResp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
+		UserIds: uniqueSenderIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get user infos: %w", err)
+	}
+
+	// 4. 调用 group-service 获取群昵称 (群聊专属)
+	groupResp, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
+		GroupId: groupID.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get group members: %w", err)
+	}
+
+	// 5. 建立查找表
+	userMap := make(map[int64]UserInfo, len(userResp.Users))
+	for _, u := range userResp.Users {
+		userMap[u.UserId] = UserInfo{ // 直接存值而不是指针，避免后续查空引发 Panic
+			UserID:   u.UserId,
+			Nickname: u.Nickname,
+			Avatar:   u.Avatar,
+		}
+	}
+
+	groupNicknameMap := make(map[int64]string, len(groupResp.Members))
+	for _, gm := range groupResp.Members {
+		groupNicknameMap[gm.UserId] = gm.Nickname
+	}
+
+	// 6. 统计每条消息的回复数，读取 @ 列表和表情回应
+	replyCounts, err := countReplies(db, messages)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := loadMentions(db, messages)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := loadReactions(db, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	lookupUser := func(id int64) UserInfo {
+		u, exists := userMap[id]
+		if !exists {
+			u = UserInfo{UserID: id, Nickname: "未知用户"}
+		}
+		if nickname := groupNicknameMap[id]; nickname != "" {
+			u.Nickname = nickname
+		}
+		return u
+	}
+
+	// 7. 组装返回数据 (包含安全防御)
+	messageWithUserInfos := make([]*MessageWithUser, 0, len(messages))
+	for _, m := range messages {
+		// 安全获取用户信息，如果没有拿到，给个默认兜底，防止前端渲染报错
+		uInfo, exists := userMap[m.SenderID]
+		if !exists {
+			uInfo = UserInfo{UserID: m.SenderID, Nickname: "未知用户"}
+		}
+
+		mwu := &MessageWithUser{
+			Message:       *m,
+			GroupNickname: groupNicknameMap[m.SenderID],
+			User:          uInfo,
+		}
+		if m.IsWithdrawed && m.WithdrawnBy != nil {
+			wInfo := lookupUser(*m.WithdrawnBy)
+			mwu.WithdrawnBy = &wInfo
+		}
+		if m.ReplyToMsgID != nil {
+			if q, ok := quoted[*m.ReplyToMsgID]; ok {
+				mwu.ReplyTo = toQuotedMessage(q, lookupUser(q.SenderID), floor)
+			}
+		}
+		mwu.ReplyCount = replyCounts[m.MsgID]
+		mwu.Mentions = mentions[m.MsgID]
+		mwu.Reactions = reactions[m.MsgID]
+		messageWithUserInfos = append(messageWithUserInfos, mwu)
+	}
+	return messageWithUserInfos, nil
+}
+
+// 统计每条消息被回复的次数
+func countReplies(db *gorm.DB, messages []*model.Message) (map[int64]int, error) {
+	counts := make(map[int64]int)
+	if len(messages) == 0 {
+		return counts, nil
+	}
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.MsgID)
+	}
+	var rows []struct {
+		ReplyToMsgID int64
+		Count        int
+	}
+	if err := db.Model(&model.Message{}).
+		Select("reply_to_msg_id, COUNT(*) AS count").
+		Where("reply_to_msg_id IN ?", ids).
+		Group("reply_to_msg_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ReplyToMsgID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetGroupReplies 群聊子话题：按时间正序列出直接回复某条消息的所有消息
+func (r *messageRepo) GetGroupReplies(ctx context.Context, userID int64, groupID uuid.UUID, rootMsgID, lastMsgID int64,
+	pageSize int) (*ConversationGroupMessages, error) {
+	db := r.db.WithContext(ctx)
+
+	var thread model.Thread
+	if err := db.Where("group_id = ?", groupID).First(&thread).Error; err != nil {
+		return nil, err
+	}
+	floor, err := r.readFloor(ctx, userID, &thread)
+	if err != nil {
+		return nil, err
+	}
+
+	var root model.Message
+	if err := db.Where("id = ? AND thread_id = ? AND seq_id > ?", rootMsgID, thread.ID, floor).
+		First(&root).Error; err != nil {
+		return nil, fmt.Errorf("root message not found: %w", err)
+	}
+
+	conv, err := getOwnerConversation(db, userID, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursorSeqID int64
+	if lastMsgID > 0 {
+		if err := db.Model(&model.Message{}).
+			Where("thread_id = ? AND id = ?", thread.ID, lastMsgID).
+			Pluck("seq_id", &cursorSeqID).Error; err != nil {
+			return nil, fmt.Errorf("invalid cursor message id: %w", err)
+		}
+	}
+
+	messages := make([]*model.Message, 0, pageSize+1)
+	query := visibleMessages(db, userID, thread.ID, max(conv.ClearedSeq, floor)).
+		Where("reply_to_msg_id = ?", rootMsgID).
+		Order("seq_id ASC").
+		Limit(pageSize + 1)
+	if cursorSeqID > 0 {
+		query = query.Where("seq_id > ?", cursorSeqID)
+	}
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := false
+	if len(messages) > pageSize {
+		hasMore = true
+		messages = messages[:pageSize]
+	}
+
+	replies, err := r.buildGroupMessages(ctx, db, groupID, floor, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &ConversationGroupMessages{
+		Thread:   &thread,
+		Messages: replies,
+		HasMore:  hasMore,
+	}, nil
+}
+
+// 以某条消息为锚点取消息的方向
+const (
+	WindowBefore = "before" // 锚点之前（更早）的消息，不含锚点
+	WindowAfter  = "after"  // 锚点之后（更新）的消息，不含锚点
+	WindowAround = "around" // 锚点前后各一半，包含锚点
+)
+
+// 以锚点为中心的一段消息，按 seq_id 倒序，与普通历史分页一致
+type MessageWindow struct {
+	Thread        *model.Thread
+	Messages      []*MessageWithUser
+	AnchorSeq     int64
+	HasMoreBefore bool
+	HasMoreAfter  bool
+}
+
+// GetMessageWindow 跳转到指定消息：按 anchorMsgID（优先）或 anchorSeq 定位，向前、向后或前后双向取消息。
+// targetID 和 groupID 二选一，分别对应单聊和群聊。
+func (r *messageRepo) GetMessageWindow(ctx context.Context, userID, targetID int64, groupID *uuid.UUID,
+	anchorMsgID, anchorSeq int64, direction string, limit int) (*MessageWindow, error) {
+	db := r.db.WithContext(ctx)
+
+	// 1. 找到 thread 并校验权限，群聊入群之前的消息不可见
+	var thread model.Thread
+	var floor int64
+	if groupID != nil {
+		if err := db.Where("group_id = ?", *groupID).First(&thread).Error; err != nil {
+			return nil, err
+		}
+		var err error
+		if floor, err = r.readFloor(ctx, userID, &thread); err != nil {
+			return nil, err
+		}
+	} else if err := db.Where(
+		"(peer_a = ? AND peer_b = ?) OR (peer_a = ? AND peer_b = ?)",
+		userID, targetID, targetID, userID,
+	).First(&thread).Error; err != nil {
+		return nil, err
+	}
+
+	// 2. 把锚点消息翻译成 SeqID
+	if anchorMsgID > 0 {
+		if err := db.Model(&model.Message{}).
+			Where("thread_id = ? AND id = ?", thread.ID, anchorMsgID).
+			Select("seq_id").
+			Scan(&anchorSeq).Error; err != nil {
+			return nil, err
+		}
+		if anchorSeq == 0 {
+			return nil, errors.New("anchor message not found")
+		}
+	}
+	if anchorSeq <= 0 {
+		return nil, errors.New("invalid anchor")
+	}
+
+	conv, err := getOwnerConversation(db, userID, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+	visible := func() *gorm.DB { return visibleMessages(db, userID, thread.ID, max(conv.ClearedSeq, floor)) }
+
+	// 3. 按方向取消息，多取一条用来判断是否还有更多
+	var older, newer []*model.Message
+	beforeLimit, afterLimit := 0, 0
+	switch direction {
+	case WindowBefore:
+		beforeLimit = limit
+	case WindowAfter:
+		afterLimit = limit
+	case WindowAround:
+		beforeLimit = limit / 2
+		afterLimit = limit - beforeLimit - 1 // 锚点本身占一条
+	default:
+		return nil, errors.New("invalid direction")
+	}
+
+	window := &MessageWindow{Thread: &thread, AnchorSeq: anchorSeq}
+	if beforeLimit > 0 {
+		if err := visible().Where("seq_id < ?", anchorSeq).
+			Order("seq_id DESC").Limit(beforeLimit + 1).Find(&older).Error; err != nil {
+			return nil, err
+		}
+		if len(older) > beforeLimit {
+			window.HasMoreBefore = true
+			older = older[:beforeLimit]
+		}
+	}
+	if afterLimit > 0 {
+		if err := visible().Where("seq_id > ?", anchorSeq).
+			Order("seq_id ASC").Limit(afterLimit + 1).Find(&newer).Error; err != nil {
+			return nil, err
+		}
+		if len(newer) > afterLimit {
+			window.HasMoreAfter = true
+			newer = newer[:afterLimit]
+		}
+	}
+	var anchor []*model.Message
+	if direction == WindowAround {
+		if err := visible().Where("seq_id = ?", anchorSeq).Find(&anchor).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. 没有取的那一侧单独判断是否还有消息：before/after 的反方向，或者 around 的 limit 太小分不到的一侧。
+	// before/after 的结果不含锚点，锚点本身算在另一侧
+	exists := func(query *gorm.DB) (bool, error) {
+		var ids []int64
+		err := query.Limit(1).Pluck("id", &ids).Error
+		return len(ids) > 0, err
+	}
+	olderCond, newerCond := "seq_id <= ?", "seq_id >= ?"
+	if direction == WindowAround {
+		olderCond, newerCond = "seq_id < ?", "seq_id > ?"
+	}
+	if beforeLimit == 0 {
+		if window.HasMoreBefore, err = exists(visible().Where(olderCond, anchorSeq)); err != nil {
+			return nil, err
+		}
+	}
+	if afterLimit == 0 {
+		if window.HasMoreAfter, err = exists(visible().Where(newerCond, anchorSeq)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 5. 拼成 seq_id 倒序
+	messages := make([]*model.Message, 0, len(newer)+len(anchor)+len(older))
+	for i := len(newer) - 1; i >= 0; i-- {
+		messages = append(messages, newer[i])
+	}
+	messages = append(messages, anchor...)
+	messages = append(messages, older...)
 
-	if cursorSeqID > 0 {
-		// 【修改】使用 seq_id < ? 进行游标分页，完美规避重复数据
-		query = query.Where("seq_id < ?", cursorSeqID)
+	if thread.GroupID != nil {
+		window.Messages, err = r.buildGroupMessages(ctx, db, *thread.GroupID, floor, messages)
+	} else {
+		window.Messages, err = r.buildSingleMessages(ctx, db, userID, targetID, messages)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return window, nil
+}
 
-	if err := query.Find(&messages).Error; err != nil {
+// 读取用户自己的会话条目，不存在时返回零值（例如从未收发过消息的群成员）
+func getOwnerConversation(db *gorm.DB, ownerID, threadID int64) (*model.Conversation, error) {
+	var conv model.Conversation
+	err := db.Where("owner_id = ? AND thread_id = ?", ownerID, threadID).First(&conv).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
+	return &conv, nil
+}
 
-	hasMore := false
-	if len(messages) > pageSize {
-		hasMore = true
-		messages = messages[:pageSize] // 丢弃最后一条，保持 pageSize
+// 对某个用户可见的消息：排除清空水位线之前的消息、"仅自己删除" 的消息和已过期的消息
+func visibleMessages(db *gorm.DB, userID, threadID, clearedSeq int64) *gorm.DB {
+	return db.Model(&model.Message{}).
+		Where("thread_id = ? AND seq_id > ? AND is_expired = ?", threadID, clearedSeq, false).
+		Where("id NOT IN (?)", db.Model(&model.HiddenMessage{}).Select("message_id").Where("user_id = ?", userID))
+}
+
+// 删除消息（仅自己）：只写隐藏记录，不影响其他人；返回涉及到的 thread 用于清缓存
+func (r *messageRepo) HideMessages(ctx context.Context, userID int64, messageIDs []int64) ([]*model.Thread, error) {
+	if len(messageIDs) == 0 {
+		return nil, errors.New("message ids cannot be empty")
 	}
 
-	// 4. 查询未读数
-	var unreadCount int
-	err := db.Model(&model.Conversation{}).
-		Where("owner_id = ? AND thread_id = ?", senderID, thread.ID).
-		Pluck("unread_count", &unreadCount).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Preload("Thread").
+		Where("id IN ?", messageIDs).
+		Find(&messages).Error; err != nil {
 		return nil, err
 	}
+	// 请求里可能有重复的 ID
+	if len(messages) != len(uniqueIDs(messageIDs)) {
+		return nil, errors.New("message not found")
+	}
 
-	// 5. 收集并去重 senderIDs (优化网络开销)
-	senderIDMap := make(map[int64]struct{})
+	// 只能删除自己能看到的会话里的消息
+	threadMap := make(map[int64]*model.Thread)
+	for i := range messages {
+		threadMap[messages[i].ThreadID] = &messages[i].Thread
+	}
+	threads := make([]*model.Thread, 0, len(threadMap))
+	for _, t := range threadMap {
+		ok, err := r.canReadThread(ctx, userID, t)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("insufficient permissions")
+		}
+		threads = append(threads, t)
+	}
+
+	hidden := make([]model.HiddenMessage, 0, len(messages))
 	for _, m := range messages {
-		senderIDMap[m.SenderID] = struct{}{}
+		hidden = append(hidden, model.HiddenMessage{UserID: userID, MessageID: m.MsgID})
 	}
-	uniqueSenderIDs := make([]int64, 0, len(senderIDMap))
-	for id := range senderIDMap {
-		uniqueSenderIDs = append(uniqueSenderIDs, id)
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&hidden).Error; err != nil {
+		return nil, err
 	}
+	return threads, nil
+}
 
-	// 6. 调用 user-service 获取用户信息
-	userResp, err := r.userClient.GetUserInfos(ctx, &userpb.GetUserInfosRequest{
-		UserIds: uniqueSenderIDs,
+// 清空聊天记录：把水位线移动到当前最大的 seq_id，同时清零未读
+func (r *messageRepo) ClearHistory(ctx context.Context, userID, threadID int64) (*model.Thread, error) {
+	var thread model.Thread
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return clearHistory(tx, userID, threadID, &thread)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("fail to get user infos: %w", err)
+		return nil, err
 	}
+	return &thread, nil
+}
 
-	// 7. 调用 group-service 获取群昵称 (群聊专属)
-	groupResp, err := r.groupClient.ListGroupMembers(ctx, &grouppb.ListGroupMembersRequest{
-		GroupId: groupID.String(),
+// 删除会话：从会话列表中隐藏，收到新消息时会重新出现；clearHistory 为 true 时同时清空聊天记录
+func (r *messageRepo) DeleteConversation(ctx context.Context, userID, threadID int64, clearHistoryToo bool) (*model.Thread, error) {
+	var thread model.Thread
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if clearHistoryToo {
+			if err := clearHistory(tx, userID, threadID, &thread); err != nil {
+				return err
+			}
+		} else if err := tx.Where("id = ?", threadID).First(&thread).Error; err != nil {
+			return err
+		}
+
+		res := tx.Model(&model.Conversation{}).
+			Where("owner_id = ? AND thread_id = ?", userID, threadID).
+			Updates(map[string]interface{}{
+				"is_deleted":    true,
+				"unread_count":  0,
+				"mention_count": 0,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("conversation not found")
+		}
+		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("fail to get group members: %w", err)
+		return nil, err
 	}
+	return &thread, nil
+}
 
-	// 8. 建立查找表
-	userMap := make(map[int64]UserInfo, len(userResp.Users))
-	for _, u := range userResp.Users {
-		userMap[u.UserId] = UserInfo{ // 直接存值而不是指针，避免后续查空引发 Panic
-			UserID:   u.UserId,
-			Nickname: u.Nickname,
-			Avatar:   u.Avatar,
-		}
+func clearHistory(tx *gorm.DB, userID, threadID int64, thread *model.Thread) error {
+	if err := tx.Where("id = ?", threadID).First(thread).Error; err != nil {
+		return err
 	}
 
-	groupNicknameMap := make(map[int64]string, len(groupResp.Members))
-	for _, gm := range groupResp.Members {
-		groupNicknameMap[gm.UserId] = gm.Nickname
+	var maxSeq int64
+	if err := tx.Model(&model.Message{}).
+		Where("thread_id = ?", threadID).
+		Select("COALESCE(MAX(seq_id), 0)").
+		Scan(&maxSeq).Error; err != nil {
+		return err
 	}
 
-	// 9. 组装返回数据 (包含安全防御)
-	messageWithUserInfos := make([]*MessageWithUser, 0, len(messages))
-	for _, m := range messages {
-		// 安全获取用户信息，如果没有拿到，给个默认兜底，防止前端渲染报错
-		uInfo, exists := userMap[m.SenderID]
-		if !exists {
-			uInfo = UserInfo{UserID: m.SenderID, Nickname: "未知用户"}
-		}
-
-		mwu := &MessageWithUser{
-			Message:       *m,
-			GroupNickname: groupNicknameMap[m.SenderID],
-			User:          uInfo,
-		}
-		messageWithUserInfos = append(messageWithUserInfos, mwu)
+	res := tx.Model(&model.Conversation{}).
+		Where("owner_id = ? AND thread_id = ?", userID, threadID).
+		Updates(map[string]interface{}{
+			"cleared_seq":   maxSeq,
+			"unread_count":  0,
+			"mention_count": 0,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("conversation not found")
 	}
 
-	return &ConversationGroupMessages{
-		Thread:   &thread,
-		Messages: messageWithUserInfos,
-		HasMore:  hasMore,
-		Unread:   unreadCount,
-	}, nil
+	// 被清空的消息同时视为已读
+	return tx.Model(&model.MessageStatus{}).
+		Where("user_id = ? AND message_id IN (?)", userID,
+			tx.Model(&model.Message{}).Select("id").Where("thread_id = ?", threadID),
+		).
+		Update("status", 1).Error
 }
 
 // 当前哪个用户在读，在哪个thread读，同时获取最后已读messageid
 func (r *messageRepo) UpdateUnread(ctx context.Context, userID, threadID int64) error {
+	var thread model.Thread
+	if err := r.db.WithContext(ctx).Where("id = ?", threadID).First(&thread).Error; err != nil {
+		return err
+	}
+	// 大群的会话可能在这里才创建，先确认是群成员
+	if thread.LargeGroup {
+		ok, err := r.canReadThread(ctx, userID, &thread)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotGroupMember
+		}
+	}
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 更新 MessageStatus 已读状态
-		if err := tx.Model(&model.MessageStatus{}).
+		// 大群没有 MessageStatus，把已读游标移到最新一条，还没有会话的成员这时创建
+		if thread.LargeGroup {
+			lastMsgID := int64(0)
+			if thread.LastMessageID != nil {
+				lastMsgID = *thread.LastMessageID
+			}
+			if err := upsertReadCursor(tx, []int64{userID}, threadID, lastMsgID, thread.LastSeq); err != nil {
+				return err
+			}
+		} else if err := tx.Model(&model.MessageStatus{}).
 			Where("user_id = ? AND message_id IN (?)", userID,
 				tx.Model(&model.Message{}).Select("id").Where("thread_id = ?", threadID),
 			).
@@ -799,10 +2242,14 @@ func (r *messageRepo) UpdateUnread(ctx context.Context, userID, threadID int64)
 			return err
 		}
 
-		// 更新 Conversation.unread_count
+		// 更新 Conversation.unread_count，同时清除 @ 提醒和手动标记的未读
 		if err := tx.Model(&model.Conversation{}).
 			Where("owner_id = ? AND thread_id = ?", userID, threadID).
-			Update("unread_count", 0).Error; err != nil {
+			Updates(map[string]interface{}{
+				"unread_count":  0,
+				"mention_count": 0,
+				"marked_unread": false,
+			}).Error; err != nil {
 			return err
 		}
 		return nil
@@ -845,6 +2292,9 @@ func (r *messageRepo) GetSingleConversationsFromDB(ctx context.Context, userID i
 			msgMap[m.MsgID] = &m
 		}
 	}
+	if err := r.dropInvisibleMessages(ctx, userID, msgMap); err != nil {
+		return nil, err
+	}
 
 	// 4. 拼接 Conversation
 	for _, c := range conversations {
@@ -859,13 +2309,18 @@ func (r *messageRepo) GetSingleConversationsFromDB(ctx context.Context, userID i
 		if c.LastMessageID != nil {
 			lastMsg = msgMap[*c.LastMessageID]
 		}
+		// 清空聊天记录之后，最后一条消息不再展示
+		if lastMsg != nil && lastMsg.SeqID <= c.ClearedSeq {
+			lastMsg = nil
+		}
 
 		convs = append(convs, &SingleConversation{
-			ThreadID:    c.ThreadID,
-			PeerID:      peerID,
-			LastMessage: lastMsg,
-			UnreadCount: c.UnreadCount,
-			UpdateTime:  c.UpdatedAt,
+			ThreadID:             c.ThreadID,
+			PeerID:               peerID,
+			LastMessage:          lastMsg,
+			UnreadCount:          c.UnreadCount,
+			UpdateTime:           c.UpdatedAt,
+			ConversationSettings: settingsOf(&c),
 		})
 	}
 	return convs, nil
@@ -884,6 +2339,19 @@ func (r *messageRepo) GetGroupConversationsFromDB(ctx context.Context, userID in
 		Find(&conversations).Error; err != nil {
 		return nil, err
 	}
+	// 大群的最后一条消息和未读数不在会话上，从 Thread 和已读游标补上
+	large, err := largeGroupCounts(r.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range conversations {
+		c := &conversations[i]
+		if n, ok := large[c.ThreadID]; ok {
+			c.LastMessageID = c.Thread.LastMessageID
+			c.UnreadCount = n.Unread
+			c.MentionCount += n.MentionAll
+		}
+	}
 
 	// 收集 LastMessageID
 	convs := make([]*GroupConversation, 0, len(conversations))
@@ -904,6 +2372,9 @@ func (r *messageRepo) GetGroupConversationsFromDB(ctx context.Context, userID in
 			msgMap[m.MsgID] = &m
 		}
 	}
+	if err := r.dropInvisibleMessages(ctx, userID, msgMap); err != nil {
+		return nil, err
+	}
 	// 拼接 Conversation
 	for _, c := range conversations {
 		groupID := c.Thread.GroupID
@@ -911,18 +2382,45 @@ func (r *messageRepo) GetGroupConversationsFromDB(ctx context.Context, userID in
 		if c.LastMessageID != nil {
 			lastMsg = msgMap[*c.LastMessageID]
 		}
+		// 清空聊天记录之后，最后一条消息不再展示
+		if lastMsg != nil && lastMsg.SeqID <= c.ClearedSeq {
+			lastMsg = nil
+		}
 		convs = append(convs, &GroupConversation{
-			ThreadID:    c.ThreadID,
-			GroupID:     *groupID,
-			LastMessage: lastMsg,
-			UnreadCount: c.UnreadCount,
-			UpdateTime:  c.UpdatedAt,
+			ThreadID:             c.ThreadID,
+			GroupID:              *groupID,
+			LastMessage:          lastMsg,
+			UnreadCount:          c.UnreadCount,
+			MentionCount:         c.MentionCount,
+			UpdateTime:           c.UpdatedAt,
+			Dissolved:            c.Thread.DissolvedAt != nil,
+			ConversationSettings: settingsOf(&c),
 		})
 	}
 	return convs, nil
 }
 
-// TODO: 添加置顶功能
+// 从 msgMap 中移除用户 "仅自己删除" 的消息
+func (r *messageRepo) dropInvisibleMessages(ctx context.Context, userID int64, msgMap map[int64]*model.Message) error {
+	if len(msgMap) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(msgMap))
+	for id := range msgMap {
+		ids = append(ids, id)
+	}
+	var hidden []int64
+	if err := r.db.WithContext(ctx).Model(&model.HiddenMessage{}).
+		Where("user_id = ? AND message_id IN ?", userID, ids).
+		Pluck("message_id", &hidden).Error; err != nil {
+		return err
+	}
+	for _, id := range hidden {
+		delete(msgMap, id)
+	}
+	return nil
+}
+
 func (r *messageRepo) GetConversations(ctx context.Context, userID int64) ([]*ConversationWithUser, error) {
 	// 从数据库查单聊会话
 	singleConversations, err := r.GetSingleConversationsFromDB(ctx, userID)
@@ -997,7 +2495,8 @@ func (r *messageRepo) GetConversations(ctx context.Context, userID int64) ([]*Co
 				Nickname: u.Nickname,
 				Avatar:   u.Avatar,
 			},
-			UpdateTime: conv.UpdateTime,
+			UpdateTime:           conv.UpdateTime,
+			ConversationSettings: conv.ConversationSettings,
 		})
 	}
 	//群聊
@@ -1008,20 +2507,344 @@ func (r *messageRepo) GetConversations(ctx context.Context, userID int64) ([]*Co
 			continue
 		}
 		result = append(result, &ConversationWithUser{
-			ThreadID:    conv.ThreadID,
-			LastMessage: conv.LastMessage,
-			UnreadCount: conv.UnreadCount,
+			ThreadID:     conv.ThreadID,
+			LastMessage:  conv.LastMessage,
+			UnreadCount:  conv.UnreadCount,
+			MentionCount: conv.MentionCount,
 			GroupInfo: &GroupInfo{
 				GroupID:   g.GroupID,
 				GroupName: g.GroupName,
 				Avatar:    g.Avatar,
 			},
-			UpdateTime: conv.UpdateTime,
+			UpdateTime:           conv.UpdateTime,
+			Dissolved:            conv.Dissolved,
+			ConversationSettings: conv.ConversationSettings,
 		})
 	}
-	// 最终按更新时间排序
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].UpdateTime.After(result[j].UpdateTime)
+	// 附上草稿
+	drafts, err := r.getDrafts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range result {
+		conv.Draft = drafts[conv.ThreadID]
+	}
+
+	// 置顶会话在最前（后置顶的排前面），其余按更新时间排序
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if a.Pinned && a.PinnedAt != nil && b.PinnedAt != nil && !a.PinnedAt.Equal(*b.PinnedAt) {
+			return a.PinnedAt.After(*b.PinnedAt)
+		}
+		return a.UpdateTime.After(b.UpdateTime)
+	})
+	return result, nil
+}
+
+func settingsOf(c *model.Conversation) ConversationSettings {
+	return ConversationSettings{
+		Pinned:       c.Pinned,
+		PinnedAt:     c.PinnedAt,
+		Muted:        isMuteActive(c.Mute, c.MuteUntil, time.Now()),
+		MuteUntil:    c.MuteUntil,
+		MarkedUnread: c.MarkedUnread,
+	}
+}
+
+// 免打扰是否生效：设置了免打扰且没有过期
+func isMuteActive(mute bool, until *time.Time, now time.Time) bool {
+	return mute && (until == nil || until.After(now))
+}
+
+// 置顶 / 取消置顶
+func (r *messageRepo) SetPinned(ctx context.Context, userID, threadID int64, pinned bool) error {
+	var pinnedAt *time.Time
+	if pinned {
+		now := time.Now()
+		pinnedAt = &now
+	}
+	return r.updateConversation(ctx, userID, threadID, map[string]interface{}{
+		"pinned":    pinned,
+		"pinned_at": pinnedAt,
 	})
+}
+
+// 免打扰 / 取消免打扰，until 为 nil 表示一直免打扰
+func (r *messageRepo) SetMute(ctx context.Context, userID, threadID int64, mute bool, until *time.Time) error {
+	if !mute {
+		until = nil
+	}
+	return r.updateConversation(ctx, userID, threadID, map[string]interface{}{
+		"mute":       mute,
+		"mute_until": until,
+	})
+}
+
+// 标记为未读，进入会话（UpdateUnread）时自动清除
+func (r *messageRepo) MarkUnread(ctx context.Context, userID, threadID int64) error {
+	return r.updateConversation(ctx, userID, threadID, map[string]interface{}{
+		"marked_unread": true,
+	})
+}
+
+// 会话的未读状态，用来重建 Redis 未读计数
+type UnreadState struct {
+	ThreadID     int64
+	UnreadCount  int
+	LastSeq      int64 // 读取时会话里已经落库的最大 seq，UnreadCount 已经包含它之前的消息
+	MarkedUnread bool
+	Mute         bool
+	MuteUntil    *time.Time
+}
+
+// 用户未删除会话的未读状态，threadIDs 为空时返回所有会话；
+// 未读数和 LastSeq 在同一条语句里读出，保证 LastSeq 之前的消息都已经计入
+func (r *messageRepo) GetUnreadStates(ctx context.Context, userID int64, threadIDs ...int64) ([]*UnreadState, error) {
+	var states []*UnreadState
+	query := r.db.WithContext(ctx).Model(&model.Conversation{}).
+		Select("thread_id, unread_count, marked_unread, mute, mute_until, "+
+			"(SELECT COALESCE(MAX(m.seq_id), 0) FROM messages AS m WHERE m.thread_id = conversations.thread_id) AS last_seq").
+		Where("owner_id = ? AND is_deleted = ?", userID, false)
+	if len(threadIDs) > 0 {
+		query = query.Where("thread_id IN ?", threadIDs)
+	}
+	if err := query.Scan(&states).Error; err != nil {
+		return nil, err
+	}
+	large, err := largeGroupCounts(r.db.WithContext(ctx), userID, threadIDs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range states {
+		if n, ok := large[st.ThreadID]; ok {
+			st.UnreadCount = n.Unread
+			st.LastSeq = n.LastSeq
+		}
+	}
+	return states, nil
+}
+
+// SaveDraft 保存草稿，会话必须已经存在；回复的消息必须在同一会话内
+func (r *messageRepo) SaveDraft(ctx context.Context, userID, threadID int64, content string,
+	replyToMsgID *int64) (*model.Draft, error) {
+	db := r.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&model.Conversation{}).
+		Where("owner_id = ? AND thread_id = ?", userID, threadID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("conversation not found")
+	}
+	if replyToMsgID != nil && replyTarget(db, threadID, &SendOptions{ReplyToMsgID: replyToMsgID}) == nil {
+		return nil, ErrInvalidReplyTarget
+	}
+
+	draft := &model.Draft{
+		OwnerID:      userID,
+		ThreadID:     threadID,
+		Content:      content,
+		ReplyToMsgID: replyToMsgID,
+		UpdatedAt:    time.Now(),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "reply_to_msg_id", "updated_at"}),
+	}).Create(draft).Error; err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// DeleteDraft 删除草稿，返回是否真的删除了；草稿不存在时不报错
+func (r *messageRepo) DeleteDraft(ctx context.Context, userID, threadID int64) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("owner_id = ? AND thread_id = ?", userID, threadID).
+		Delete(&model.Draft{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetDraftThreadIDs 用户有草稿的会话
+func (r *messageRepo) GetDraftThreadIDs(ctx context.Context, userID int64) ([]int64, error) {
+	threadIDs := make([]int64, 0)
+	if err := r.db.WithContext(ctx).Model(&model.Draft{}).
+		Where("owner_id = ?", userID).
+		Pluck("thread_id", &threadIDs).Error; err != nil {
+		return nil, err
+	}
+	return threadIDs, nil
+}
+
+func (r *messageRepo) getDrafts(ctx context.Context, userID int64) (map[int64]*model.Draft, error) {
+	var drafts []*model.Draft
+	if err := r.db.WithContext(ctx).Where("owner_id = ?", userID).Find(&drafts).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[int64]*model.Draft, len(drafts))
+	for _, d := range drafts {
+		result[d.ThreadID] = d
+	}
 	return result, nil
 }
+
+// 只更新设置字段，不触发 updated_at 变化，避免会话因为设置操作跳到列表顶部
+func (r *messageRepo) updateConversation(ctx context.Context, userID, threadID int64, fields map[string]interface{}) error {
+	res := r.db.WithContext(ctx).Model(&model.Conversation{}).
+		Where("owner_id = ? AND thread_id = ?", userID, threadID).
+		UpdateColumns(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("conversation not found")
+	}
+	return nil
+}
+
+// 搜索结果：命中的消息、发送者和所在会话
+type SearchResult struct {
+	SearchHit
+	Sender UserInfo
+	Thread *model.Thread
+}
+
+// 调用者可以搜索的群会话和各自的入群位置，按当前加入的群计算，大群里没有会话记录的成员也包括在内；
+// 已解散的群按解散时记下的位置计算
+func (r *messageRepo) searchableGroups(ctx context.Context, q *SearchQuery) ([]GroupScope, error) {
+	if q.ThreadID > 0 {
+		var thread model.Thread
+		if err := r.db.WithContext(ctx).Where("id = ?", q.ThreadID).First(&thread).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if thread.GroupID == nil {
+			return nil, nil
+		}
+		floor, err := r.readFloor(ctx, q.UserID, &thread)
+		if err != nil {
+			if errors.Is(err, ErrNotGroupMember) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []GroupScope{{ThreadID: thread.ID, Floor: floor}}, nil
+	}
+
+	res, err := r.groupClient.ListUserGroups(ctx, &grouppb.ListUserGroupsRequest{UserId: q.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user groups: %w", err)
+	}
+	scopes, err := dissolvedGroupScopes(r.db.WithContext(ctx), q.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Groups) == 0 {
+		return scopes, nil
+	}
+	floors := make(map[uuid.UUID]int64, len(res.Groups))
+	groupIDs := make([]uuid.UUID, 0, len(res.Groups))
+	for _, g := range res.Groups {
+		id, err := uuid.Parse(g.GroupId)
+		if err != nil {
+			continue
+		}
+		if !g.FullHistory {
+			floors[id] = g.JoinSeq
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	var threads []*model.Thread
+	if err := r.db.WithContext(ctx).Select("id", "group_id").
+		Where("group_id IN ?", groupIDs).
+		Find(&threads).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range threads {
+		scopes = append(scopes, GroupScope{ThreadID: t.ID, Floor: floors[*t.GroupID]})
+	}
+	return scopes, nil
+}
+
+// SearchMessages 在用户自己的会话里全文检索消息
+func (r *messageRepo) SearchMessages(ctx context.Context, q *SearchQuery) ([]*SearchResult, bool, error) {
+	groups, err := r.searchableGroups(ctx, q)
+	if err != nil {
+		return nil, false, err
+	}
+	hits, hasMore, err := r.search.Search(ctx, q, groups)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(hits) == 0 {
+		return []*SearchResult{}, hasMore, nil
+	}
+
+	threadIDs := make([]int64, 0, len(hits))
+	senderSet := make(map[int64]struct{})
+	for _, h := range hits {
+		threadIDs = append(threadIDs, h.Message.ThreadID)
+		senderSet[h.Message.SenderID] = struct{}{}
+	}
+	var threads []*model.Thread
+	if err := r.db.WithContext(ctx).Where("id IN ?", threadIDs).Find(&threads).Error; err != nil {
+		return nil, false, err
+	}
+	threadMap := make(map[int64]*model.Thread, len(threads))
+	for _, t := range threads {
+		threadMap[t.ID] = t
+	}
+
+	senderIDs := make([]int64, 0, len(senderSet))
+	for id := range senderSet {
+		senderIDs = append(senderIDs, id)
+	}
+	users, err := r.GetUserInfos(ctx, senderIDs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := make([]*SearchResult, 0, len(hits))
+	for _, h := range hits {
+		sender, ok := users[h.Message.SenderID]
+		if !ok {
+			sender = UserInfo{UserID: h.Message.SenderID, Nickname: "未知用户"}
+		}
+		results = append(results, &SearchResult{
+			SearchHit: *h,
+			Sender:    sender,
+			Thread:    threadMap[h.Message.ThreadID],
+		})
+	}
+	return results, hasMore, nil
+}
+
+// 索引失败不影响消息本身，漏掉的消息由 ReindexMissing 在下次启动时补上
+func (r *messageRepo) indexMessage(ctx context.Context, msg *model.Message) {
+	if msg == nil {
+		return
+	}
+	if err := r.search.Index(ctx, msg); err != nil {
+		log.Println("更新消息搜索索引失败：", err)
+	}
+}
+
+func (r *messageRepo) reindexMessage(ctx context.Context, msgID int64) {
+	var msg model.Message
+	if err := r.db.WithContext(ctx).Where("id = ?", msgID).First(&msg).Error; err != nil {
+		log.Println("更新消息搜索索引失败：", err)
+		return
+	}
+	r.indexMessage(ctx, &msg)
+}
+
+func (r *messageRepo) removeFromIndex(ctx context.Context, msgID int64) {
+	if err := r.search.Remove(ctx, msgID); err != nil {
+		log.Println("删除消息搜索索引失败：", err)
+	}
+}
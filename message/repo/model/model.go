@@ -14,35 +14,143 @@ type Thread struct {
 	PeerA     *int64     `gorm:"index"`           // 单聊 A
 	PeerB     *int64     `gorm:"index"`           // 单聊 B
 	CreatedAt time.Time  `gorm:"autoCreateTime"`
+
+	RecallWindowSeconds *int64 // 群聊自定义撤回时间窗口（秒），nil 表示使用全局配置
+	DisappearSeconds    *int64 // 阅后即焚计时（秒），新消息发送后多久过期，nil 表示关闭
+
+	// 大群模式：不再给每个成员写会话和未读记录，最后一条消息记在 Thread 上，未读数按成员的已读游标计算
+	LargeGroup    bool `gorm:"default:false"`
+	LastMessageID *int64
+	LastSeq       int64 `gorm:"default:0"`
+
+	DissolvedAt *time.Time // 群解散的时间，解散后会话只读，nil 表示没有解散
 }
 
 // 用户会话条目（Conversation）
 type Conversation struct {
-	ID            int64     `gorm:"primaryKey;autoIncrement"`
-	OwnerID       int64     `gorm:"not null;index"`                                  // 会话所属用户
-	ThreadID      int64     `gorm:"not null;index"`                                  // 关联 Thread
-	Thread        Thread    `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"` //用preload 懒加载
-	LastMessageID *int64    `gorm:"index"`                                           // 最近一条消息
-	UnreadCount   int       `gorm:"default:0"`
-	Pinned        bool      `gorm:"default:false"`
-	Mute          bool      `gorm:"default:false"`
-	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
-	IsDeleted     bool      `gorm:"default:false"`
+	ID            int64  `gorm:"primaryKey;autoIncrement"`
+	OwnerID       int64  `gorm:"not null;index;uniqueIndex:uq_conv,priority:1"`   // 会话所属用户
+	ThreadID      int64  `gorm:"not null;index;uniqueIndex:uq_conv,priority:2"`   // 关联 Thread
+	Thread        Thread `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"` //用preload 懒加载
+	LastMessageID *int64 `gorm:"index"`                                           // 最近一条消息
+	UnreadCount   int    `gorm:"default:0"`
+	Pinned        bool   `gorm:"default:false"`
+	PinnedAt      *time.Time
+	Mute          bool       `gorm:"default:false"`
+	MuteUntil     *time.Time // 免打扰到期时间，nil 表示一直免打扰
+	MarkedUnread  bool       `gorm:"default:false"` // 手动标记为未读
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+	IsDeleted     bool       `gorm:"default:false"`
+	ClearedSeq    int64      `gorm:"default:0"` // 清空聊天记录的水位线，seq_id <= ClearedSeq 的消息对该用户不可见
+	MentionCount  int        `gorm:"default:0"` // 未读消息中 @我（含 @所有人）的数量
+	ReadSeq       int64      `gorm:"default:0"` // 大群的已读游标，seq_id > ReadSeq 的消息算未读
+	HistoryFloor  *int64     // 群解散时记下的可见起点（入群位置），解散后成员按它查看历史，nil 表示没有记录
 	// 保证每个用户同一个 thread 只会有一条记录
-	// UNIQUE(owner_id, thread_id) -> gorm 里用 index+uniqueConstraint
+	// UNIQUE(owner_id, thread_id) -> gorm 里用 uniqueIndex uq_conv
 }
 
+// 消息类型（Message.Kind）
+const (
+	KindText          int16 = 1
+	KindImage         int16 = 2
+	KindFile          int16 = 3
+	KindMergedForward int16 = 4 // 合并转发的聊天记录，Content 为 JSON 快照
+	KindSystem        int16 = 5 // 群系统消息（成员变动、禁言、改名等群事件），Content 为 JSON，SenderID 为操作人
+)
+
 // 消息（Message）
 type Message struct {
-	MsgID        int64     `gorm:"column:id;primaryKey;not null"`
-	SeqID        int64     `gorm:"column:seq_id;not null;index"`
-	ThreadID     int64     `gorm:"not null;index"`
-	Thread       Thread    `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"` //用preload 懒加载
-	SenderID     int64     `gorm:"not null;index"`
-	Kind         int16     `gorm:"not null"` // 消息类型 1. text 2. image 3. file
+	MsgID         int64     `gorm:"column:id;primaryKey;not null"`
+	SeqID         int64     `gorm:"column:seq_id;not null;index;index:idx_message_thread_seq,priority:2"`
+	ThreadID      int64     `gorm:"not null;index;index:idx_message_thread_seq,priority:1"`
+	Thread        Thread    `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"` //用preload 懒加载
+	SenderID      int64     `gorm:"not null;index"`
+	Kind          int16     `gorm:"not null"` // 消息类型，见 Kind* 常量
+	Content       string    `gorm:"type:text;not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	IsWithdrawed  bool      `gorm:"is_withdrawed"`
+	WithdrawnBy   *int64    // 撤回操作人（发送者本人或群主/管理员）
+	WithdrawnAt   *time.Time
+	EditedAt      *time.Time // 最近一次编辑时间，nil 表示从未编辑
+	ReplyToMsgID  *int64     `gorm:"index"`         // 引用回复的消息，只能引用同一会话内的消息
+	MentionAll    bool       `gorm:"default:false"` // @所有人，只有群主和管理员可以发送
+	ForwardedFrom *int64     // 逐条转发时记录原消息
+	SearchTokens  *string    `gorm:"type:text" json:"-"` // 全文检索分词结果，NULL 表示还没建索引
+	ExpiresAt     *time.Time `gorm:"index"`              // 到期后由后台任务清除内容，nil 表示不过期
+	IsExpired     bool       `gorm:"default:false"`      // 已过期，内容已清空，对所有人不可见
+}
+
+// 草稿（Draft）：每个用户每个会话一条，和 Conversation 一样按 (owner_id, thread_id) 唯一
+type Draft struct {
+	OwnerID      int64     `gorm:"primaryKey"`
+	ThreadID     int64     `gorm:"primaryKey"`
+	Thread       Thread    `gorm:"foreignKey:ThreadID;constraint:OnDelete:CASCADE"`
 	Content      string    `gorm:"type:text;not null"`
+	ReplyToMsgID *int64    // 草稿中正在回复的消息
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+// 定时消息状态（ScheduledMessage.Status）
+const (
+	ScheduledPending     int16 = 0 // 等待发送，可以编辑和取消
+	ScheduledDispatching int16 = 1 // 已被某个实例领取，正在投递
+	ScheduledSent        int16 = 2
+	ScheduledCanceled    int16 = 3
+	ScheduledFailed      int16 = 4 // 到期时校验没通过（例如已经不在群里），Error 记录原因
+)
+
+// 定时消息（ScheduledMessage）：到期后由 worker 走正常的发送流程投递到 Kafka
+type ScheduledMessage struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement"`
+	SenderID     int64      `gorm:"not null;index"`
+	TargetID     int64      // 单聊对象，群聊为 0
+	GroupID      *uuid.UUID `gorm:"type:uuid"`
+	Content      string     `gorm:"type:text;not null"`
+	ReplyToMsgID *int64
+	Mentions     []int64    `gorm:"type:text;serializer:json"`
+	MentionAll   bool       `gorm:"default:false"`
+	SendAt       time.Time  `gorm:"not null;index:idx_scheduled_due,priority:2"`
+	Status       int16      `gorm:"not null;default:0;index:idx_scheduled_due,priority:1"`
+	MsgID        *int64     // 领取时分配的消息 ID，重新投递时沿用，消费者据此去重
+	ClaimedAt    *time.Time // 领取时间，超时未完成的会被重新领取
+	SentAt       *time.Time
+	Error        string    `gorm:"type:text"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	IsWithdrawed bool      `gorm:"is_withdrawed"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+// 消息表情回应（MessageReaction）：同一用户对同一条消息可以回应多个不同表情
+type MessageReaction struct {
+	MessageID int64     `gorm:"primaryKey"`
+	Message   Message   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	UserID    int64     `gorm:"primaryKey;index"`
+	Emoji     string    `gorm:"primaryKey;type:varchar(32)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// 消息中 @ 到的用户（MessageMention）
+type MessageMention struct {
+	MessageID int64   `gorm:"primaryKey"`
+	Message   Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	UserID    int64   `gorm:"primaryKey;index"`
+}
+
+// 消息编辑历史（MessageRevision）：每次编辑前保存一份旧内容
+type MessageRevision struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	MessageID int64     `gorm:"not null;index"`
+	Message   Message   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	EditorID  int64     `gorm:"not null"`
+	Content   string    `gorm:"type:text;not null"` // 编辑前的内容
+	EditedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// 仅对某个用户隐藏的消息（删除 "仅自己可见"）
+type HiddenMessage struct {
+	UserID    int64     `gorm:"primaryKey"`
+	MessageID int64     `gorm:"primaryKey;index"`
+	Message   Message   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
 }
 
 // 每条消息针对每个用户的读状态（MessageStatus）
@@ -35,6 +35,12 @@ func autoMigrate() {
 		&model.Conversation{},
 		&model.Message{},
 		&model.MessageStatus{},
+		&model.MessageRevision{},
+		&model.HiddenMessage{},
+		&model.MessageMention{},
+		&model.MessageReaction{},
+		&model.Draft{},
+		&model.ScheduledMessage{},
 	)
 	if err != nil {
 		log.Fatal("数据库迁移失败：", err)
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 定时消息不存在、不属于当前用户，或者已经不是待发送状态
+var ErrScheduledNotPending = errors.New("scheduled message not found or not pending")
+
+// 领取后超过这个时间还没有完成，视为领取的实例已经挂掉，允许重新领取
+const scheduledClaimTimeout = time.Minute
+
+func (r *messageRepo) CreateScheduledMessage(ctx context.Context, sm *model.ScheduledMessage) error {
+	sm.Status = model.ScheduledPending
+	return r.db.WithContext(ctx).Create(sm).Error
+}
+
+// 修改待发送的定时消息，只能改内容、回复对象、@ 和发送时间
+func (r *messageRepo) UpdateScheduledMessage(ctx context.Context, sm *model.ScheduledMessage) error {
+	res := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND sender_id = ? AND status = ?", sm.ID, sm.SenderID, model.ScheduledPending).
+		Select("content", "reply_to_msg_id", "mentions", "mention_all", "send_at").
+		Updates(sm)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrScheduledNotPending
+	}
+	return r.db.WithContext(ctx).First(sm, sm.ID).Error
+}
+
+func (r *messageRepo) CancelScheduledMessage(ctx context.Context, userID, id int64) error {
+	res := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND sender_id = ? AND status = ?", id, userID, model.ScheduledPending).
+		Update("status", model.ScheduledCanceled)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrScheduledNotPending
+	}
+	return nil
+}
+
+func (r *messageRepo) GetScheduledMessage(ctx context.Context, userID, id int64) (*model.ScheduledMessage, error) {
+	var sm model.ScheduledMessage
+	if err := r.db.WithContext(ctx).Where("id = ? AND sender_id = ?", id, userID).First(&sm).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledNotPending
+		}
+		return nil, err
+	}
+	return &sm, nil
+}
+
+// 用户待发送的定时消息，按发送时间升序
+func (r *messageRepo) ListScheduledMessages(ctx context.Context, userID int64) ([]*model.ScheduledMessage, error) {
+	var list []*model.ScheduledMessage
+	if err := r.db.WithContext(ctx).
+		Where("sender_id = ? AND status IN ?", userID,
+			[]int16{model.ScheduledPending, model.ScheduledDispatching}).
+		Order("send_at ASC, id ASC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ClaimDueScheduledMessages 领取到期的定时消息。
+// FOR UPDATE SKIP LOCKED 保证多个实例不会领到同一条；领取时分配消息 ID 并提交，
+// 之后投递到 Kafka 的消息都用这个 ID，领取者中途挂掉后被重新领取也不会产生第二条消息
+func (r *messageRepo) ClaimDueScheduledMessages(ctx context.Context, limit int, newMsgID func() int64) ([]*model.ScheduledMessage, error) {
+	var claimed []*model.ScheduledMessage
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND send_at <= ?) OR (status = ? AND claimed_at < ?)",
+				model.ScheduledPending, now, model.ScheduledDispatching, now.Add(-scheduledClaimTimeout)).
+			Order("send_at ASC").
+			Limit(limit).
+			Find(&claimed).Error; err != nil {
+			return err
+		}
+		for _, sm := range claimed {
+			if sm.MsgID == nil {
+				id := newMsgID()
+				sm.MsgID = &id
+			}
+			sm.Status = model.ScheduledDispatching
+			sm.ClaimedAt = &now
+			if err := tx.Model(sm).
+				Select("msg_id", "status", "claimed_at").
+				Updates(sm).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// 投递完成后记录结果，sendErr 为 nil 表示已经进入 Kafka
+func (r *messageRepo) FinishScheduledMessage(ctx context.Context, id int64, sendErr error) error {
+	fields := map[string]interface{}{"status": model.ScheduledSent, "sent_at": time.Now()}
+	if sendErr != nil {
+		fields = map[string]interface{}{"status": model.ScheduledFailed, "error": sendErr.Error()}
+	}
+	return r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledDispatching).
+		Updates(fields).Error
+}
+
+// 消息是否已经落库，Kafka 重复投递时用来去重
+func (r *messageRepo) MessageExists(ctx context.Context, msgID int64) (bool, error) {
+	var ids []int64
+	if err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("id = ?", msgID).
+		Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
@@ -0,0 +1,277 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"gorm.io/gorm"
+)
+
+// 消息搜索条件，Keyword 之外的条件都是可选的
+type SearchQuery struct {
+	UserID    int64
+	Keyword   string
+	ThreadID  int64
+	SenderID  int64
+	Kind      int16
+	StartTime *time.Time
+	EndTime   *time.Time
+	Cursor    int64 // 上一页最后一条消息的 id，0 表示第一页
+	PageSize  int
+}
+
+// 调用者所在的一个群会话，seq 不大于 Floor 的消息（入群前的历史）对其不可见
+type GroupScope struct {
+	ThreadID int64
+	Floor    int64
+}
+
+// 搜索命中的消息，Text 是参与检索的文本（合并转发消息为快照里的文字），用于生成高亮
+type SearchHit struct {
+	Message *model.Message
+	Text    string
+}
+
+// MessageSearchIndex 消息全文检索。
+// 实现需要自己保证只返回调用者有会话的单聊、groups 里的群会话中
+// 未撤回、未被 "仅自己删除"、不在清空水位线和入群位置之前的消息，结果按消息 id 倒序。
+// 大群成员不一定有会话记录，群会话以 groups 为准。
+// 过滤必须在分页之前完成，否则一页可能为空但还有下一页。
+type MessageSearchIndex interface {
+	// Index 新消息落库或内容变化（编辑、撤回后重新编辑）后调用
+	Index(ctx context.Context, msg *model.Message) error
+	// Remove 消息撤回后调用
+	Remove(ctx context.Context, msgID int64) error
+	Search(ctx context.Context, q *SearchQuery, groups []GroupScope) ([]*SearchHit, bool, error)
+}
+
+// 基于 Postgres 全文检索的实现。
+// CJK 文本没有空格分词，这里在写入前自己切成单字 + 双字，
+// 存到 messages.search_tokens，再用 simple 配置建 GIN 索引。
+type pgSearchIndex struct {
+	db *gorm.DB
+}
+
+func NewPostgresSearchIndex(db *gorm.DB) MessageSearchIndex {
+	return &pgSearchIndex{db: db}
+}
+
+// 创建 GIN 索引，AutoMigrate 不支持表达式索引
+func ensureSearchIndex(db *gorm.DB) error {
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_search_tokens
+		ON messages USING GIN (to_tsvector('simple', COALESCE(search_tokens, '')))`).Error
+}
+
+func (p *pgSearchIndex) Index(ctx context.Context, msg *model.Message) error {
+	return p.db.WithContext(ctx).Model(&model.Message{}).
+		Where("id = ?", msg.MsgID).
+		UpdateColumn("search_tokens", tokenizeDocument(SearchableText(msg))).Error
+}
+
+func (p *pgSearchIndex) Remove(ctx context.Context, msgID int64) error {
+	// 置为空串而不是 NULL，避免被补建索引的任务重新写回
+	return p.db.WithContext(ctx).Model(&model.Message{}).
+		Where("id = ?", msgID).
+		UpdateColumn("search_tokens", "").Error
+}
+
+func (p *pgSearchIndex) Search(ctx context.Context, q *SearchQuery, groups []GroupScope) ([]*SearchHit, bool, error) {
+	tokens := tokenizeQuery(q.Keyword)
+	if tokens == "" {
+		return []*SearchHit{}, false, nil
+	}
+
+	db := p.db.WithContext(ctx)
+	query := db.Table("messages AS m").
+		Select("m.*").
+		Joins("JOIN threads t ON t.id = m.thread_id").
+		Joins("LEFT JOIN conversations c ON c.thread_id = m.thread_id AND c.owner_id = ?", q.UserID).
+		Where("m.seq_id > COALESCE(c.cleared_seq, 0)").
+		Where("m.is_withdrawed = ? AND m.is_expired = ?", false, false).
+		Where("NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = ?)", q.UserID).
+		Where("to_tsvector('simple', COALESCE(m.search_tokens, '')) @@ plainto_tsquery('simple', ?)", tokens)
+
+	if len(groups) > 0 {
+		// 每个群的入群位置作为一张临时表关联进来
+		values := make([]string, 0, len(groups))
+		args := make([]interface{}, 0, 2*len(groups))
+		for _, g := range groups {
+			values = append(values, "(?::bigint, ?::bigint)")
+			args = append(args, g.ThreadID, g.Floor)
+		}
+		query = query.
+			Joins("LEFT JOIN (VALUES "+strings.Join(values, ", ")+") AS f(thread_id, floor_seq) ON f.thread_id = m.thread_id", args...).
+			Where("((t.group_id IS NULL AND c.id IS NOT NULL) OR m.seq_id > f.floor_seq)")
+	} else {
+		query = query.Where("t.group_id IS NULL AND c.id IS NOT NULL")
+	}
+	if q.ThreadID > 0 {
+		query = query.Where("m.thread_id = ?", q.ThreadID)
+	}
+	if q.SenderID > 0 {
+		query = query.Where("m.sender_id = ?", q.SenderID)
+	}
+	if q.Kind > 0 {
+		query = query.Where("m.kind = ?", q.Kind)
+	}
+	if q.StartTime != nil {
+		query = query.Where("m.created_at >= ?", *q.StartTime)
+	}
+	if q.EndTime != nil {
+		query = query.Where("m.created_at < ?", *q.EndTime)
+	}
+	if q.Cursor > 0 {
+		query = query.Where("m.id < ?", q.Cursor)
+	}
+
+	messages := make([]*model.Message, 0, q.PageSize+1)
+	if err := query.Order("m.id DESC").Limit(q.PageSize + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+	hasMore := len(messages) > q.PageSize
+	if hasMore {
+		messages = messages[:q.PageSize]
+	}
+
+	hits := make([]*SearchHit, 0, len(messages))
+	for _, m := range messages {
+		hits = append(hits, &SearchHit{Message: m, Text: SearchableText(m)})
+	}
+	return hits, hasMore, nil
+}
+
+// ReindexMissing 给还没有 search_tokens 的历史消息补建索引，启动时在后台执行
+func ReindexMissing(ctx context.Context, db *gorm.DB, index MessageSearchIndex) {
+	if err := ensureSearchIndex(db.WithContext(ctx)); err != nil {
+		log.Println("创建消息搜索索引失败：", err)
+	}
+	const batchSize = 500
+	var lastID int64
+	for {
+		var messages []*model.Message
+		if err := db.WithContext(ctx).
+			Where("search_tokens IS NULL AND is_withdrawed = ? AND is_expired = ? AND id > ?", false, false, lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&messages).Error; err != nil {
+			log.Println("补建消息搜索索引失败：", err)
+			return
+		}
+		for _, m := range messages {
+			if err := index.Index(ctx, m); err != nil {
+				log.Println("补建消息搜索索引失败：", err)
+				return
+			}
+			lastID = m.MsgID
+		}
+		if len(messages) < batchSize {
+			return
+		}
+	}
+}
+
+// SearchableText 参与检索的文本：文本消息是原文，合并转发是标题和每条记录的文字，其余类型不参与检索
+func SearchableText(msg *model.Message) string {
+	switch msg.Kind {
+	case model.KindText:
+		return msg.Content
+	case model.KindMergedForward:
+		var record struct {
+			Title string `json:"title"`
+			Items []struct {
+				Kind    int16  `json:"kind"`
+				Content string `json:"content"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(msg.Content), &record); err != nil {
+			return ""
+		}
+		parts := []string{record.Title}
+		for _, item := range record.Items {
+			if item.Kind == model.KindText {
+				parts = append(parts, item.Content)
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
+
+// 中日韩文字没有空格分隔，需要按字切分
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// 把文本切成词：连续的字母数字为一个词（小写），连续的 CJK 字符为一段
+func splitRuns(text string) (words []string, cjkRuns [][]rune) {
+	var word []rune
+	var cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		if len(cjk) > 0 {
+			cjkRuns = append(cjkRuns, cjk)
+			cjk = nil
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				cjkRuns = append(cjkRuns, cjk)
+				cjk = nil
+			}
+			word = append(word, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words, cjkRuns
+}
+
+// 文档分词：CJK 同时写入单字和相邻双字，既能搜单个字也能按词组精确匹配
+func tokenizeDocument(text string) string {
+	words, runs := splitRuns(text)
+	tokens := words
+	for _, run := range runs {
+		for i := range run {
+			tokens = append(tokens, string(run[i]))
+			if i+1 < len(run) {
+				tokens = append(tokens, string(run[i:i+2]))
+			}
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// 查询分词：CJK 只用双字（单个字时用单字），所有词之间是 AND 关系
+func tokenizeQuery(text string) string {
+	words, runs := splitRuns(text)
+	tokens := words
+	for _, run := range runs {
+		if len(run) == 1 {
+			tokens = append(tokens, string(run))
+			continue
+		}
+		for i := 0; i+1 < len(run); i++ {
+			tokens = append(tokens, string(run[i:i+2]))
+		}
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,49 @@
+package repo
+
+import "testing"
+
+func TestTokenizeDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"latin words are lowercased", "Hello, World 42", "hello world 42"},
+		{"single cjk rune", "好", "好"},
+		{"cjk unigrams and bigrams", "明天见", "明 明天 天 天见 见"},
+		{"mixed text keeps words first", "周末go开会", "go 周 周末 末 开 开会 会"},
+		{"punctuation splits cjk runs", "你好，世界", "你 你好 好 世 世界 界"},
+		{"kana and hangul form one run", "カナ한글", "カ カナ ナ ナ한 한 한글 글"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeDocument(tt.text); got != tt.want {
+				t.Errorf("tokenizeDocument(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"only punctuation", "，。!?", ""},
+		{"latin words", "Release Notes", "release notes"},
+		{"single cjk rune", "好", "好"},
+		{"cjk bigrams only", "明天见", "明天 天见"},
+		{"separate cjk runs", "你好 世界", "你好 世界"},
+		{"mixed text", "周末go开会", "go 周末 开会"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeQuery(tt.text); got != tt.want {
+				t.Errorf("tokenizeQuery(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -9,12 +9,37 @@ import (
 func SetMessageRouter(r *gin.Engine, m *handler.MessageHandler) {
 	r.POST("/message/send", m.SendMessageToSingle)
 	r.POST("/message/group/send", m.SendMessageToGroup)
+	r.POST("/message/forward", m.ForwardMessages)
+	r.POST("/message/typing", m.SendTyping)
+	r.POST("/message/schedule", m.ScheduleMessage)
+	r.PUT("/message/schedule", m.UpdateScheduledMessage)
+	r.DELETE("/message/schedule", m.CancelScheduledMessage)
+	r.GET("/message/schedules", m.ListScheduledMessages)
 	r.GET("/conversation/get", m.GetConversationMessagesSingle)
 	r.GET("/conversation/group/get", m.GetConversationMessagesGroup)
+	r.GET("/conversation/around", m.GetMessageWindow)
+	r.GET("/message/group/replies", m.GetGroupReplies)
 	r.PUT("/message/withdraw", m.WithdrawMessageSingle)
 	r.PUT("/message/group/withdraw", m.WithdrawMessageGroup)
+	r.PUT("/message/group/recall_window", m.SetGroupRecallWindow)
 	r.PUT("/message/unwithdraw", m.UnWithdrawMessageSingle)
 	r.PUT("/message/group/unwithdraw", m.UnWithdrawMessageGroup)
+	r.PUT("/message/edit", m.EditMessageSingle)
+	r.PUT("/message/group/edit", m.EditMessageGroup)
+	r.GET("/message/revisions", m.GetMessageRevisions)
+	r.GET("/message/search", m.SearchMessages)
+	r.DELETE("/message/delete", m.DeleteMessagesForMe)
+	r.PUT("/message/reaction", m.AddReaction)
+	r.DELETE("/message/reaction", m.RemoveReaction)
 	r.PUT("/conversation/unread", m.UpdateUnread)
+	r.PUT("/conversation/clear", m.ClearHistory)
+	r.DELETE("/conversation", m.DeleteConversation)
+	r.PUT("/conversation/pin", m.PinConversation)
+	r.PUT("/conversation/mute", m.MuteConversation)
+	r.PUT("/conversation/mark_unread", m.MarkConversationUnread)
+	r.PUT("/conversation/draft", m.SaveDraft)
+	r.DELETE("/conversation/draft", m.DeleteDraft)
+	r.PUT("/conversation/disappearing", m.SetDisappearingTimer)
 	r.GET("/conversations", m.GetConversations)
+	r.GET("/conversations/unread", m.GetUnreadSummary)
 }
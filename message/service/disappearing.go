@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/repo"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	// 阅后即焚时间上限，会话设置和单条消息的 TTL 共用
+	maxDisappearSeconds = int64(7 * 24 * 60 * 60)
+	// 清理过期消息的间隔和每批处理的条数
+	reaperInterval  = 5 * time.Second
+	reaperBatchSize = 200
+)
+
+// SetDisappearingTimer 设置会话的阅后即焚计时，seconds 为 0 表示关闭，只影响之后发送的消息
+func (s *MessageService) SetDisappearingTimer(ctx context.Context, userID, threadID, seconds int64) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	if seconds < 0 || seconds > maxDisappearSeconds {
+		return errors.New("invalid seconds")
+	}
+	thread, err := s.repo.SetDisappearTimer(ctx, userID, threadID, seconds)
+	if err != nil {
+		s.logger.Error("set disappear timer error", zap.Error(err))
+		return fmt.Errorf("set disappear timer failed: %w", err)
+	}
+
+	event := &PushEvent{
+		Event: "disappearing_updated",
+		Data: map[string]interface{}{
+			"thread_id":  thread.ID,
+			"group_id":   thread.GroupID,
+			"seconds":    seconds,
+			"updated_by": userID,
+		},
+	}
+	s.publishToUsers(ctx, s.threadParticipants(ctx, thread), event)
+	return nil
+}
+
+// RunReaper 定时清理到期的消息，ctx 取消后退出。
+// 每个实例都可以运行，行锁保证同一条消息只会被处理一次
+func (s *MessageService) RunReaper(ctx context.Context) {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reapExpired(ctx)
+		}
+	}
+}
+
+func (s *MessageService) reapExpired(ctx context.Context) {
+	for {
+		expired, err := s.repo.ExpireMessages(ctx, reaperBatchSize)
+		if err != nil {
+			s.logger.Error("expire messages error", zap.Error(err))
+			return
+		}
+		count := 0
+		for _, et := range expired {
+			count += len(et.MsgIDs)
+			s.evictExpired(ctx, et)
+		}
+		if count < reaperBatchSize {
+			return
+		}
+	}
+}
+
+// 把过期消息从 Redis 缓存里清掉，并通知会话成员删除本地副本
+func (s *MessageService) evictExpired(ctx context.Context, et *repo.ExpiredThread) {
+	thread, msgIDs := &et.Thread, et.MsgIDs
+	participants := s.threadParticipants(ctx, thread)
+
+	s.evictCachedMessages(ctx, participants, msgIDs)
+
+	if thread.GroupID != nil {
+		s.invalidateGroupPageCache(ctx, *thread.GroupID)
+	} else if thread.PeerA != nil && thread.PeerB != nil {
+		s.invalidateSinglePageCache(ctx, *thread.PeerA, *thread.PeerB)
+	}
+
+	s.publishToUsers(ctx, participants, &PushEvent{
+		Event: "messages_expired",
+		Data: map[string]interface{}{
+			"thread_id":       thread.ID,
+			"group_id":        thread.GroupID,
+			"msg_ids":         msgIDs,
+			"last_message_id": et.LastMessageID,
+		},
+	})
+}
+
+// 从 user:{id}:msg_cache 里删掉指定的消息。
+// 分数是 float64 的雪花 ID，相邻的 ID 可能落在同一个分数上，
+// 所以先按分数取出候选，解出消息 ID 完全相同的再按成员删除
+func (s *MessageService) evictCachedMessages(ctx context.Context, userIDs, msgIDs []int64) {
+	expired := make(map[int64]bool, len(msgIDs))
+	for _, id := range msgIDs {
+		expired[id] = true
+	}
+	type lookup struct {
+		key string
+		cmd *redis.StringSliceCmd
+	}
+	var lookups []lookup
+	pipe := s.rdb.Pipeline()
+	for _, uid := range userIDs {
+		key := fmt.Sprintf("user:%d:msg_cache", uid)
+		for _, id := range msgIDs {
+			score := strconv.FormatFloat(float64(id), 'f', -1, 64)
+			lookups = append(lookups, lookup{key, pipe.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: score, Max: score})})
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		s.logger.Warn("failed to read cached messages", zap.Error(err))
+		return
+	}
+
+	pipe = s.rdb.Pipeline()
+	removed := 0
+	for _, l := range lookups {
+		for _, member := range l.cmd.Val() {
+			var cached AsyncMessage
+			if err := json.Unmarshal([]byte(member), &cached); err != nil || !expired[cached.MsgID] {
+				continue
+			}
+			pipe.ZRem(ctx, l.key, member)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("failed to evict expired messages from cache", zap.Error(err))
+	}
+}
+
+// 会话的参与者：单聊是双方，群聊是当前群成员
+func (s *MessageService) threadParticipants(ctx context.Context, thread *model.Thread) []int64 {
+	if thread.GroupID != nil {
+		memberIDs, err := s.repo.GetGroupMemberIDs(ctx, *thread.GroupID)
+		if err != nil {
+			s.logger.Warn("failed to list group members", zap.Error(err))
+		}
+		return memberIDs
+	}
+	var ids []int64
+	if thread.PeerA != nil {
+		ids = append(ids, *thread.PeerA)
+	}
+	if thread.PeerB != nil {
+		ids = append(ids, *thread.PeerB)
+	}
+	return ids
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// 有草稿的会话记在 Redis set linkim:drafts:{userID}，消息发出后只有命中的会话才去删 Postgres 里的草稿。
+// 成员 "_" 是哨兵，表示 set 已经从 Postgres 完整加载过。
+// 保存草稿时先写 set 再写库，set 里多出来的会话只会多删一次，不会漏删。
+const (
+	maxDraftLen       = 2000 // 草稿最多保存的字符数
+	draftLoadedMember = "_"
+	draftThreadsTTL   = 7 * 24 * time.Hour
+)
+
+func draftThreadsKey(userID int64) string {
+	return fmt.Sprintf("linkim:drafts:%d", userID)
+}
+
+// 记下用户在这个会话有草稿
+func markDraft(ctx context.Context, rdb *redis.Client, userID, threadID int64) error {
+	key := draftThreadsKey(userID)
+	pipe := rdb.TxPipeline()
+	pipe.SAdd(ctx, key, strconv.FormatInt(threadID, 10))
+	pipe.Expire(ctx, key, draftThreadsTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func unmarkDraft(ctx context.Context, rdb *redis.Client, userID, threadID int64) error {
+	return rdb.SRem(ctx, draftThreadsKey(userID), strconv.FormatInt(threadID, 10)).Err()
+}
+
+// 草稿变化时推送给自己的其他设备，Draft 为 nil 表示草稿已清空；
+// Platform 是发起修改的设备，客户端据此忽略自己发出的同步
+type DraftUpdatedEvent struct {
+	ThreadID int64         `json:"thread_id"`
+	Draft    *dto.DraftDTO `json:"draft"`
+	Platform int           `json:"platform"`
+}
+
+// SaveDraft 保存草稿并同步到其他在线设备，内容为空且没有回复对象时视为删除
+func (s *MessageService) SaveDraft(ctx context.Context, userID, threadID int64, content string,
+	replyToMsgID *int64, platform int) (*dto.DraftDTO, error) {
+	if userID <= 0 || threadID <= 0 {
+		return nil, errors.New("invalid userID or threadID")
+	}
+	if len([]rune(content)) > maxDraftLen {
+		return nil, errors.New("draft is too long")
+	}
+	if content == "" && replyToMsgID == nil {
+		return nil, s.DeleteDraft(ctx, userID, threadID, platform)
+	}
+
+	if err := markDraft(ctx, s.rdb, userID, threadID); err != nil {
+		// 标记失败时删掉整个 set，下次发消息从 Postgres 重新加载
+		s.logger.Warn("failed to mark draft", zap.Error(err))
+		_ = s.rdb.Del(ctx, draftThreadsKey(userID)).Err()
+	}
+	draft, err := s.repo.SaveDraft(ctx, userID, threadID, content, replyToMsgID)
+	if err != nil {
+		s.logger.Error("save draft error", zap.Error(err))
+		return nil, fmt.Errorf("save draft failed: %w", err)
+	}
+	d := toDraftDTO(draft)
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "draft_updated",
+		Data:  &DraftUpdatedEvent{ThreadID: threadID, Draft: d, Platform: platform},
+	})
+	return d, nil
+}
+
+// DeleteDraft 删除草稿并同步到其他在线设备
+func (s *MessageService) DeleteDraft(ctx context.Context, userID, threadID int64, platform int) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	if _, err := s.repo.DeleteDraft(ctx, userID, threadID); err != nil {
+		s.logger.Error("delete draft error", zap.Error(err))
+		return fmt.Errorf("delete draft failed: %w", err)
+	}
+	if err := unmarkDraft(ctx, s.rdb, userID, threadID); err != nil {
+		s.logger.Warn("failed to unmark draft", zap.Error(err))
+	}
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "draft_updated",
+		Data:  &DraftUpdatedEvent{ThreadID: threadID, Platform: platform},
+	})
+	return nil
+}
+
+// 用户在这个会话是否可能有草稿；set 没加载过时从 Postgres 加载，出错时按有草稿处理
+func (h *ConsumerHandler) mayHaveDraft(ctx context.Context, userID, threadID int64) bool {
+	key := draftThreadsKey(userID)
+	member := strconv.FormatInt(threadID, 10)
+	pipe := h.rdb.Pipeline()
+	loaded := pipe.SIsMember(ctx, key, draftLoadedMember)
+	has := pipe.SIsMember(ctx, key, member)
+	if _, err := pipe.Exec(ctx); err == nil && loaded.Val() {
+		return has.Val()
+	}
+
+	threadIDs, err := h.repo.GetDraftThreadIDs(ctx, userID)
+	if err != nil {
+		h.logger.Warn("failed to load draft threads", zap.Error(err))
+		return true
+	}
+	// 只追加不覆盖，加载期间新记下的会话不会丢
+	members := []interface{}{draftLoadedMember}
+	found := false
+	for _, id := range threadIDs {
+		members = append(members, strconv.FormatInt(id, 10))
+		found = found || id == threadID
+	}
+	tx := h.rdb.TxPipeline()
+	tx.SAdd(ctx, key, members...)
+	tx.Expire(ctx, key, draftThreadsTTL)
+	if _, err := tx.Exec(ctx); err != nil {
+		h.logger.Warn("failed to cache draft threads", zap.Error(err))
+	}
+	return found
+}
+
+// 消息发出后清掉草稿，只有真的删除了草稿才通知其他设备
+func (h *ConsumerHandler) clearDraft(ctx context.Context, userID, threadID int64) {
+	if !h.mayHaveDraft(ctx, userID, threadID) {
+		return
+	}
+	// 先去掉标记再删库，删库之后才保存的草稿会重新打上标记
+	if err := unmarkDraft(ctx, h.rdb, userID, threadID); err != nil {
+		h.logger.Warn("failed to unmark draft", zap.Error(err))
+	}
+	deleted, err := h.repo.DeleteDraft(ctx, userID, threadID)
+	if err != nil {
+		h.logger.Warn("failed to clear draft", zap.Error(err))
+		// 草稿还在，把标记加回去，下一条消息再删
+		_ = markDraft(ctx, h.rdb, userID, threadID)
+		return
+	}
+	if !deleted {
+		return
+	}
+	payload, _ := json.Marshal(&PushEvent{
+		Event: "draft_updated",
+		Data:  &DraftUpdatedEvent{ThreadID: threadID},
+	})
+	if err := h.rdb.Publish(ctx, fmt.Sprintf("user:%d:messages", userID), payload).Err(); err != nil {
+		h.logger.Warn("failed to publish draft cleared", zap.Error(err))
+	}
+}
+
+func toDraftDTO(d *model.Draft) *dto.DraftDTO {
+	if d == nil {
+		return nil
+	}
+	return &dto.DraftDTO{
+		ThreadID:     d.ThreadID,
+		Content:      d.Content,
+		ReplyToMsgID: d.ReplyToMsgID,
+		UpdatedAt:    d.UpdatedAt,
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxForwardMessages = 100 // 一次最多转发的消息数
+	maxForwardTargets  = 9   // 一次最多转发到的会话数
+	defaultMergedTitle = "聊天记录"
+)
+
+// ForwardTarget 转发目标，TargetID 和 GroupID 二选一
+type ForwardTarget struct {
+	TargetID int64      `json:"target_id"`
+	GroupID  *uuid.UUID `json:"group_id"`
+}
+
+// ForwardMessages 把调用者可见的消息转发到其他单聊或群聊。
+// merged 为 false 时逐条转发，为 true 时打包成一条合并转发消息。
+// 转发复用正常的发送流程，seq 分配、落库和推送都和普通消息一致。
+func (s *MessageService) ForwardMessages(ctx context.Context, userID int64, messageIDs []int64,
+	targets []ForwardTarget, merged bool, title string) ([]*dto.ForwardResultDTO, error) {
+	if userID <= 0 || len(messageIDs) == 0 || len(targets) == 0 {
+		return nil, errors.New("invalid userID, messageIDs or targets")
+	}
+	if len(messageIDs) > maxForwardMessages || len(targets) > maxForwardTargets {
+		return nil, errors.New("too many messages or targets")
+	}
+
+	messages, err := s.repo.GetForwardableMessages(ctx, userID, messageIDs)
+	if err != nil {
+		s.logger.Error("get forwardable messages error", zap.Error(err))
+		return nil, err
+	}
+
+	var payloads []*SendOptions
+	var contents []string
+	if merged {
+		content, err := s.buildMergedForward(ctx, messages, title)
+		if err != nil {
+			return nil, err
+		}
+		payloads = []*SendOptions{{Kind: model.KindMergedForward}}
+		contents = []string{content}
+	} else {
+		for _, m := range messages {
+			from := m.MsgID
+			payloads = append(payloads, &SendOptions{Kind: m.Kind, ForwardedFrom: &from})
+			contents = append(contents, m.Content)
+		}
+	}
+
+	// 单个目标失败不影响其他目标
+	results := make([]*dto.ForwardResultDTO, 0, len(targets))
+	for _, t := range targets {
+		result := &dto.ForwardResultDTO{TargetID: t.TargetID, GroupID: t.GroupID, MsgIDs: []int64{}}
+		for i, opts := range payloads {
+			var msgID *int64
+			if t.GroupID != nil {
+				msgID, err = s.SendMessageToGroup(ctx, userID, *t.GroupID, contents[i], opts)
+			} else {
+				msgID, err = s.SendMessageToSingle(ctx, userID, t.TargetID, contents[i], opts)
+			}
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.MsgIDs = append(result.MsgIDs, *msgID)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 生成合并转发的快照，发送者信息取转发时的昵称和头像
+func (s *MessageService) buildMergedForward(ctx context.Context, messages []*model.Message, title string) (string, error) {
+	senderSet := make(map[int64]struct{})
+	for _, m := range messages {
+		senderSet[m.SenderID] = struct{}{}
+	}
+	senderIDs := make([]int64, 0, len(senderSet))
+	for id := range senderSet {
+		senderIDs = append(senderIDs, id)
+	}
+	users, err := s.repo.GetUserInfos(ctx, senderIDs)
+	if err != nil {
+		s.logger.Error("get user infos for merged forward error", zap.Error(err))
+		return "", err
+	}
+
+	if title = strings.TrimSpace(title); title == "" {
+		title = defaultMergedTitle
+	}
+	record := &dto.MergedForwardDTO{
+		Title: title,
+		Items: make([]*dto.MergedForwardItemDTO, 0, len(messages)),
+	}
+	for _, m := range messages {
+		u := users[m.SenderID]
+		record.Items = append(record.Items, &dto.MergedForwardItemDTO{
+			MsgID:          m.MsgID,
+			SenderID:       m.SenderID,
+			SenderNickname: u.Nickname,
+			SenderAvatar:   u.Avatar,
+			Kind:           m.Kind,
+			Content:        m.Content,
+			CreatedAt:      m.CreatedAt,
+		})
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
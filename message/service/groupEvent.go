@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	grouppb "github.com/AdventureDe/LinkIM/api/group"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// 群服务发布群事件的 topic
+const GroupEventTopic = "im_group_event_topic"
+
+// 同一个群事件只写一条系统消息，Kafka 重复投递时靠这个 key 去重
+const groupEventDedupTTL = 24 * time.Hour
+
+// 处理失败时的重试间隔，从 groupEventRetryMin 开始每次翻倍
+const (
+	groupEventRetryMin = 100 * time.Millisecond
+	groupEventRetryMax = 30 * time.Second
+)
+
+// 格式不对的事件重试也不会成功，记日志后跳过
+var errMalformedGroupEvent = errors.New("malformed group event")
+
+// 系统消息的内容，序列化后存到 Message.Content，客户端按 Event 和各字段本地化渲染提示文案
+type SystemNotice struct {
+	Event      string  `json:"event"`
+	OperatorID int64   `json:"operator_id"`
+	TargetIDs  []int64 `json:"target_ids,omitempty"`
+	Until      int64   `json:"until,omitempty"` // 禁言到期时间（unix 秒）
+	GroupName  string  `json:"group_name,omitempty"`
+	Notice     string  `json:"notice,omitempty"`
+
+	AnnouncementID int64 `json:"announcement_id,omitempty"` // 客户端点击系统消息时打开这条公告
+}
+
+func groupEventName(t grouppb.GroupEventType) string {
+	switch t {
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_MUTED:
+		return "group_muted"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_UNMUTED:
+		return "group_unmuted"
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_MUTED:
+		return "member_muted"
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_UNMUTED:
+		return "member_unmuted"
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_LEFT:
+		return "member_left"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_DISSOLVED:
+		return "group_dissolved"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_ARCHIVED:
+		return "group_archived"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_UNARCHIVED:
+		return "group_unarchived"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_CREATED:
+		return "group_created"
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED:
+		return "member_joined"
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_KICKED:
+		return "member_kicked"
+	case grouppb.GroupEventType_GROUP_EVENT_ADMIN_PROMOTED:
+		return "admin_promoted"
+	case grouppb.GroupEventType_GROUP_EVENT_ADMIN_DEMOTED:
+		return "admin_demoted"
+	case grouppb.GroupEventType_GROUP_EVENT_OWNER_TRANSFERRED:
+		return "owner_transferred"
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_RENAMED:
+		return "group_renamed"
+	case grouppb.GroupEventType_GROUP_EVENT_NOTICE_UPDATED:
+		return "notice_updated"
+	default:
+		return ""
+	}
+}
+
+// SendSystemMessage 在群里写一条系统消息，不做成员和禁言校验
+func (s *MessageService) SendSystemMessage(ctx context.Context, groupID uuid.UUID, notice *SystemNotice) (*int64, error) {
+	content, err := json.Marshal(notice)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueueGroupMessage(ctx, notice.OperatorID, groupID, string(content),
+		&SendOptions{Kind: model.KindSystem})
+}
+
+// GroupEventHandler 消费群事件并写成系统消息
+type GroupEventHandler struct {
+	service *MessageService
+	logger  *zap.Logger
+}
+
+func NewGroupEventHandler(s *MessageService, logger *zap.Logger) *GroupEventHandler {
+	return &GroupEventHandler{
+		service: s,
+		logger:  logger,
+	}
+}
+
+func (h *GroupEventHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *GroupEventHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *GroupEventHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		var event grouppb.GroupEvent
+		if err := proto.Unmarshal(msg.Value, &event); err != nil {
+			h.logger.Error("failed to unmarshal group event", zap.Error(err))
+			session.MarkMessage(msg, "")
+			continue
+		}
+		// 失败的事件不能跳过：标记后面的消息会把它的 offset 一起提交
+		if !h.handleWithRetry(session.Context(), &event) {
+			return nil
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+// 失败时按退避重试，直到成功或者会话结束；会话结束时返回 false，事件在重新分配后再次投递
+func (h *GroupEventHandler) handleWithRetry(ctx context.Context, event *grouppb.GroupEvent) bool {
+	backoff := groupEventRetryMin
+	for {
+		err := h.handle(ctx, event)
+		if err == nil {
+			return true
+		}
+		h.logger.Error("failed to handle group event",
+			zap.String("eventID", event.EventId),
+			zap.String("groupID", event.GroupId),
+			zap.Error(err),
+		)
+		if errors.Is(err, errMalformedGroupEvent) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > groupEventRetryMax {
+			backoff = groupEventRetryMax
+		}
+	}
+}
+
+func (h *GroupEventHandler) handle(ctx context.Context, event *grouppb.GroupEvent) error {
+	name := groupEventName(event.Type)
+	if name == "" {
+		return fmt.Errorf("%w: unknown type %v", errMalformedGroupEvent, event.Type)
+	}
+	groupID, err := uuid.Parse(event.GroupId)
+	if err != nil {
+		return fmt.Errorf("%w: invalid group id %q", errMalformedGroupEvent, event.GroupId)
+	}
+
+	dedupKey := fmt.Sprintf("linkim:group_event:%s", event.EventId)
+	first, err := h.service.rdb.SetNX(ctx, dedupKey, 1, groupEventDedupTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !first {
+		return nil
+	}
+
+	// 退群和解散要先更新会话，再写系统消息
+	if err := h.service.applyGroupEvent(ctx, groupID, event); err != nil {
+		_ = h.service.rdb.Del(ctx, dedupKey).Err()
+		return err
+	}
+	_, err = h.service.SendSystemMessage(ctx, groupID, &SystemNotice{
+		Event:      name,
+		OperatorID: event.OperatorId,
+		TargetIDs:  event.TargetIds,
+		Until:      event.Until,
+		GroupName:  event.GroupName,
+		Notice:     event.Notice,
+
+		AnnouncementID: event.AnnouncementId,
+	})
+	if err != nil {
+		_ = h.service.rdb.Del(ctx, dedupKey).Err()
+	}
+	return err
+}
+
+// 成员退群或被移出时移除他的群会话；群解散时把群会话标记为已解散，之后只能查看；
+// 大群发消息不会给新成员创建会话，入群时在这里创建
+func (s *MessageService) applyGroupEvent(ctx context.Context, groupID uuid.UUID, event *grouppb.GroupEvent) error {
+	switch event.Type {
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_LEFT, grouppb.GroupEventType_GROUP_EVENT_MEMBER_KICKED,
+		grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED, grouppb.GroupEventType_GROUP_EVENT_GROUP_DISSOLVED:
+		if err := dropGroupMemberIDs(ctx, s.rdb, groupID); err != nil {
+			return err
+		}
+	}
+
+	var threadID int64
+	var err error
+	switch event.Type {
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_LEFT, grouppb.GroupEventType_GROUP_EVENT_MEMBER_KICKED:
+		threadID, err = s.repo.RemoveGroupConversations(ctx, groupID, event.TargetIds)
+	case grouppb.GroupEventType_GROUP_EVENT_MEMBER_JOINED:
+		return s.repo.EnsureGroupConversations(ctx, groupID, event.TargetIds)
+	case grouppb.GroupEventType_GROUP_EVENT_GROUP_DISSOLVED:
+		floors := make(map[int64]int64, len(event.FormerMembers))
+		for _, m := range event.FormerMembers {
+			if m.FullHistory {
+				floors[m.UserId] = 0
+			} else {
+				floors[m.UserId] = m.JoinSeq
+			}
+		}
+		threadID, err = s.repo.TombstoneGroupThread(ctx, groupID, floors)
+	default:
+		return nil
+	}
+	if err != nil || threadID == 0 {
+		return err
+	}
+	for _, uid := range event.TargetIds {
+		s.resetUnread(ctx, uid, threadID)
+	}
+	return nil
+}
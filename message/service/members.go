@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/repo"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// 群成员 ID 缓存在 Redis set linkim:group_members:{groupID}，给推送、输入状态这类扇出使用，
+// 不用每次都通过 gRPC 拉完整的成员列表。成员变化的群事件到达时删除，过期时间兜底
+const groupMembersCacheTTL = time.Minute
+
+func groupMembersKey(groupID uuid.UUID) string {
+	return fmt.Sprintf("linkim:group_members:%s", groupID)
+}
+
+func cachedGroupMemberIDs(ctx context.Context, rdb *redis.Client, r repo.MessageRepo, groupID uuid.UUID) ([]int64, error) {
+	key := groupMembersKey(groupID)
+	if members, err := rdb.SMembers(ctx, key).Result(); err == nil && len(members) > 0 {
+		ids := make([]int64, 0, len(members))
+		for _, m := range members {
+			if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	ids, err := r.GetGroupMemberIDs(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		members := make([]interface{}, len(ids))
+		for i, id := range ids {
+			members[i] = strconv.FormatInt(id, 10)
+		}
+		pipe := rdb.TxPipeline()
+		pipe.Del(ctx, key)
+		pipe.SAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, groupMembersCacheTTL)
+		_, _ = pipe.Exec(ctx)
+	}
+	return ids, nil
+}
+
+// 成员变化后删掉缓存的成员集合
+func dropGroupMemberIDs(ctx context.Context, rdb *redis.Client, groupID uuid.UUID) error {
+	return rdb.Del(ctx, groupMembersKey(groupID)).Err()
+}
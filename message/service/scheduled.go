@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 最多提前多久预约
+	maxScheduleAhead = 365 * 24 * time.Hour
+	// worker 轮询间隔和每次领取的条数
+	scheduledPollInterval = 5 * time.Second
+	scheduledBatchSize    = 100
+)
+
+// ScheduleMessage 预约发送消息，targetID 和 groupID 二选一；opts 里的 Mentions / MentionAll 只对群聊生效
+func (s *MessageService) ScheduleMessage(ctx context.Context, senderID, targetID int64, groupID *uuid.UUID,
+	text string, sendAt time.Time, opts *SendOptions) (*dto.ScheduledMessageDTO, error) {
+	if senderID <= 0 || (groupID == nil && (targetID <= 0 || targetID == senderID)) ||
+		(groupID != nil && (*groupID == uuid.Nil || targetID != 0)) {
+		return nil, errors.New("invalid senderID, targetID or groupID")
+	}
+	if opts == nil {
+		opts = &SendOptions{}
+	}
+	if err := s.checkSchedule(ctx, senderID, targetID, groupID, text, sendAt, opts); err != nil {
+		return nil, err
+	}
+
+	sm := &model.ScheduledMessage{
+		SenderID:     senderID,
+		TargetID:     targetID,
+		GroupID:      groupID,
+		Content:      text,
+		ReplyToMsgID: opts.ReplyToMsgID,
+		SendAt:       sendAt,
+	}
+	if groupID != nil {
+		sm.Mentions = opts.Mentions
+		sm.MentionAll = opts.MentionAll
+	}
+	if err := s.repo.CreateScheduledMessage(ctx, sm); err != nil {
+		s.logger.Error("create scheduled message error", zap.Error(err))
+		return nil, fmt.Errorf("create scheduled message failed: %w", err)
+	}
+	return toScheduledMessageDTO(sm), nil
+}
+
+// UpdateScheduledMessage 修改还没发出的定时消息
+func (s *MessageService) UpdateScheduledMessage(ctx context.Context, senderID, id int64, text string,
+	sendAt time.Time, opts *SendOptions) (*dto.ScheduledMessageDTO, error) {
+	if senderID <= 0 || id <= 0 {
+		return nil, errors.New("invalid senderID or id")
+	}
+	if opts == nil {
+		opts = &SendOptions{}
+	}
+	current, err := s.repo.GetScheduledMessage(ctx, senderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != model.ScheduledPending {
+		return nil, repo.ErrScheduledNotPending
+	}
+	if err := s.checkSchedule(ctx, senderID, current.TargetID, current.GroupID, text, sendAt, opts); err != nil {
+		return nil, err
+	}
+
+	current.Content = text
+	current.ReplyToMsgID = opts.ReplyToMsgID
+	current.SendAt = sendAt
+	if current.GroupID != nil {
+		current.Mentions = opts.Mentions
+		current.MentionAll = opts.MentionAll
+	}
+	if err := s.repo.UpdateScheduledMessage(ctx, current); err != nil {
+		s.logger.Error("update scheduled message error", zap.Error(err))
+		return nil, fmt.Errorf("update scheduled message failed: %w", err)
+	}
+	return toScheduledMessageDTO(current), nil
+}
+
+// CancelScheduledMessage 取消还没发出的定时消息
+func (s *MessageService) CancelScheduledMessage(ctx context.Context, senderID, id int64) error {
+	if senderID <= 0 || id <= 0 {
+		return errors.New("invalid senderID or id")
+	}
+	if err := s.repo.CancelScheduledMessage(ctx, senderID, id); err != nil {
+		s.logger.Error("cancel scheduled message error", zap.Error(err))
+		return fmt.Errorf("cancel scheduled message failed: %w", err)
+	}
+	return nil
+}
+
+// ListScheduledMessages 列出还没发出的定时消息
+func (s *MessageService) ListScheduledMessages(ctx context.Context, senderID int64) ([]*dto.ScheduledMessageDTO, error) {
+	if senderID <= 0 {
+		return nil, errors.New("invalid senderID")
+	}
+	list, err := s.repo.ListScheduledMessages(ctx, senderID)
+	if err != nil {
+		s.logger.Error("list scheduled messages error", zap.Error(err))
+		return nil, fmt.Errorf("list scheduled messages failed: %w", err)
+	}
+	res := make([]*dto.ScheduledMessageDTO, 0, len(list))
+	for _, sm := range list {
+		res = append(res, toScheduledMessageDTO(sm))
+	}
+	return res, nil
+}
+
+// 预约时先做一遍和发送时相同的校验，到期时发送流程还会再校验一次
+func (s *MessageService) checkSchedule(ctx context.Context, senderID, targetID int64, groupID *uuid.UUID,
+	text string, sendAt time.Time, opts *SendOptions) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("message text cannot be empty")
+	}
+	now := time.Now()
+	if !sendAt.After(now) || sendAt.After(now.Add(maxScheduleAhead)) {
+		return errors.New("invalid send time")
+	}
+	if groupID != nil {
+		if err := s.repo.CheckGroupMember(ctx, *groupID, senderID); err != nil {
+			return err
+		}
+	}
+	if opts.ReplyToMsgID != nil {
+		if err := s.repo.CheckReplyTarget(ctx, *opts.ReplyToMsgID, senderID, targetID, groupID); err != nil {
+			return err
+		}
+	}
+	if groupID != nil && opts.MentionAll {
+		allowed, err := s.repo.CanMentionAll(ctx, *groupID, senderID)
+		if err != nil {
+			s.logger.Error("check mention all permission error", zap.Error(err))
+			return err
+		}
+		if !allowed {
+			return errors.New("only group owner or admin can mention all")
+		}
+	}
+	return nil
+}
+
+// RunScheduler 定时领取到期的定时消息并投递，ctx 取消后退出。
+// 每个实例都可以运行，领取时的行锁保证同一条消息只会被一个实例投递
+func (s *MessageService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduledPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatchScheduled(ctx)
+		}
+	}
+}
+
+func (s *MessageService) dispatchScheduled(ctx context.Context) {
+	for {
+		claimed, err := s.repo.ClaimDueScheduledMessages(ctx, scheduledBatchSize, func() int64 {
+			return s.idGen.Generate().Int64()
+		})
+		if err != nil {
+			s.logger.Error("claim scheduled messages error", zap.Error(err))
+			return
+		}
+		for _, sm := range claimed {
+			s.deliverScheduled(ctx, sm)
+		}
+		if len(claimed) < scheduledBatchSize {
+			return
+		}
+	}
+}
+
+// 走和普通发送相同的流程，消息 ID 用领取时分配的
+func (s *MessageService) deliverScheduled(ctx context.Context, sm *model.ScheduledMessage) {
+	opts := &SendOptions{
+		MsgID:        *sm.MsgID,
+		ReplyToMsgID: sm.ReplyToMsgID,
+		Mentions:     sm.Mentions,
+		MentionAll:   sm.MentionAll,
+	}
+	var sendErr error
+	if sm.GroupID != nil {
+		_, sendErr = s.SendMessageToGroup(ctx, sm.SenderID, *sm.GroupID, sm.Content, opts)
+	} else {
+		_, sendErr = s.SendMessageToSingle(ctx, sm.SenderID, sm.TargetID, sm.Content, opts)
+	}
+	if sendErr != nil {
+		s.logger.Warn("scheduled message failed", zap.Int64("id", sm.ID), zap.Error(sendErr))
+	}
+	if err := s.repo.FinishScheduledMessage(ctx, sm.ID, sendErr); err != nil {
+		// 状态没写回去，超时后会被重新领取，沿用同一个消息 ID，消费者会去重
+		s.logger.Error("finish scheduled message error", zap.Int64("id", sm.ID), zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	if sendErr != nil {
+		sm.Status = model.ScheduledFailed
+		sm.Error = sendErr.Error()
+	} else {
+		sm.Status = model.ScheduledSent
+		sm.SentAt = &now
+	}
+	s.publishToUsers(ctx, []int64{sm.SenderID}, &PushEvent{
+		Event: "scheduled_message",
+		Data:  toScheduledMessageDTO(sm),
+	})
+}
+
+func scheduledStatusName(status int16) string {
+	switch status {
+	case model.ScheduledPending:
+		return "pending"
+	case model.ScheduledDispatching:
+		return "dispatching"
+	case model.ScheduledSent:
+		return "sent"
+	case model.ScheduledCanceled:
+		return "canceled"
+	case model.ScheduledFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func toScheduledMessageDTO(sm *model.ScheduledMessage) *dto.ScheduledMessageDTO {
+	mentions := sm.Mentions
+	if mentions == nil {
+		mentions = []int64{}
+	}
+	return &dto.ScheduledMessageDTO{
+		ID:           sm.ID,
+		TargetID:     sm.TargetID,
+		GroupID:      sm.GroupID,
+		Text:         sm.Content,
+		ReplyToMsgID: sm.ReplyToMsgID,
+		Mentions:     mentions,
+		MentionAll:   sm.MentionAll,
+		SendAt:       sm.SendAt,
+		Status:       scheduledStatusName(sm.Status),
+		MsgID:        sm.MsgID,
+		Error:        sm.Error,
+		SentAt:       sm.SentAt,
+		CreatedAt:    sm.CreatedAt,
+	}
+}
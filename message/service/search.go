@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 50
+	maxSearchKeywordLen   = 100
+	snippetRunes          = 80 // 高亮摘要最多保留的字符数
+	snippetLeading        = 20 // 第一个命中位置之前保留的字符数
+)
+
+// SearchMessages 在用户自己的会话里搜索消息，结果按时间倒序，用 next_cursor 翻页
+func (s *MessageService) SearchMessages(ctx context.Context, q *repo.SearchQuery) (*dto.SearchResultDTO, error) {
+	q.Keyword = strings.TrimSpace(q.Keyword)
+	if q.UserID <= 0 {
+		return nil, errors.New("invalid userID")
+	}
+	if q.Keyword == "" || len([]rune(q.Keyword)) > maxSearchKeywordLen {
+		return nil, errors.New("invalid keyword")
+	}
+	if q.StartTime != nil && q.EndTime != nil && !q.StartTime.Before(*q.EndTime) {
+		return nil, errors.New("invalid time range")
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultSearchPageSize
+	} else if q.PageSize > maxSearchPageSize {
+		q.PageSize = maxSearchPageSize
+	}
+
+	results, hasMore, err := s.repo.SearchMessages(ctx, q)
+	if err != nil {
+		s.logger.Error("search messages error", zap.Error(err))
+		return nil, fmt.Errorf("search messages failed: %w", err)
+	}
+
+	keywords := strings.Fields(q.Keyword)
+	items := make([]*dto.SearchHitDTO, 0, len(results))
+	for _, r := range results {
+		hit := &dto.SearchHitDTO{
+			MsgID:          r.Message.MsgID,
+			ThreadID:       r.Message.ThreadID,
+			SenderID:       r.Message.SenderID,
+			SenderNickname: r.Sender.Nickname,
+			SenderAvatar:   r.Sender.Avatar,
+			Kind:           r.Message.Kind,
+			Content:        r.Message.Content,
+			Highlight:      highlight(r.Text, keywords),
+			CreatedAt:      r.Message.CreatedAt,
+		}
+		if t := r.Thread; t != nil {
+			hit.GroupID = t.GroupID
+			if t.GroupID == nil && t.PeerA != nil && t.PeerB != nil {
+				hit.PeerID = *t.PeerA
+				if hit.PeerID == q.UserID {
+					hit.PeerID = *t.PeerB
+				}
+			}
+		}
+		items = append(items, hit)
+	}
+
+	result := &dto.SearchResultDTO{Items: items, HasMore: hasMore}
+	if hasMore && len(items) > 0 {
+		result.NextCursor = items[len(items)-1].MsgID
+	}
+	return result, nil
+}
+
+// 在 text 中标出关键词（不区分大小写），用 <em> 包裹，其余部分做 HTML 转义。
+// 关键词在原文里不连续出现时（CJK 按双字检索命中）退化为按单字标注。
+func highlight(text string, keywords []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	mark := func(term []rune) bool {
+		found := false
+		if len(term) == 0 {
+			return false
+		}
+		for i := 0; i+len(term) <= len(lower); i++ {
+			if string(lower[i:i+len(term)]) == string(term) {
+				spans = append(spans, span{i, i + len(term)})
+				found = true
+			}
+		}
+		return found
+	}
+	for _, kw := range keywords {
+		term := []rune(strings.ToLower(kw))
+		if mark(term) || len(term) < 2 {
+			continue
+		}
+		for i := range term {
+			mark(term[i : i+1])
+		}
+	}
+
+	// 合并重叠区间
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := make([]span, 0, len(spans))
+	for _, sp := range spans {
+		if n := len(merged); n > 0 && sp.start <= merged[n-1].end {
+			if sp.end > merged[n-1].end {
+				merged[n-1].end = sp.end
+			}
+			continue
+		}
+		merged = append(merged, sp)
+	}
+
+	// 长文本只保留第一个命中附近的一段
+	from, to := 0, len(runes)
+	if len(runes) > snippetRunes {
+		if len(merged) > 0 && merged[0].start > snippetLeading {
+			from = merged[0].start - snippetLeading
+		}
+		if from+snippetRunes < to {
+			to = from + snippetRunes
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, sp := range merged {
+		if sp.end <= from || sp.start >= to {
+			continue
+		}
+		start, end := maxInt(sp.start, from), minInt(sp.end, to)
+		b.WriteString(html.EscapeString(string(runes[pos:start])))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(runes[start:end])))
+		b.WriteString("</em>")
+		pos = end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
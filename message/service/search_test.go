@@ -0,0 +1,41 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		keywords []string
+		want     string
+	}{
+		{"no match", "hello", []string{"xyz"}, "hello"},
+		{"case insensitive", "Hello World", []string{"world"}, "Hello <em>World</em>"},
+		{"escapes text around match", "<b>a&b</b>", []string{"a"}, "&lt;b&gt;<em>a</em>&amp;b&lt;/b&gt;"},
+		{"escapes matched text", "x<y", []string{"x<y"}, "<em>x&lt;y</em>"},
+		{"markup in keyword is not injected", "say <script>", []string{"<script>"}, "say <em>&lt;script&gt;</em>"},
+		{"overlapping spans merge", "abcd", []string{"abc", "bcd"}, "<em>abcd</em>"},
+		{"cjk phrase", "明天见面", []string{"明天"}, "<em>明天</em>见面"},
+		{"cjk falls back to single runes", "明日天气", []string{"明天"}, "<em>明</em>日<em>天</em>气"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.keywords); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.keywords, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	text := strings.Repeat("a", 100) + "key" + strings.Repeat("b", 100)
+	got := highlight(text, []string{"key"})
+	want := "…" + strings.Repeat("a", snippetLeading) + "<em>key</em>" +
+		strings.Repeat("b", snippetRunes-snippetLeading-3) + "…"
+	if got != want {
+		t.Errorf("highlight snippet = %q, want %q", got, want)
+	}
+}
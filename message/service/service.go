@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AdventureDe/LinkIM/message/config"
 	"github.com/AdventureDe/LinkIM/message/dto"
 	"github.com/AdventureDe/LinkIM/message/repo"
+	"github.com/AdventureDe/LinkIM/message/repo/model"
 	"github.com/IBM/sarama"
 	"github.com/bwmarrin/snowflake" // 假设使用了此包作为 idGen
 	"github.com/go-redis/redis/v8"
@@ -27,6 +31,57 @@ type AsyncMessage struct {
 	Text      string    `json:"text"`
 	Timestamp int64     `json:"timestamp"`
 	Type      int       `json:"type"` // 1:单聊 2:群聊
+
+	Kind          int16   `json:"kind,omitempty"`            // 消息类型，为 0 时按文本处理
+	ForwardedFrom *int64  `json:"forwarded_from,omitempty"`  // 逐条转发的原消息
+	ReplyToMsgID  *int64  `json:"reply_to_msg_id,omitempty"` // 引用回复的消息
+	Mentions      []int64 `json:"mentions,omitempty"`        // 群聊中 @ 的用户
+	MentionAll    bool    `json:"mention_all,omitempty"`     // @所有人
+	TTLSeconds    int64   `json:"ttl_seconds,omitempty"`     // 单条消息的阅后即焚时间
+}
+
+// SendOptions 发送消息的可选参数，Mentions / MentionAll 只对群聊生效
+type SendOptions struct {
+	MsgID         int64 // 预先分配的消息 ID，为 0 时现生成；定时消息重新投递时靠它去重
+	Kind          int16
+	ForwardedFrom *int64
+	ReplyToMsgID  *int64
+	Mentions      []int64
+	MentionAll    bool
+	TTLSeconds    int64 // 单条消息的阅后即焚时间，为 0 时使用会话设置
+}
+
+// 通过 Redis Pub/Sub 推送给客户端的事件，统一用 json.Marshal 序列化
+type PushEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// 消息被编辑 / 撤回后重新编辑时推送的数据
+type MessageEditedEvent struct {
+	MsgID    int64      `json:"msg_id"`
+	ThreadID int64      `json:"thread_id"`
+	EditorID int64      `json:"editor_id"`
+	TargetID int64      `json:"target_id,omitempty"`
+	GroupID  *uuid.UUID `json:"group_id,omitempty"`
+	Text     string     `json:"text"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+}
+
+// 表情回应变化时推送的数据，只通知会话参与者，不影响会话列表
+type ReactionEvent struct {
+	MsgID    int64  `json:"msg_id"`
+	ThreadID int64  `json:"thread_id"`
+	UserID   int64  `json:"user_id"`
+	Emoji    string `json:"emoji"`
+}
+
+// 消息被撤回时推送的数据，客户端据此展示撤回提示
+type MessageRecalledEvent struct {
+	MsgID      int64      `json:"msg_id"`
+	OperatorID int64      `json:"operator_id"`
+	TargetID   int64      `json:"target_id,omitempty"`
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
 }
 
 type MessageService struct {
@@ -35,21 +90,25 @@ type MessageService struct {
 	logger        *zap.Logger
 	kafkaProducer sarama.AsyncProducer // Kafka 生产者
 	idGen         *snowflake.Node      // 分布式 ID 生成器
+	cfg           *config.Config
 }
 
 // 在初始化时注入 kafkaProducer 和 idGen
-func NewMessageService(r repo.MessageRepo, u *redis.Client, l *zap.Logger, kp sarama.AsyncProducer, idGen *snowflake.Node) *MessageService {
+func NewMessageService(r repo.MessageRepo, u *redis.Client, l *zap.Logger, kp sarama.AsyncProducer,
+	idGen *snowflake.Node, cfg *config.Config) *MessageService {
 	return &MessageService{
 		repo:          r,
 		rdb:           u,
 		logger:        l,
 		kafkaProducer: kp,
 		idGen:         idGen,
+		cfg:           cfg,
 	}
 }
 
 // SendMessageToSingle 发送单聊消息
-func (s *MessageService) SendMessageToSingle(ctx context.Context, senderID, targetID int64, text string) (*int64, error) {
+func (s *MessageService) SendMessageToSingle(ctx context.Context, senderID, targetID int64, text string,
+	opts *SendOptions) (*int64, error) {
 	// 1. 参数校验
 	if senderID <= 0 || targetID <= 0 || senderID == targetID {
 		return nil, errors.New("invalid senderID or targetID")
@@ -57,6 +116,17 @@ func (s *MessageService) SendMessageToSingle(ctx context.Context, senderID, targ
 	if strings.TrimSpace(text) == "" {
 		return nil, errors.New("message text cannot be empty")
 	}
+	if opts == nil {
+		opts = &SendOptions{}
+	}
+	if opts.TTLSeconds < 0 || opts.TTLSeconds > maxDisappearSeconds {
+		return nil, errors.New("invalid ttl")
+	}
+	if opts.ReplyToMsgID != nil {
+		if err := s.repo.CheckReplyTarget(ctx, *opts.ReplyToMsgID, senderID, targetID, nil); err != nil {
+			return nil, err
+		}
+	}
 
 	// 2. 【核心新增】利用 Redis 生成会话级的连续自增 ID (SeqID)
 	// 使用 min 和 max 保证 A发给B 和 B发给A 共享同一个计数器
@@ -70,7 +140,10 @@ func (s *MessageService) SendMessageToSingle(ctx context.Context, senderID, targ
 	}
 
 	// 3. 提前生成分布式 ID (Snowflake)，用于对外暴露
-	msgID := s.idGen.Generate().Int64()
+	msgID := opts.MsgID
+	if msgID == 0 {
+		msgID = s.idGen.Generate().Int64()
+	}
 
 	// 4. 组装消息体
 	msgPayload := AsyncMessage{
@@ -81,6 +154,11 @@ func (s *MessageService) SendMessageToSingle(ctx context.Context, senderID, targ
 		Text:      text,
 		Timestamp: time.Now().UnixMilli(),
 		Type:      1, // 单聊
+
+		Kind:          opts.Kind,
+		ForwardedFrom: opts.ForwardedFrom,
+		ReplyToMsgID:  opts.ReplyToMsgID,
+		TTLSeconds:    opts.TTLSeconds,
 	}
 
 	// 5. 序列化
@@ -159,31 +237,80 @@ func (h *ConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 		// ================= 业务逻辑开始 =================
 
 		// 2. 写入数据库 (Step 1)
-		err := h.persistMessageToDB(session.Context(), &payload)
+		persisted, recipients, err := h.persistMessageToDB(session.Context(), &payload)
 		if err != nil {
+			// 同一个 MsgID 重复投递（例如定时消息被重新领取），已经落库的直接跳过
+			if exists, _ := h.repo.MessageExists(session.Context(), payload.MsgID); exists {
+				h.logger.Warn("duplicate message skipped", zap.Int64("msgID", payload.MsgID))
+				session.MarkMessage(msg, "")
+				continue
+			}
 			h.logger.Error("failed to persist message DB", zap.Error(err), zap.Int64("msgID", payload.MsgID))
 			// 如果数据库挂了，不 MarkMessage，让 Kafka 稍后重试
 			continue
 		}
 
 		// 3. 推送 Redis Pub/Sub (Step 2)
-		h.pushToRedisPubSub(session.Context(), &payload)
+		h.pushToRedisPubSub(session.Context(), &payload, persisted.ThreadID, recipients)
+
+		// 4. 写入 Redis 缓存 (Step 3)，大群每个成员都写一份代价太大，直接从数据库读
+		if !persisted.Thread.LargeGroup {
+			h.saveToRedisCache(session.Context(), &payload, recipients)
+		}
+		h.cacheThreadID(session.Context(), &payload, persisted.ThreadID)
 
-		// 4. 写入 Redis 缓存 (Step 3)
-		h.saveToRedisCache(session.Context(), &payload)
+		// 5. 累加 Redis 未读计数
+		h.incrUnread(session.Context(), persisted.ThreadID, persisted.SeqID, recipients)
+
+		// 发送成功后清掉发送者在这个会话的草稿
+		h.clearDraft(session.Context(), payload.SenderID, persisted.ThreadID)
 
 		// ================= 业务逻辑结束 =================
 
-		// 5. 标记消息已处理
+		// 6. 标记消息已处理
 		session.MarkMessage(msg, "")
 	}
 	return nil
 }
 
-// 修复：彻底重写此函数，解决作用域和参数错误
-func (h *ConsumerHandler) persistMessageToDB(ctx context.Context, msg *AsyncMessage) error {
+// 按消息类型持久化，返回写入的消息和需要推送的接收者
+func (h *ConsumerHandler) persistMessageToDB(ctx context.Context, msg *AsyncMessage) (*model.Message, []int64, error) {
+	opts := &repo.SendOptions{
+		Kind:          msg.Kind,
+		ForwardedFrom: msg.ForwardedFrom,
+		ReplyToMsgID:  msg.ReplyToMsgID,
+		Mentions:      msg.Mentions,
+		MentionAll:    msg.MentionAll,
+		TTLSeconds:    msg.TTLSeconds,
+	}
+	if msg.Type == 2 {
+		persisted, memberIDs, err := h.repo.SendMessageToGroup(ctx, msg.MsgID, msg.SeqID, msg.SenderID, msg.GroupID, msg.Text, opts)
+		if err != nil {
+			h.logger.Error("failed to persist group message",
+				zap.Int64("senderID", msg.SenderID),
+				zap.String("groupID", msg.GroupID.String()),
+				zap.Error(err),
+			)
+			return nil, nil, fmt.Errorf("persist group message failed: %w", err)
+		}
+		// 大群落库时没有拉成员列表，推送用缓存的成员集合
+		if persisted.Thread.LargeGroup {
+			if memberIDs, err = cachedGroupMemberIDs(ctx, h.rdb, h.repo, msg.GroupID); err != nil {
+				// 消息已经落库，推送失败不影响持久化
+				h.logger.Warn("failed to list group members for push", zap.Error(err))
+			}
+		}
+		recipients := make([]int64, 0, len(memberIDs))
+		for _, id := range memberIDs {
+			if id != msg.SenderID {
+				recipients = append(recipients, id)
+			}
+		}
+		return persisted, recipients, nil
+	}
+
 	// msg 已经是 *AsyncMessage 结构体，直接取值调用 repo
-	_, err := h.repo.SendMessageToSingle(ctx, msg.MsgID, msg.SeqID, msg.SenderID, msg.TargetID, msg.Text)
+	persisted, err := h.repo.SendMessageToSingle(ctx, msg.MsgID, msg.SeqID, msg.SenderID, msg.TargetID, msg.Text, opts)
 	if err != nil {
 		h.logger.Error("failed to persist message",
 			zap.Int64("senderID", msg.SenderID),
@@ -191,22 +318,43 @@ func (h *ConsumerHandler) persistMessageToDB(ctx context.Context, msg *AsyncMess
 			zap.String("text", msg.Text),
 			zap.Error(err),
 		)
-		return fmt.Errorf("persist message failed: %w", err)
+		return nil, nil, fmt.Errorf("persist message failed: %w", err)
 	}
-	return nil
+	return persisted, []int64{msg.TargetID}, nil
 }
 
-func (h *ConsumerHandler) pushToRedisPubSub(ctx context.Context, msg *AsyncMessage) {
-	channel := fmt.Sprintf("user:%d:messages", msg.TargetID)
-	payload, _ := json.Marshal(msg)
+// 推送给客户端的新消息，Silent 为 true 时客户端不弹通知（接收者设置了免打扰）
+// 被直接 @ 的用户即使开了免打扰也会收到通知，@所有人不算
+type PushMessage struct {
+	AsyncMessage
+	ThreadID  int64 `json:"thread_id"`
+	Silent    bool  `json:"silent"`
+	Mentioned bool  `json:"mentioned"`
+}
+
+func (h *ConsumerHandler) pushToRedisPubSub(ctx context.Context, msg *AsyncMessage, threadID int64, recipients []int64) {
+	muted := mutedUsers(ctx, h.rdb, threadID, recipients)
+	mentioned := make(map[int64]bool, len(msg.Mentions))
+	for _, uid := range msg.Mentions {
+		mentioned[uid] = true
+	}
 
-	if err := h.rdb.Publish(ctx, channel, payload).Err(); err != nil {
+	pipe := h.rdb.Pipeline()
+	for _, uid := range recipients {
+		payload, _ := json.Marshal(&PushMessage{
+			AsyncMessage: *msg,
+			ThreadID:     threadID,
+			Silent:       muted[uid] && !mentioned[uid],
+			Mentioned:    mentioned[uid] || msg.MentionAll,
+		})
+		pipe.Publish(ctx, fmt.Sprintf("user:%d:messages", uid), payload)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
 		h.logger.Warn("failed to publish redis", zap.Error(err))
 	}
 }
 
-func (h *ConsumerHandler) saveToRedisCache(ctx context.Context, msg *AsyncMessage) {
-	key := fmt.Sprintf("user:%d:msg_cache", msg.TargetID)
+func (h *ConsumerHandler) saveToRedisCache(ctx context.Context, msg *AsyncMessage, recipients []int64) {
 	payload, _ := json.Marshal(msg)
 
 	z := &redis.Z{
@@ -215,9 +363,12 @@ func (h *ConsumerHandler) saveToRedisCache(ctx context.Context, msg *AsyncMessag
 	}
 
 	pipe := h.rdb.Pipeline()
-	pipe.ZAdd(ctx, key, z)
-	pipe.ZRemRangeByRank(ctx, key, 0, -101)
-	pipe.Expire(ctx, key, 7*24*time.Hour)
+	for _, uid := range recipients {
+		key := fmt.Sprintf("user:%d:msg_cache", uid)
+		pipe.ZAdd(ctx, key, z)
+		pipe.ZRemRangeByRank(ctx, key, 0, -101)
+		pipe.Expire(ctx, key, 7*24*time.Hour)
+	}
 
 	if _, err := pipe.Exec(ctx); err != nil {
 		h.logger.Warn("failed to update redis cache", zap.Error(err))
@@ -225,7 +376,8 @@ func (h *ConsumerHandler) saveToRedisCache(ctx context.Context, msg *AsyncMessag
 }
 
 // SendMessageToGroup 发送群聊消息 (异步改造版)
-func (s *MessageService) SendMessageToGroup(ctx context.Context, senderID int64, groupID uuid.UUID, text string) (*int64, error) {
+func (s *MessageService) SendMessageToGroup(ctx context.Context, senderID int64, groupID uuid.UUID, text string,
+	opts *SendOptions) (*int64, error) {
 	// 1. 参数校验
 	if senderID <= 0 || groupID == uuid.Nil {
 		return nil, errors.New("invalid senderID or groupID")
@@ -233,13 +385,46 @@ func (s *MessageService) SendMessageToGroup(ctx context.Context, senderID int64,
 	if strings.TrimSpace(text) == "" {
 		return nil, errors.New("text cannot be empty")
 	}
+	if opts == nil {
+		opts = &SendOptions{}
+	}
+	if opts.TTLSeconds < 0 || opts.TTLSeconds > maxDisappearSeconds {
+		return nil, errors.New("invalid ttl")
+	}
+	if err := s.repo.CheckCanSpeak(ctx, groupID, senderID); err != nil {
+		return nil, err
+	}
+	if opts.ReplyToMsgID != nil {
+		if err := s.repo.CheckReplyTarget(ctx, *opts.ReplyToMsgID, senderID, 0, &groupID); err != nil {
+			return nil, err
+		}
+	}
+	if opts.MentionAll {
+		allowed, err := s.repo.CanMentionAll(ctx, groupID, senderID)
+		if err != nil {
+			s.logger.Error("check mention all permission error", zap.Error(err))
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.New("only group owner or admin can mention all")
+		}
+	}
+	return s.enqueueGroupMessage(ctx, senderID, groupID, text, opts)
+}
+
+// 分配 seq 和消息 ID 后投递到 Kafka，权限校验由调用方负责
+func (s *MessageService) enqueueGroupMessage(ctx context.Context, senderID int64, groupID uuid.UUID, text string,
+	opts *SendOptions) (*int64, error) {
 	// 伪代码演示群聊的 Key 生成
 	redisSeqKey := fmt.Sprintf("linkim:seq:group:%s", groupID.String())
 
 	// 然后一样去 INCR
 	seqID, err := s.rdb.Incr(ctx, redisSeqKey).Result()
 	// 2. 【核心】在这里生成全局唯一的 MessageID！
-	msgID := s.idGen.Generate().Int64()
+	msgID := opts.MsgID
+	if msgID == 0 {
+		msgID = s.idGen.Generate().Int64()
+	}
 
 	// 3. 组装发给 Kafka 的消息体
 	msgPayload := AsyncMessage{
@@ -251,6 +436,13 @@ func (s *MessageService) SendMessageToGroup(ctx context.Context, senderID int64,
 		Text:      text,
 		Timestamp: time.Now().UnixMilli(),
 		Type:      2, // 2: 群聊
+
+		Kind:          opts.Kind,
+		ForwardedFrom: opts.ForwardedFrom,
+		ReplyToMsgID:  opts.ReplyToMsgID,
+		Mentions:      opts.Mentions,
+		MentionAll:    opts.MentionAll,
+		TTLSeconds:    opts.TTLSeconds,
 	}
 
 	// 4. 序列化
@@ -303,17 +495,7 @@ func (s *MessageService) GetConversationMessagesSingle(ctx context.Context, send
 
 	msgs := make([]*dto.MessageDTO, len(cm.Messages))
 	for i, m := range cm.Messages {
-		msgs[i] = &dto.MessageDTO{
-			ID:         m.Message.MsgID,
-			Content:    m.Message.Content,
-			Sender:     m.Message.SenderID,
-			CreateTime: m.Message.CreatedAt,
-			UserInfo: &dto.UserInfoDTO{
-				UserID:       m.User.UserID,
-				SelfNickname: m.User.Nickname,
-				Avatar:       m.User.Avatar,
-			},
-		}
+		msgs[i] = toMessageDTO(m)
 	}
 
 	dtoResult := &dto.ConversationMessagesDTO{
@@ -333,11 +515,15 @@ func (s *MessageService) GetConversationMessagesSingle(ctx context.Context, send
 }
 
 func (s *MessageService) GetConversationMessagesGroup(ctx context.Context, senderID int64, groupID uuid.UUID, lastMsgID int64, pageNum int, pageSize int) (*dto.ConversationMessagesDTO, error) {
+	// 缓存之前先确认还是群成员（或已解散的群解散前的成员），被移出的成员不能再读到缓存的第一页
+	if err := s.repo.CheckGroupReadable(ctx, groupID, senderID); err != nil {
+		return nil, err
+	}
 	useCache := (pageNum == 1)
 
 	var cacheKey string
 	if useCache {
-		cacheKey = fmt.Sprintf("conv:%d:grp:%s:page:1", senderID, groupID.String())
+		cacheKey = s.groupPageCacheKey(ctx, senderID, groupID)
 		if val, err := s.rdb.Get(ctx, cacheKey).Result(); err == nil {
 			var cached dto.ConversationMessagesDTO
 			if jsonErr := json.Unmarshal([]byte(val), &cached); jsonErr == nil {
@@ -354,18 +540,7 @@ func (s *MessageService) GetConversationMessagesGroup(ctx context.Context, sende
 
 	msgs := make([]*dto.MessageDTO, len(cm.Messages))
 	for i, m := range cm.Messages {
-		msgs[i] = &dto.MessageDTO{
-			ID:            m.Message.MsgID,
-			Content:       m.Message.Content,
-			Sender:        m.Message.SenderID,
-			CreateTime:    m.Message.CreatedAt,
-			GroupNickname: m.GroupNickname,
-			UserInfo: &dto.UserInfoDTO{
-				UserID:       m.User.UserID,
-				SelfNickname: m.User.Nickname,
-				Avatar:       m.User.Avatar,
-			},
-		}
+		msgs[i] = toMessageDTO(m)
 	}
 
 	dtoResult := &dto.ConversationMessagesDTO{
@@ -384,15 +559,203 @@ func (s *MessageService) GetConversationMessagesGroup(ctx context.Context, sende
 	return dtoResult, nil
 }
 
+// 表情最多 32 字节，足够放下带肤色和 ZWJ 组合的 emoji
+const maxEmojiLen = 32
+
+// AddReaction 给消息添加表情回应
+func (s *MessageService) AddReaction(ctx context.Context, userID, messageID int64, emoji string) error {
+	return s.updateReaction(ctx, userID, messageID, emoji, true)
+}
+
+// RemoveReaction 取消表情回应
+func (s *MessageService) RemoveReaction(ctx context.Context, userID, messageID int64, emoji string) error {
+	return s.updateReaction(ctx, userID, messageID, emoji, false)
+}
+
+func (s *MessageService) updateReaction(ctx context.Context, userID, messageID int64, emoji string, add bool) error {
+	emoji = strings.TrimSpace(emoji)
+	if userID <= 0 || messageID <= 0 {
+		return errors.New("invalid userID or messageID")
+	}
+	if emoji == "" || len(emoji) > maxEmojiLen {
+		return errors.New("invalid emoji")
+	}
+
+	var (
+		msg   *model.Message
+		err   error
+		event = "reaction_removed"
+	)
+	if add {
+		msg, err = s.repo.AddReaction(ctx, userID, messageID, emoji)
+		event = "reaction_added"
+	} else {
+		msg, err = s.repo.RemoveReaction(ctx, userID, messageID, emoji)
+	}
+	if err != nil {
+		s.logger.Error("update reaction error", zap.Error(err))
+		return fmt.Errorf("update reaction failed: %w", err)
+	}
+
+	push := &PushEvent{
+		Event: event,
+		Data: &ReactionEvent{
+			MsgID:    msg.MsgID,
+			ThreadID: msg.ThreadID,
+			UserID:   userID,
+			Emoji:    emoji,
+		},
+	}
+	if msg.Thread.GroupID != nil {
+		s.publishToGroup(ctx, *msg.Thread.GroupID, push)
+		s.invalidateGroupPageCache(ctx, *msg.Thread.GroupID)
+	} else if msg.Thread.PeerA != nil && msg.Thread.PeerB != nil {
+		s.publishToUsers(ctx, []int64{*msg.Thread.PeerA, *msg.Thread.PeerB}, push)
+		s.invalidateSinglePageCache(ctx, *msg.Thread.PeerA, *msg.Thread.PeerB)
+	}
+	return nil
+}
+
+// GetMessageWindow 跳转到某条消息（搜索结果、引用、@ 提醒），返回锚点前后的消息。
+// 之后可以用返回消息中最早 / 最新的 SeqID 作为锚点继续向前 / 向后翻页。
+func (s *MessageService) GetMessageWindow(ctx context.Context, userID, targetID int64, groupID *uuid.UUID,
+	anchorMsgID, anchorSeq int64, direction string, limit int) (*dto.MessageWindowDTO, error) {
+	if userID <= 0 || (groupID == nil && (targetID <= 0 || targetID == userID)) {
+		return nil, errors.New("invalid userID, targetID or groupID")
+	}
+	if anchorMsgID <= 0 && anchorSeq <= 0 {
+		return nil, errors.New("msg_id or seq is required")
+	}
+	if direction == "" {
+		direction = repo.WindowAround
+	}
+	if limit <= 0 {
+		limit = 20
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	w, err := s.repo.GetMessageWindow(ctx, userID, targetID, groupID, anchorMsgID, anchorSeq, direction, limit)
+	if err != nil {
+		s.logger.Error("get message window error", zap.Error(err))
+		return nil, fmt.Errorf("get message window failed: %w", err)
+	}
+
+	msgs := make([]*dto.MessageDTO, len(w.Messages))
+	for i, m := range w.Messages {
+		msgs[i] = toMessageDTO(m)
+	}
+	return &dto.MessageWindowDTO{
+		ThreadID:      w.Thread.ID,
+		Messages:      msgs,
+		AnchorSeq:     w.AnchorSeq,
+		HasMoreBefore: w.HasMoreBefore,
+		HasMoreAfter:  w.HasMoreAfter,
+	}, nil
+}
+
+// GetGroupReplies 群聊子话题：回复某条消息的所有消息，按时间正序分页
+func (s *MessageService) GetGroupReplies(ctx context.Context, userID int64, groupID uuid.UUID, rootMsgID, lastMsgID int64,
+	pageSize int) (*dto.ConversationMessagesDTO, error) {
+	if userID <= 0 || groupID == uuid.Nil || rootMsgID <= 0 {
+		return nil, errors.New("invalid userID, groupID or msgID")
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	cm, err := s.repo.GetGroupReplies(ctx, userID, groupID, rootMsgID, lastMsgID, pageSize)
+	if err != nil {
+		s.logger.Error("get group replies error", zap.Error(err))
+		return nil, fmt.Errorf("get replies failed: %w", err)
+	}
+
+	msgs := make([]*dto.MessageDTO, len(cm.Messages))
+	for i, m := range cm.Messages {
+		msgs[i] = toMessageDTO(m)
+	}
+	return &dto.ConversationMessagesDTO{
+		ThreadID: cm.Thread.ID,
+		Messages: msgs,
+		HasMore:  cm.HasMore,
+	}, nil
+}
+
+// 把 repo 层的消息转换为返回给前端的 DTO，已撤回的消息只保留撤回提示，不返回原文
+func toMessageDTO(m *repo.MessageWithUser) *dto.MessageDTO {
+	d := &dto.MessageDTO{
+		ID:            m.Message.MsgID,
+		SeqID:         m.Message.SeqID,
+		Kind:          m.Message.Kind,
+		ForwardedFrom: m.Message.ForwardedFrom,
+		Content:       m.Message.Content,
+		Sender:        m.Message.SenderID,
+		CreateTime:    m.Message.CreatedAt,
+		EditedAt:      m.Message.EditedAt,
+		GroupNickname: m.GroupNickname,
+		UserInfo: &dto.UserInfoDTO{
+			UserID:       m.User.UserID,
+			SelfNickname: m.User.Nickname,
+			Avatar:       m.User.Avatar,
+		},
+	}
+	if m.Message.IsWithdrawed {
+		d.Content = ""
+		d.IsWithdrawn = true
+		d.Tombstone = recallTombstone(m.WithdrawnBy)
+	}
+	if q := m.ReplyTo; q != nil {
+		d.ReplyTo = &dto.QuotedMessageDTO{
+			MsgID:          q.MsgID,
+			SenderID:       q.Sender.UserID,
+			SenderNickname: q.Sender.Nickname,
+			Kind:           q.Kind,
+			Content:        q.Content,
+			IsWithdrawn:    q.IsWithdrawn,
+			IsExpired:      q.IsExpired,
+			Unavailable:    q.Unavailable,
+		}
+	}
+	d.ReplyCount = m.ReplyCount
+	d.Mentions = m.Mentions
+	for _, r := range m.Reactions {
+		d.Reactions = append(d.Reactions, &dto.ReactionDTO{
+			Emoji:   r.Emoji,
+			Count:   r.Count,
+			UserIDs: r.UserIDs,
+		})
+	}
+	d.MentionAll = m.Message.MentionAll
+	d.ExpiresAt = m.Message.ExpiresAt
+	return d
+}
+
+func recallTombstone(by *repo.UserInfo) string {
+	if by == nil || by.Nickname == "" {
+		return "一条消息已被撤回"
+	}
+	return fmt.Sprintf("%s 撤回了一条消息", by.Nickname)
+}
+
 func (s *MessageService) WithdrawMessageSingle(ctx context.Context, senderID int64, targetID int64, messageID int64) (int64, error) {
 	if senderID <= 0 || targetID <= 0 || senderID == targetID {
 		return -1, errors.New("invalid senderID or targetID")
 	}
 
-	lastMsgID, err := s.repo.WithdrawMessageSingle(ctx, senderID, targetID, messageID)
+	lastMsgID, err := s.repo.WithdrawMessageSingle(ctx, senderID, targetID, messageID, s.cfg.RecallWindow)
 	if err != nil {
 		return -1, fmt.Errorf("fail to withdraw this message:%d,error:%w", messageID, err)
 	}
+
+	s.publishToUsers(ctx, []int64{senderID, targetID}, &PushEvent{
+		Event: "message_recalled",
+		Data: &MessageRecalledEvent{
+			MsgID:      messageID,
+			OperatorID: senderID,
+			TargetID:   targetID,
+		},
+	})
+	s.invalidateSinglePageCache(ctx, senderID, targetID)
+
 	return lastMsgID, nil
 }
 
@@ -403,16 +766,22 @@ func (s *MessageService) UnWithdrawMessageSingle(ctx context.Context, senderID i
 	if strings.TrimSpace(newText) == "" {
 		return -1, errors.New("message text cannot be empty")
 	}
-	lastMsgID, err := s.repo.UnWithdrawMessageSingle(ctx, senderID, targetID, messageID, newText)
+	lastMsgID, err := s.repo.UnWithdrawMessageSingle(ctx, senderID, targetID, messageID, newText, s.cfg.EditWindow)
 	if err != nil {
 		s.logger.Error("fail to persist message", zap.Error(err))
+		return -1, fmt.Errorf("unwithdraw message failed: %w", err)
 	}
 
-	channel := fmt.Sprintf("targetID:%d,messages", targetID)
-	payload := fmt.Sprintf(`{"msgID":%d,"from":%d,"to":%d,"text":"%s"}`, messageID, senderID, targetID, newText)
-	if err := s.rdb.Publish(ctx, channel, payload).Err(); err != nil {
-		s.logger.Warn("failed to push message via redis", zap.Error(err))
-	}
+	s.publishToUsers(ctx, []int64{senderID, targetID}, &PushEvent{
+		Event: "message_unwithdrawn",
+		Data: &MessageEditedEvent{
+			MsgID:    messageID,
+			EditorID: senderID,
+			TargetID: targetID,
+			Text:     newText,
+		},
+	})
+	s.invalidateSinglePageCache(ctx, senderID, targetID)
 
 	return lastMsgID, nil
 }
@@ -421,13 +790,37 @@ func (s *MessageService) WithdrawMessageGroup(ctx context.Context, senderID int6
 	if senderID <= 0 || groupID == uuid.Nil {
 		return -1, errors.New("invalid senderID or groupID")
 	}
-	lastMsgID, err := s.repo.WithdrawMessageGroup(ctx, senderID, groupID, messageID)
+	lastMsgID, err := s.repo.WithdrawMessageGroup(ctx, senderID, groupID, messageID, s.cfg.RecallWindow)
 	if err != nil {
 		s.logger.Error("withdraw message error", zap.Error(err))
+		return -1, fmt.Errorf("fail to withdraw this message:%d,error:%w", messageID, err)
 	}
+
+	s.publishToGroup(ctx, groupID, &PushEvent{
+		Event: "message_recalled",
+		Data: &MessageRecalledEvent{
+			MsgID:      messageID,
+			OperatorID: senderID,
+			GroupID:    &groupID,
+		},
+	})
+	s.invalidateGroupPageCache(ctx, groupID)
+
 	return lastMsgID, nil
 }
 
+// SetGroupRecallWindow 设置群聊的撤回时间窗口（秒），seconds <= 0 恢复全局配置
+func (s *MessageService) SetGroupRecallWindow(ctx context.Context, operatorID int64, groupID uuid.UUID, seconds int64) error {
+	if operatorID <= 0 || groupID == uuid.Nil {
+		return errors.New("invalid operatorID or groupID")
+	}
+	if err := s.repo.SetGroupRecallWindow(ctx, operatorID, groupID, seconds); err != nil {
+		s.logger.Error("set recall window error", zap.Error(err))
+		return fmt.Errorf("set recall window failed: %w", err)
+	}
+	return nil
+}
+
 func (s *MessageService) UnWithdrawMessageGroup(ctx context.Context, senderID int64, groupID uuid.UUID, messageID int64, newText string) (lastMessageID int64, err error) {
 	if senderID <= 0 || groupID == uuid.Nil {
 		return -1, errors.New("invalid senderID or groupID")
@@ -435,22 +828,384 @@ func (s *MessageService) UnWithdrawMessageGroup(ctx context.Context, senderID in
 	if strings.TrimSpace(newText) == "" {
 		return -1, errors.New("message text cannot be empty")
 	}
+	if err := s.repo.CheckGroupMember(ctx, groupID, senderID); err != nil {
+		return -1, err
+	}
 
-	lastMsgID, err := s.repo.UnWithdrawMessageGroup(ctx, senderID, groupID, messageID, newText)
+	lastMsgID, err := s.repo.UnWithdrawMessageGroup(ctx, senderID, groupID, messageID, newText, s.cfg.EditWindow)
 	if err != nil {
 		s.logger.Error("fail to persist message", zap.Error(err))
 		return -1, fmt.Errorf("unwithdraw message failed: %w", err) // 修复：改掉了文案 "update unread failed"
 	}
 
-	channel := fmt.Sprintf("groupID:%s,messages", groupID.String())
-	payload := fmt.Sprintf(`{"msgID":%d,"from":%d,"to":"%s","text":"%s"}`, messageID, senderID, groupID.String(), newText) // 修复：to 加上双引号以防 JSON 崩溃
-	if err := s.rdb.Publish(ctx, channel, payload).Err(); err != nil {
-		s.logger.Warn("failed to push message via redis", zap.Error(err))
-	}
+	s.publishToGroup(ctx, groupID, &PushEvent{
+		Event: "message_unwithdrawn",
+		Data: &MessageEditedEvent{
+			MsgID:    messageID,
+			EditorID: senderID,
+			GroupID:  &groupID,
+			Text:     newText,
+		},
+	})
+	s.invalidateGroupPageCache(ctx, groupID)
 
 	return lastMsgID, nil
 }
 
+// EditMessageSingle 编辑单聊消息，旧内容进入编辑历史
+func (s *MessageService) EditMessageSingle(ctx context.Context, editorID, targetID, messageID int64,
+	newText string) (*dto.MessageDTO, error) {
+	if editorID <= 0 || targetID <= 0 || editorID == targetID {
+		return nil, errors.New("invalid editorID or targetID")
+	}
+	if strings.TrimSpace(newText) == "" {
+		return nil, errors.New("message text cannot be empty")
+	}
+
+	msg, err := s.repo.EditMessageSingle(ctx, editorID, targetID, messageID, newText, s.cfg.EditWindow)
+	if err != nil {
+		s.logger.Error("edit message error", zap.Error(err))
+		return nil, fmt.Errorf("edit message failed: %w", err)
+	}
+
+	s.publishToUsers(ctx, []int64{editorID, targetID}, &PushEvent{
+		Event: "message_edited",
+		Data: &MessageEditedEvent{
+			MsgID:    msg.MsgID,
+			ThreadID: msg.ThreadID,
+			EditorID: editorID,
+			TargetID: targetID,
+			Text:     msg.Content,
+			EditedAt: msg.EditedAt,
+		},
+	})
+	s.invalidateSinglePageCache(ctx, editorID, targetID)
+
+	return &dto.MessageDTO{
+		ID:         msg.MsgID,
+		Content:    msg.Content,
+		Sender:     msg.SenderID,
+		CreateTime: msg.CreatedAt,
+		EditedAt:   msg.EditedAt,
+	}, nil
+}
+
+// EditMessageGroup 编辑群聊消息，是否允许群主/管理员编辑由 MESSAGE_EDIT_POLICY 决定
+func (s *MessageService) EditMessageGroup(ctx context.Context, editorID int64, groupID uuid.UUID, messageID int64,
+	newText string) (*dto.MessageDTO, error) {
+	if editorID <= 0 || groupID == uuid.Nil {
+		return nil, errors.New("invalid editorID or groupID")
+	}
+	if strings.TrimSpace(newText) == "" {
+		return nil, errors.New("message text cannot be empty")
+	}
+
+	allowAdmin := s.cfg.EditPolicy == config.EditBySenderOrAdmin
+	msg, err := s.repo.EditMessageGroup(ctx, editorID, groupID, messageID, newText, s.cfg.EditWindow, allowAdmin)
+	if err != nil {
+		s.logger.Error("edit group message error", zap.Error(err))
+		return nil, fmt.Errorf("edit message failed: %w", err)
+	}
+
+	s.publishToGroup(ctx, groupID, &PushEvent{
+		Event: "message_edited",
+		Data: &MessageEditedEvent{
+			MsgID:    msg.MsgID,
+			ThreadID: msg.ThreadID,
+			EditorID: editorID,
+			GroupID:  &groupID,
+			Text:     msg.Content,
+			EditedAt: msg.EditedAt,
+		},
+	})
+	s.invalidateGroupPageCache(ctx, groupID)
+
+	return &dto.MessageDTO{
+		ID:         msg.MsgID,
+		Content:    msg.Content,
+		Sender:     msg.SenderID,
+		CreateTime: msg.CreatedAt,
+		EditedAt:   msg.EditedAt,
+	}, nil
+}
+
+func (s *MessageService) GetMessageRevisions(ctx context.Context, userID, messageID int64) ([]*dto.MessageRevisionDTO, error) {
+	if userID <= 0 || messageID <= 0 {
+		return nil, errors.New("invalid userID or messageID")
+	}
+	revisions, err := s.repo.GetMessageRevisions(ctx, userID, messageID)
+	if err != nil {
+		s.logger.Error("failed to get message revisions", zap.Error(err))
+		return nil, fmt.Errorf("fail to get message revisions: %w", err)
+	}
+
+	res := make([]*dto.MessageRevisionDTO, 0, len(revisions))
+	for _, rv := range revisions {
+		res = append(res, &dto.MessageRevisionDTO{
+			ID:        rv.ID,
+			MessageID: rv.MessageID,
+			EditorID:  rv.EditorID,
+			Content:   rv.Content,
+			EditedAt:  rv.EditedAt,
+		})
+	}
+	return res, nil
+}
+
+// 推送事件到指定用户的频道（与 Kafka 消费者使用同一个 user:%d:messages 频道）
+func (s *MessageService) publishToUsers(ctx context.Context, userIDs []int64, event *PushEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error("failed to marshal push event", zap.Error(err))
+		return
+	}
+	pipe := s.rdb.Pipeline()
+	for _, uid := range userIDs {
+		pipe.Publish(ctx, fmt.Sprintf("user:%d:messages", uid), payload)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("failed to push event via redis", zap.String("event", event.Event), zap.Error(err))
+	}
+}
+
+// 推送事件给所有群成员
+func (s *MessageService) publishToGroup(ctx context.Context, groupID uuid.UUID, event *PushEvent) {
+	// 编辑、撤回、表情回应都会推送，成员列表走缓存
+	memberIDs, err := cachedGroupMemberIDs(ctx, s.rdb, s.repo, groupID)
+	if err != nil {
+		s.logger.Warn("failed to list group members for push", zap.Error(err))
+		return
+	}
+	s.publishToUsers(ctx, memberIDs, event)
+}
+
+// 消息内容变化后清掉第一页缓存，否则 5 分钟内会读到旧数据
+func (s *MessageService) invalidateSinglePageCache(ctx context.Context, userA, userB int64) {
+	keys := []string{
+		fmt.Sprintf("conv:%d:tar:%d:page:1", userA, userB),
+		fmt.Sprintf("conv:%d:tar:%d:page:1", userB, userA),
+	}
+	if err := s.rdb.Del(ctx, keys...).Err(); err != nil {
+		s.logger.Warn("failed to invalidate page cache", zap.Error(err))
+	}
+}
+
+// 群的第一页缓存 key 里带着群的缓存版本号，版本号加一后旧 key 不再被读到，等 TTL 自然过期
+func groupPageVersionKey(groupID uuid.UUID) string {
+	return fmt.Sprintf("conv:grp:%s:page_ver", groupID.String())
+}
+
+func (s *MessageService) groupPageCacheKey(ctx context.Context, userID int64, groupID uuid.UUID) string {
+	ver, err := s.rdb.Get(ctx, groupPageVersionKey(groupID)).Int64()
+	if err != nil && err != redis.Nil {
+		s.logger.Warn("failed to get group page cache version", zap.Error(err))
+	}
+	return fmt.Sprintf("conv:%d:grp:%s:v%d:page:1", userID, groupID.String(), ver)
+}
+
+func (s *MessageService) invalidateGroupPageCache(ctx context.Context, groupID uuid.UUID) {
+	if err := s.rdb.Incr(ctx, groupPageVersionKey(groupID)).Err(); err != nil {
+		s.logger.Warn("failed to invalidate group page cache", zap.Error(err))
+	}
+}
+
+// DeleteMessagesForMe 删除消息（仅自己），其他人仍然可以看到
+func (s *MessageService) DeleteMessagesForMe(ctx context.Context, userID int64, messageIDs []int64) error {
+	if userID <= 0 || len(messageIDs) == 0 {
+		return errors.New("invalid userID or messageIDs")
+	}
+	threads, err := s.repo.HideMessages(ctx, userID, messageIDs)
+	if err != nil {
+		s.logger.Error("delete messages for me error", zap.Error(err))
+		return fmt.Errorf("delete messages failed: %w", err)
+	}
+	for _, t := range threads {
+		s.invalidateOwnerPageCache(ctx, userID, t)
+	}
+
+	// 同步给自己的其他在线设备
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "messages_deleted",
+		Data:  map[string]interface{}{"msg_ids": messageIDs},
+	})
+	return nil
+}
+
+// ClearHistory 清空聊天记录（仅自己）
+func (s *MessageService) ClearHistory(ctx context.Context, userID, threadID int64) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	thread, err := s.repo.ClearHistory(ctx, userID, threadID)
+	if err != nil {
+		s.logger.Error("clear history error", zap.Error(err))
+		return fmt.Errorf("clear history failed: %w", err)
+	}
+	s.invalidateOwnerPageCache(ctx, userID, thread)
+	s.resetUnread(ctx, userID, threadID)
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "history_cleared",
+		Data:  map[string]interface{}{"thread_id": threadID},
+	})
+	return nil
+}
+
+// DeleteConversation 从会话列表删除会话，收到新消息时会重新出现
+func (s *MessageService) DeleteConversation(ctx context.Context, userID, threadID int64, clearHistory bool) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	thread, err := s.repo.DeleteConversation(ctx, userID, threadID, clearHistory)
+	if err != nil {
+		s.logger.Error("delete conversation error", zap.Error(err))
+		return fmt.Errorf("delete conversation failed: %w", err)
+	}
+	s.invalidateOwnerPageCache(ctx, userID, thread)
+	s.resetUnread(ctx, userID, threadID)
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "conversation_deleted",
+		Data:  map[string]interface{}{"thread_id": threadID, "clear_history": clearHistory},
+	})
+	return nil
+}
+
+// 只清掉某个用户自己的第一页缓存
+func (s *MessageService) invalidateOwnerPageCache(ctx context.Context, userID int64, thread *model.Thread) {
+	var key string
+	if thread.GroupID != nil {
+		key = s.groupPageCacheKey(ctx, userID, *thread.GroupID)
+	} else {
+		peerID := int64(0)
+		if thread.PeerA != nil && *thread.PeerA != userID {
+			peerID = *thread.PeerA
+		} else if thread.PeerB != nil {
+			peerID = *thread.PeerB
+		}
+		key = fmt.Sprintf("conv:%d:tar:%d:page:1", userID, peerID)
+	}
+	if err := s.rdb.Del(ctx, key).Err(); err != nil {
+		s.logger.Warn("failed to invalidate page cache", zap.Error(err))
+	}
+}
+
+// 免打扰状态同时写一份到 Redis，推送时不用查 Postgres
+// ZSET member 为 threadID，score 为到期时间（unix 秒），一直免打扰时为 +inf
+func mutedKey(userID int64) string {
+	return fmt.Sprintf("linkim:muted:%d", userID)
+}
+
+// 返回 userIDs 中对 threadID 开启了免打扰且未过期的用户
+func mutedUsers(ctx context.Context, rdb *redis.Client, threadID int64, userIDs []int64) map[int64]bool {
+	muted := make(map[int64]bool)
+	if len(userIDs) == 0 {
+		return muted
+	}
+	member := strconv.FormatInt(threadID, 10)
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(userIDs))
+	for i, uid := range userIDs {
+		cmds[i] = pipe.ZScore(ctx, mutedKey(uid), member)
+	}
+	_, _ = pipe.Exec(ctx) // redis.Nil 表示未免打扰，其余错误按未免打扰处理
+	now := float64(time.Now().Unix())
+	for i, cmd := range cmds {
+		if score, err := cmd.Result(); err == nil && score > now {
+			muted[userIDs[i]] = true
+		}
+	}
+	return muted
+}
+
+// PinConversation 置顶 / 取消置顶会话
+func (s *MessageService) PinConversation(ctx context.Context, userID, threadID int64, pinned bool) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	if err := s.repo.SetPinned(ctx, userID, threadID, pinned); err != nil {
+		s.logger.Error("pin conversation error", zap.Error(err))
+		return fmt.Errorf("pin conversation failed: %w", err)
+	}
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "conversation_pinned",
+		Data:  map[string]interface{}{"thread_id": threadID, "pinned": pinned},
+	})
+	return nil
+}
+
+// MuteConversation 免打扰 / 取消免打扰，duration 为 0 表示一直免打扰
+func (s *MessageService) MuteConversation(ctx context.Context, userID, threadID int64, mute bool, duration time.Duration) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	if duration < 0 {
+		return errors.New("invalid mute duration")
+	}
+	var until *time.Time
+	if mute && duration > 0 {
+		t := time.Now().Add(duration)
+		until = &t
+	}
+	if err := s.repo.SetMute(ctx, userID, threadID, mute, until); err != nil {
+		s.logger.Error("mute conversation error", zap.Error(err))
+		return fmt.Errorf("mute conversation failed: %w", err)
+	}
+
+	member := strconv.FormatInt(threadID, 10)
+	var err error
+	if mute {
+		score := math.Inf(1)
+		if until != nil {
+			score = float64(until.Unix())
+		}
+		err = s.rdb.ZAdd(ctx, mutedKey(userID), &redis.Z{Score: score, Member: member}).Err()
+	} else {
+		err = s.rdb.ZRem(ctx, mutedKey(userID), member).Err()
+	}
+	if err != nil {
+		// 数据库已经更新，Redis 失败只影响推送是否静音
+		s.logger.Warn("failed to sync mute state to redis", zap.Error(err))
+	}
+
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "conversation_muted",
+		Data:  map[string]interface{}{"thread_id": threadID, "mute": mute, "mute_until": until},
+	})
+	return nil
+}
+
+// MarkConversationUnread 标记为未读，再次进入会话时清除
+func (s *MessageService) MarkConversationUnread(ctx context.Context, userID, threadID int64) error {
+	if userID <= 0 || threadID <= 0 {
+		return errors.New("invalid userID or threadID")
+	}
+	if err := s.repo.MarkUnread(ctx, userID, threadID); err != nil {
+		s.logger.Error("mark unread error", zap.Error(err))
+		return fmt.Errorf("mark unread failed: %w", err)
+	}
+	s.markUnreadCounter(ctx, userID, threadID)
+	s.publishToUsers(ctx, []int64{userID}, &PushEvent{
+		Event: "conversation_marked_unread",
+		Data:  map[string]interface{}{"thread_id": threadID},
+	})
+	return nil
+}
+
+// TotalUnread 计算总未读角标：免打扰的会话不计入，手动标记未读的会话至少算 1
+func TotalUnread(conversations []*dto.ConversationDTO) int {
+	total := 0
+	for _, c := range conversations {
+		if c.Muted {
+			continue
+		}
+		if c.UnreadCount > 0 {
+			total += c.UnreadCount
+		} else if c.MarkedUnread {
+			total++
+		}
+	}
+	return total
+}
+
 func (s *MessageService) UpdateUnread(ctx context.Context, userID, threadID int64) error {
 	if userID <= 0 || threadID <= 0 {
 		return errors.New("invalid userID or threadID")
@@ -459,6 +1214,7 @@ func (s *MessageService) UpdateUnread(ctx context.Context, userID, threadID int6
 		s.logger.Error("update unread fail", zap.Error(err))
 		return fmt.Errorf("update unread failed: %w", err)
 	}
+	s.resetUnread(ctx, userID, threadID)
 	return nil
 }
 
@@ -497,20 +1253,33 @@ func (s *MessageService) GetConversations(ctx context.Context, userID int64) ([]
 			}
 		}
 
-		c = append(c, &dto.ConversationDTO{
-			Type:     ty,
-			ThreadID: conv.ThreadID,
-			LastMessage: &dto.Message{
+		// 会话被清空或最后一条消息被自己删除时没有 LastMessage
+		var lastMessage *dto.Message
+		if conv.LastMessage != nil {
+			lastMessage = &dto.Message{
 				ID:        conv.LastMessage.MsgID,
 				SenderID:  conv.LastMessage.SenderID,
 				Kind:      conv.LastMessage.Kind,
 				Content:   conv.LastMessage.Content,
 				CreatedAt: conv.LastMessage.CreatedAt,
-			},
-			UnreadCount: conv.UnreadCount,
-			UserInfo:    userInfo,
-			GroupInfo:   groupInfo,
-			UpdateTime:  conv.UpdateTime,
+			}
+		}
+
+		c = append(c, &dto.ConversationDTO{
+			Type:         ty,
+			ThreadID:     conv.ThreadID,
+			LastMessage:  lastMessage,
+			UnreadCount:  conv.UnreadCount,
+			MentionCount: conv.MentionCount,
+			Draft:        toDraftDTO(conv.Draft),
+			UserInfo:     userInfo,
+			GroupInfo:    groupInfo,
+			UpdateTime:   conv.UpdateTime,
+			Pinned:       conv.Pinned,
+			Muted:        conv.Muted,
+			MuteUntil:    conv.MuteUntil,
+			MarkedUnread: conv.MarkedUnread,
+			Dissolved:    conv.Dissolved,
 		})
 	}
 
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 输入状态只走 Redis，不落库也不进 Kafka
+const (
+	TypingStateTyping    = "typing"
+	TypingStateRecording = "recording"
+	TypingStateStopped   = "stopped"
+)
+
+const (
+	// 同一个状态在这个时间内只推送一次，客户端可以每次按键都上报
+	typingThrottle = 3 * time.Second
+	// 服务端状态的有效期，客户端收到事件后超过 ExpiresIn 没有新事件就当作已停止
+	typingTTL = 6 * time.Second
+)
+
+// 输入状态变化时推送的数据，单聊按 UserID 区分会话，群聊按 GroupID
+// ThreadID 取自 Redis 缓存，会话还没有消息时为 0
+type TypingEvent struct {
+	UserID    int64      `json:"user_id"`
+	GroupID   *uuid.UUID `json:"group_id,omitempty"`
+	ThreadID  int64      `json:"thread_id,omitempty"`
+	State     string     `json:"state"`
+	ExpiresIn int64      `json:"expires_in"` // 秒
+}
+
+// 单聊两个方向共用一个 scope
+func typingScope(userID, targetID int64, groupID *uuid.UUID) string {
+	if groupID != nil {
+		return "grp:" + groupID.String()
+	}
+	return fmt.Sprintf("single:%d_%d", min(userID, targetID), max(userID, targetID))
+}
+
+func typingStateKey(scope string, userID int64) string {
+	return fmt.Sprintf("linkim:typing:%s:%d", scope, userID)
+}
+
+func typingThrottleKey(scope string, userID int64, state string) string {
+	return fmt.Sprintf("linkim:typing:throttle:%s:%d:%s", scope, userID, state)
+}
+
+// scope -> threadID 的缓存，由 Kafka 消费者在消息落库后写入，
+// 推送输入状态时用来判断接收者是否开了免打扰，避免查 Postgres
+func threadCacheKey(scope string) string {
+	return "linkim:thread:" + scope
+}
+
+func (h *ConsumerHandler) cacheThreadID(ctx context.Context, msg *AsyncMessage, threadID int64) {
+	var scope string
+	if msg.Type == 2 {
+		scope = typingScope(msg.SenderID, 0, &msg.GroupID)
+	} else {
+		scope = typingScope(msg.SenderID, msg.TargetID, nil)
+	}
+	if err := h.rdb.Set(ctx, threadCacheKey(scope), threadID, 7*24*time.Hour).Err(); err != nil {
+		h.logger.Warn("failed to cache thread id", zap.Error(err))
+	}
+}
+
+// SendTyping 推送输入状态给会话里的其他人。
+// typing / recording 会被节流，stopped 只有在之前有状态时才推送；
+// 把发送者拉黑了的人、对这个会话开了免打扰的人收不到
+func (s *MessageService) SendTyping(ctx context.Context, userID, targetID int64, groupID *uuid.UUID, state string) error {
+	if userID <= 0 || (groupID == nil && (targetID <= 0 || targetID == userID)) ||
+		(groupID != nil && *groupID == uuid.Nil) {
+		return errors.New("invalid userID, targetID or groupID")
+	}
+	if state != TypingStateTyping && state != TypingStateRecording && state != TypingStateStopped {
+		return errors.New("invalid typing state")
+	}
+
+	scope := typingScope(userID, targetID, groupID)
+	stateKey := typingStateKey(scope, userID)
+	if state == TypingStateStopped {
+		n, err := s.rdb.Del(ctx, stateKey,
+			typingThrottleKey(scope, userID, TypingStateTyping),
+			typingThrottleKey(scope, userID, TypingStateRecording)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to clear typing state: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+	} else {
+		if err := s.rdb.Set(ctx, stateKey, state, typingTTL).Err(); err != nil {
+			return fmt.Errorf("failed to save typing state: %w", err)
+		}
+		ok, err := s.rdb.SetNX(ctx, typingThrottleKey(scope, userID, state), 1, typingThrottle).Result()
+		if err != nil {
+			return fmt.Errorf("failed to throttle typing state: %w", err)
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	recipients, err := s.typingRecipients(ctx, userID, targetID, groupID)
+	if err != nil {
+		return err
+	}
+	blocked, err := s.repo.GetBlockedBy(ctx, userID, recipients)
+	if err != nil {
+		return err
+	}
+	var threadID int64
+	if id, err := s.rdb.Get(ctx, threadCacheKey(scope)).Int64(); err == nil {
+		threadID = id
+	} else if err != redis.Nil {
+		s.logger.Warn("failed to get cached thread id", zap.Error(err))
+	}
+	muted := make(map[int64]bool)
+	if threadID > 0 {
+		muted = mutedUsers(ctx, s.rdb, threadID, recipients)
+	}
+
+	targets := make([]int64, 0, len(recipients))
+	for _, uid := range recipients {
+		if !blocked[uid] && !muted[uid] {
+			targets = append(targets, uid)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	s.publishToUsers(ctx, targets, &PushEvent{
+		Event: "typing",
+		Data: &TypingEvent{
+			UserID:    userID,
+			GroupID:   groupID,
+			ThreadID:  threadID,
+			State:     state,
+			ExpiresIn: int64(typingTTL / time.Second),
+		},
+	})
+	return nil
+}
+
+// 单聊是对方，群聊是除自己以外的群成员；不能在群里发言（不在群里、被禁言、群已归档）时不能发送输入状态
+func (s *MessageService) typingRecipients(ctx context.Context, userID, targetID int64, groupID *uuid.UUID) ([]int64, error) {
+	if groupID == nil {
+		return []int64{targetID}, nil
+	}
+	if err := s.repo.CheckCanSpeak(ctx, *groupID, userID); err != nil {
+		return nil, err
+	}
+	memberIDs, err := cachedGroupMemberIDs(ctx, s.rdb, s.repo, *groupID)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]int64, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id != userID {
+			recipients = append(recipients, id)
+		}
+	}
+	return recipients, nil
+}
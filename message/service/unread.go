@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/message/dto"
+	"github.com/AdventureDe/LinkIM/message/repo"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// 未读计数缓存在 Redis hash linkim:unread:{userID}
+//   - field {threadID}   -> 未读条数
+//   - field m:{threadID} -> 手动标记为未读
+//   - field s:{threadID} -> 加载时会话里已经落库的最大 seq，seq 不超过它的消息已经计入
+//   - field _            -> 哨兵，表示 hash 已经从 Postgres 完整加载
+//
+// Postgres 的 Conversation.UnreadCount 仍然是权威数据。消息在加载前落库、加载后才累加时，
+// 靠 s:{threadID} 拒绝这次累加，避免同一条消息被算两次。
+const (
+	unreadLoadedField = "_"
+	unreadMarkPrefix  = "m:"
+	unreadSeqPrefix   = "s:"
+	unreadCacheTTL    = time.Hour
+)
+
+func unreadKey(userID int64) string {
+	return fmt.Sprintf("linkim:unread:%d", userID)
+}
+
+// 只在 hash 已经加载、且消息没有在加载时计入过的情况下才累加
+var incrUnreadScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local loadedSeq = tonumber(redis.call("HGET", KEYS[1], ARGV[3]) or "0")
+if tonumber(ARGV[4]) <= loadedSeq then
+	return 0
+end
+return redis.call("HINCRBY", KEYS[1], ARGV[2], 1)
+`)
+
+// 新消息落库后给接收者累加未读。
+// 流水线里的 Run 只会发 EVALSHA，脚本没加载时不会自动退回 EVAL，所以这里直接用 Eval
+func (h *ConsumerHandler) incrUnread(ctx context.Context, threadID, seq int64, recipients []int64) {
+	field := strconv.FormatInt(threadID, 10)
+	pipe := h.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(recipients))
+	for i, uid := range recipients {
+		cmds[i] = incrUnreadScript.Eval(ctx, pipe, []string{unreadKey(uid)}, unreadLoadedField, field, unreadSeqPrefix+field, seq)
+	}
+	_, _ = pipe.Exec(ctx)
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if err == nil || err == redis.Nil {
+			continue
+		}
+		h.logger.Warn("failed to incr unread counter", zap.Int64("user_id", recipients[i]), zap.Error(err))
+		// Redis 返回的错误说明脚本没有执行，计数没变；
+		// 连接出错时不知道有没有累加，删掉让下次读取从 Postgres 重新加载
+		var redisErr redis.Error
+		if !errors.As(err, &redisErr) {
+			_ = h.rdb.Del(ctx, unreadKey(recipients[i])).Err()
+		}
+	}
+}
+
+// 用 Postgres 里的状态覆盖一个会话的计数，只在 hash 已加载时写入
+// ARGV: 哨兵, 计数 field, 标记 field, seq field, 未读数, 是否标记未读, LastSeq
+var refreshUnreadScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[1], ARGV[2], ARGV[3])
+if tonumber(ARGV[5]) > 0 then
+	redis.call("HSET", KEYS[1], ARGV[2], ARGV[5])
+end
+if ARGV[6] == "1" then
+	redis.call("HSET", KEYS[1], ARGV[3], 1)
+end
+if tonumber(ARGV[7]) > 0 then
+	redis.call("HSET", KEYS[1], ARGV[4], ARGV[7])
+end
+return 1
+`)
+
+// 会话已读、清空或删除后，从 Postgres 重新读出这个会话的计数覆盖 Redis，
+// 同时更新 s:{threadID}，这之前落库、之后才到的累加会被拒绝
+func (s *MessageService) resetUnread(ctx context.Context, userID, threadID int64) {
+	key := unreadKey(userID)
+	states, err := s.repo.GetUnreadStates(ctx, userID, threadID)
+	if err == nil {
+		st := &repo.UnreadState{ThreadID: threadID}
+		if len(states) > 0 {
+			st = states[0]
+		}
+		field := strconv.FormatInt(threadID, 10)
+		marked := "0"
+		if st.MarkedUnread {
+			marked = "1"
+		}
+		err = refreshUnreadScript.Run(ctx, s.rdb, []string{key}, unreadLoadedField, field,
+			unreadMarkPrefix+field, unreadSeqPrefix+field, st.UnreadCount, marked, st.LastSeq).Err()
+	}
+	if err != nil && err != redis.Nil {
+		s.logger.Warn("failed to reset unread counter", zap.Error(err))
+		_ = s.rdb.Del(ctx, key).Err()
+	}
+}
+
+// 标记未读只在 hash 已加载时写入，没加载时下次会从 Postgres 读到
+func (s *MessageService) markUnreadCounter(ctx context.Context, userID, threadID int64) {
+	key := unreadKey(userID)
+	loaded, err := s.rdb.HExists(ctx, key, unreadLoadedField).Result()
+	if err == nil && loaded {
+		err = s.rdb.HSet(ctx, key, unreadMarkPrefix+strconv.FormatInt(threadID, 10), 1).Err()
+	}
+	if err != nil {
+		s.logger.Warn("failed to mark unread counter", zap.Error(err))
+		_ = s.rdb.Del(ctx, key).Err()
+	}
+}
+
+// 从 Postgres 重新加载未读计数，同时重建免打扰集合
+func (s *MessageService) reloadUnread(ctx context.Context, userID int64) (map[string]string, error) {
+	states, err := s.repo.GetUnreadStates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{unreadLoadedField: "1"}
+	var muted []*redis.Z
+	now := time.Now()
+	for _, st := range states {
+		id := strconv.FormatInt(st.ThreadID, 10)
+		if st.UnreadCount > 0 {
+			fields[id] = strconv.Itoa(st.UnreadCount)
+		}
+		if st.MarkedUnread {
+			fields[unreadMarkPrefix+id] = "1"
+		}
+		if st.LastSeq > 0 {
+			fields[unreadSeqPrefix+id] = strconv.FormatInt(st.LastSeq, 10)
+		}
+		if st.Mute && (st.MuteUntil == nil || st.MuteUntil.After(now)) {
+			score := math.Inf(1)
+			if st.MuteUntil != nil {
+				score = float64(st.MuteUntil.Unix())
+			}
+			muted = append(muted, &redis.Z{Score: score, Member: id})
+		}
+	}
+
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		values[k] = v
+	}
+	key := unreadKey(userID)
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, values)
+	pipe.Expire(ctx, key, unreadCacheTTL)
+	pipe.Del(ctx, mutedKey(userID))
+	if len(muted) > 0 {
+		pipe.ZAdd(ctx, mutedKey(userID), muted...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("failed to cache unread counters", zap.Error(err))
+	}
+	return fields, nil
+}
+
+// GetUnreadSummary 返回总未读数和每个会话的未读数，免打扰的会话不计入
+func (s *MessageService) GetUnreadSummary(ctx context.Context, userID int64) (*dto.UnreadSummaryDTO, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("invalid userID")
+	}
+
+	fields, err := s.rdb.HGetAll(ctx, unreadKey(userID)).Result()
+	if err != nil || fields[unreadLoadedField] == "" {
+		if err != nil {
+			s.logger.Warn("failed to read unread counters", zap.Error(err))
+		}
+		if fields, err = s.reloadUnread(ctx, userID); err != nil {
+			s.logger.Error("failed to load unread states", zap.Error(err))
+			return nil, fmt.Errorf("get unread failed: %w", err)
+		}
+	}
+
+	threads := make(map[int64]*dto.ThreadUnreadDTO)
+	for field, value := range fields {
+		if field == unreadLoadedField || strings.HasPrefix(field, unreadSeqPrefix) {
+			continue
+		}
+		marked := strings.HasPrefix(field, unreadMarkPrefix)
+		threadID, err := strconv.ParseInt(strings.TrimPrefix(field, unreadMarkPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		t, ok := threads[threadID]
+		if !ok {
+			t = &dto.ThreadUnreadDTO{ThreadID: threadID}
+			threads[threadID] = t
+		}
+		if marked {
+			t.MarkedUnread = true
+		} else {
+			t.UnreadCount, _ = strconv.Atoi(value)
+		}
+	}
+
+	ids := make([]int64, 0, len(threads))
+	for id := range threads {
+		ids = append(ids, id)
+	}
+	muted := mutedThreads(ctx, s.rdb, userID, ids)
+
+	summary := &dto.UnreadSummaryDTO{Threads: make([]*dto.ThreadUnreadDTO, 0, len(threads))}
+	for id, t := range threads {
+		if muted[id] || (t.UnreadCount <= 0 && !t.MarkedUnread) {
+			continue
+		}
+		// 和会话列表的 total_unread 保持一致：手动标记未读至少算 1
+		if t.UnreadCount > 0 {
+			summary.Total += t.UnreadCount
+		} else {
+			summary.Total++
+		}
+		summary.Threads = append(summary.Threads, t)
+	}
+	sort.Slice(summary.Threads, func(i, j int) bool {
+		return summary.Threads[i].ThreadID < summary.Threads[j].ThreadID
+	})
+	return summary, nil
+}
+
+// 返回 threadIDs 中当前处于免打扰的会话
+func mutedThreads(ctx context.Context, rdb *redis.Client, userID int64, threadIDs []int64) map[int64]bool {
+	muted := make(map[int64]bool)
+	if len(threadIDs) == 0 {
+		return muted
+	}
+	members := make([]string, len(threadIDs))
+	for i, id := range threadIDs {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(members))
+	for i, m := range members {
+		cmds[i] = pipe.ZScore(ctx, mutedKey(userID), m)
+	}
+	_, _ = pipe.Exec(ctx)
+	now := float64(time.Now().Unix())
+	for i, cmd := range cmds {
+		if score, err := cmd.Result(); err == nil && score > now {
+			muted[threadIDs[i]] = true
+		}
+	}
+	return muted
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
 
@@ -56,6 +57,8 @@ func main() {
 	userHandler := handler.NewUserHandler(userService)
 	router.SetupRouter(r, userHandler)
 	router.SetupFriendRouter(r, userHandler)
+	router.SetupPresenceRouter(r, userHandler)
+	go userService.SweepPresence(context.Background())
 
 	userServiceWithRedis := service.NewVerificationService(userRepoRedis)
 	userHandlerWithRedis := handler.NewVerificationHandler(userServiceWithRedis)
@@ -63,7 +66,7 @@ func main() {
 
 	// 6. 初始化并注册 gRPC 服务
 	grpcServer := grpc.NewServer()
-	userServer := repo.NewUserServiceServer(userRepo)
+	userServer := repo.NewUserServiceServer(userRepo, userService)
 	userpb.RegisterUserServiceServer(grpcServer, userServer)
 	reflection.Register(grpcServer)
 
@@ -16,3 +16,18 @@ type LogoutRequest struct {
 	UserID int64  `json:"userID" binding:"required"`
 	Token  string `json:"token" binding:"required"`
 }
+
+// 在线状态，Visible 为 false 时表示对方不让查看，其余字段为空
+type Presence struct {
+	UserID    int64      `json:"user_id"`
+	Visible   bool       `json:"visible"`
+	Online    bool       `json:"online"`
+	Platforms []int      `json:"platforms"`
+	LastSeen  *time.Time `json:"last_seen"`
+}
+
+// 通过 Redis Pub/Sub 推送给客户端的事件，频道与消息服务相同（user:%d:messages）
+type PushEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
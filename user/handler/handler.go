@@ -617,3 +617,113 @@ func (h *UserHandler) GetBlockedFriends(c *gin.Context) {
 		"detail":  info,
 	})
 }
+
+/* ----------------------------------------------------- */
+// 在线状态部分
+
+// 心跳，platform 区分设备
+func (h *UserHandler) Heartbeat(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id" binding:"required"`
+		Platform int   `json:"platform" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.Heartbeat(c.Request.Context(), input.UserID, input.Platform); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "heartbeat ok",
+	})
+}
+
+// 设备主动下线，例如 App 退到后台或关闭网页
+func (h *UserHandler) Offline(c *gin.Context) {
+	var input struct {
+		UserID   int64 `json:"user_id" binding:"required"`
+		Platform int   `json:"platform" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.Offline(c.Request.Context(), input.UserID, input.Platform); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "offline ok",
+	})
+}
+
+// 修改在线状态可见范围：everyone / friends / nobody
+func (h *UserHandler) UpdatePresenceVisibility(c *gin.Context) {
+	var input struct {
+		UserID     int64  `json:"user_id" binding:"required"`
+		Platform   int    `json:"platform" binding:"required,min=1"`
+		Visibility string `json:"visibility" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	if err := h.service.UpdatePresenceVisibility(c.Request.Context(), input.UserID, input.Visibility); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "update presence privacy ok",
+	})
+}
+
+// 订阅一批用户的在线状态变化，返回当前状态
+func (h *UserHandler) SubscribePresence(c *gin.Context) {
+	var input struct {
+		UserID    int64   `json:"user_id" binding:"required"`
+		Platform  int     `json:"platform" binding:"required,min=1"`
+		TargetIDs []int64 `json:"target_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	presences, err := h.service.SubscribePresence(c.Request.Context(), input.UserID, input.TargetIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "subscribe presence ok",
+		"detail":  presences,
+	})
+}
+
+// 批量查询在线状态，target_ids 可以重复传多个
+func (h *UserHandler) GetPresences(c *gin.Context) {
+	var input struct {
+		UserID    int64   `form:"user_id" binding:"required"`
+		Platform  int     `form:"platform" binding:"required,min=1"`
+		TargetIDs []int64 `form:"target_ids" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	presences, err := h.service.GetPresences(c.Request.Context(), input.UserID, input.TargetIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "get presences ok",
+		"detail":  presences,
+	})
+}
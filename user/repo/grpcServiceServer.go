@@ -5,18 +5,26 @@ import (
 	"log"
 
 	userpb "github.com/AdventureDe/LinkIM/api/user"
+	"github.com/AdventureDe/LinkIM/user/dto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// PresenceReader 在线状态查询，由 service 层实现，避免 repo 反向依赖 service
+type PresenceReader interface {
+	GetPresences(ctx context.Context, viewerID int64, userIDs []int64) ([]*dto.Presence, error)
+}
+
 type UserServiceServer struct {
 	userpb.UnimplementedUserServiceServer
-	repo UserRepo
+	repo     UserRepo
+	presence PresenceReader
 }
 
-func NewUserServiceServer(r UserRepo) *UserServiceServer {
+func NewUserServiceServer(r UserRepo, p PresenceReader) *UserServiceServer {
 	return &UserServiceServer{
-		repo: r,
+		repo:     r,
+		presence: p,
 	}
 }
 
@@ -66,3 +74,41 @@ func (s *UserServiceServer) GetUserInfos(ctx context.Context, req *userpb.GetUse
 		Users: userProtos,
 	}, nil
 }
+
+// 在候选用户里找出拉黑了 user_id 的人，用于消息服务过滤输入状态等推送
+func (s *UserServiceServer) GetBlockedBy(ctx context.Context, req *userpb.GetBlockedByRequest) (*userpb.GetBlockedByResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id")
+	}
+	userIDs, err := s.repo.GetBlockedBy(ctx, req.GetUserId(), req.GetCandidateIds())
+	if err != nil {
+		log.Printf("Failed to get blocked by: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to retrieve blacklist")
+	}
+	return &userpb.GetBlockedByResponse{UserIds: userIDs}, nil
+}
+
+// 批量查询在线状态，用于会话列表、好友列表
+func (s *UserServiceServer) GetPresences(ctx context.Context, req *userpb.GetPresencesRequest) (*userpb.GetPresencesResponse, error) {
+	presences, err := s.presence.GetPresences(ctx, req.GetViewerId(), req.GetUserIds())
+	if err != nil {
+		log.Printf("Failed to get presences: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to retrieve presences")
+	}
+	res := make([]*userpb.Presence, 0, len(presences))
+	for _, p := range presences {
+		pb := &userpb.Presence{
+			UserId:  p.UserID,
+			Visible: p.Visible,
+			Online:  p.Online,
+		}
+		for _, platform := range p.Platforms {
+			pb.Platforms = append(pb.Platforms, int32(platform))
+		}
+		if p.LastSeen != nil {
+			pb.LastSeen = p.LastSeen.Unix()
+		}
+		res = append(res, pb)
+	}
+	return &userpb.GetPresencesResponse{Presences: res}, nil
+}
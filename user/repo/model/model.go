@@ -12,6 +12,15 @@ const (
 	Rejected Status = "rejected"
 )
 
+// 谁可以看到我的在线状态和最后在线时间
+type Visibility string
+
+const (
+	VisibleEveryone Visibility = "everyone"
+	VisibleFriends  Visibility = "friends"
+	VisibleNobody   Visibility = "nobody"
+)
+
 // 测试账号：  12321412411
 // 测试密码:   qwer12345678
 type User struct {
@@ -26,6 +35,8 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	LastLoginAt  time.Time `json:"last_login_at "`
+	// 在线状态可见范围，默认仅好友可见
+	PresenceVisibility Visibility `gorm:"size:16;default:'friends'" json:"presence_visibility"`
 }
 
 type Friendship struct {
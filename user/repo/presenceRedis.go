@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/user/dto"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 客户端每 30 秒发一次心跳，超过 PresenceTimeout 没有心跳的设备视为离线
+const PresenceTimeout = 90 * time.Second
+
+// presence:devices:{uid}    HASH  platform -> 最后一次心跳（unix 秒）
+// presence:heartbeats       ZSET  "{uid}:{platform}" -> 最后一次心跳，用于扫描超时设备
+// presence:last_seen:{uid}  最后一个设备离线的时间
+// presence:subscribers:{uid} ZSET 订阅了 uid 在线状态的用户 -> 订阅到期时间
+const presenceHeartbeatsKey = "presence:heartbeats"
+
+func presenceDevicesKey(userID int64) string {
+	return fmt.Sprintf("presence:devices:%d", userID)
+}
+
+func presenceLastSeenKey(userID int64) string {
+	return fmt.Sprintf("presence:last_seen:%d", userID)
+}
+
+func presenceSubscribersKey(userID int64) string {
+	return fmt.Sprintf("presence:subscribers:%d", userID)
+}
+
+func presenceMember(userID int64, platform int) string {
+	return fmt.Sprintf("%d:%d", userID, platform)
+}
+
+// 记录心跳，返回 1 表示之前没有在线设备（用户刚上线）
+var heartbeatScript = redis.NewScript(`
+local online = 0
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 2, #fields, 2 do
+	if tonumber(fields[i]) > tonumber(ARGV[3]) then
+		online = 1
+		break
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[4])
+if online == 0 then
+	return 1
+end
+return 0
+`)
+
+// 设备主动下线，ARGV[1] 为 0 时下线所有设备；返回 1 表示用户已经没有在线设备
+var offlineScript = redis.NewScript(`
+local function alive()
+	local fields = redis.call('HGETALL', KEYS[1])
+	for i = 2, #fields, 2 do
+		if tonumber(fields[i]) > tonumber(ARGV[3]) then
+			return true
+		end
+	end
+	return false
+end
+local was = alive()
+if ARGV[1] == '0' then
+	for _, p in ipairs(redis.call('HKEYS', KEYS[1])) do
+		redis.call('ZREM', KEYS[2], ARGV[4] .. ':' .. p)
+	end
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('ZREM', KEYS[2], ARGV[4] .. ':' .. ARGV[1])
+end
+if was and not alive() then
+	redis.call('SET', KEYS[3], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// 清理一个超时设备，期间收到过新心跳则跳过；返回 1 表示用户已经没有在线设备。
+// 多个实例同时扫描时只有一个会返回 1
+var sweepScript = redis.NewScript(`
+local ts = redis.call('HGET', KEYS[1], ARGV[1])
+if ts and tonumber(ts) > tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[2])
+if not ts then
+	return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 2, #fields, 2 do
+	if tonumber(fields[i]) > tonumber(ARGV[3]) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[3], ts)
+return 1
+`)
+
+func presenceCutoff(now time.Time) int64 {
+	return now.Add(-PresenceTimeout).Unix()
+}
+
+func (r *userRedis) Heartbeat(ctx context.Context, userID int64, platform int) (bool, error) {
+	now := time.Now()
+	res, err := heartbeatScript.Run(ctx, r.rdb,
+		[]string{presenceDevicesKey(userID), presenceHeartbeatsKey},
+		platform, now.Unix(), presenceCutoff(now), presenceMember(userID, platform)).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func (r *userRedis) Offline(ctx context.Context, userID int64, platform int) (bool, error) {
+	now := time.Now()
+	res, err := offlineScript.Run(ctx, r.rdb,
+		[]string{presenceDevicesKey(userID), presenceHeartbeatsKey, presenceLastSeenKey(userID)},
+		platform, now.Unix(), presenceCutoff(now), userID).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// SweepPresence 清理超时的设备，返回因此离线的用户
+func (r *userRedis) SweepPresence(ctx context.Context) ([]int64, error) {
+	now := time.Now()
+	cutoff := presenceCutoff(now)
+	members, err := r.rdb.ZRangeByScore(ctx, presenceHeartbeatsKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(cutoff, 10),
+		Count: 500,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var offline []int64
+	for _, member := range members {
+		uidStr, platform, ok := strings.Cut(member, ":")
+		userID, err := strconv.ParseInt(uidStr, 10, 64)
+		if !ok || err != nil {
+			r.rdb.ZRem(ctx, presenceHeartbeatsKey, member)
+			continue
+		}
+		res, err := sweepScript.Run(ctx, r.rdb,
+			[]string{presenceDevicesKey(userID), presenceHeartbeatsKey, presenceLastSeenKey(userID)},
+			platform, member, cutoff).Int()
+		if err != nil {
+			return offline, err
+		}
+		if res == 1 {
+			offline = append(offline, userID)
+		}
+	}
+	return offline, nil
+}
+
+// GetPresences 批量读取在线状态，不做可见性过滤
+func (r *userRedis) GetPresences(ctx context.Context, userIDs []int64) (map[int64]*dto.Presence, error) {
+	pipe := r.rdb.Pipeline()
+	devices := make([]*redis.StringStringMapCmd, len(userIDs))
+	lastSeen := make([]*redis.StringCmd, len(userIDs))
+	for i, uid := range userIDs {
+		devices[i] = pipe.HGetAll(ctx, presenceDevicesKey(uid))
+		lastSeen[i] = pipe.Get(ctx, presenceLastSeenKey(uid))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	cutoff := presenceCutoff(time.Now())
+	res := make(map[int64]*dto.Presence, len(userIDs))
+	for i, uid := range userIDs {
+		p := &dto.Presence{UserID: uid, Visible: true, Platforms: []int{}}
+		var latest int64
+		for platform, ts := range devices[i].Val() {
+			beat, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil || beat <= cutoff {
+				continue
+			}
+			if pf, err := strconv.Atoi(platform); err == nil {
+				p.Platforms = append(p.Platforms, pf)
+			}
+			latest = max(latest, beat)
+		}
+		p.Online = len(p.Platforms) > 0
+		if !p.Online {
+			latest, _ = lastSeen[i].Int64()
+		}
+		if latest > 0 {
+			t := time.Unix(latest, 0)
+			p.LastSeen = &t
+		}
+		res[uid] = p
+	}
+	return res, nil
+}
+
+// SubscribePresence 订阅 targetIDs 的在线状态变化，ttl 后需要重新订阅
+func (r *userRedis) SubscribePresence(ctx context.Context, subscriberID int64, targetIDs []int64, ttl time.Duration) error {
+	expireAt := float64(time.Now().Add(ttl).Unix())
+	pipe := r.rdb.Pipeline()
+	for _, id := range targetIDs {
+		key := presenceSubscribersKey(id)
+		pipe.ZAdd(ctx, key, &redis.Z{Score: expireAt, Member: subscriberID})
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetPresenceSubscribers 返回还在有效期内的订阅者
+func (r *userRedis) GetPresenceSubscribers(ctx context.Context, userID int64) ([]int64, error) {
+	key := presenceSubscribersKey(userID)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := r.rdb.ZRemRangeByScore(ctx, key, "-inf", now).Err(); err != nil {
+		return nil, err
+	}
+	members, err := r.rdb.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Publish 推送到用户的频道
+func (r *userRedis) Publish(ctx context.Context, userIDs []int64, payload []byte) error {
+	pipe := r.rdb.Pipeline()
+	for _, uid := range userIDs {
+		pipe.Publish(ctx, fmt.Sprintf("user:%d:messages", uid), payload)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -61,6 +61,10 @@ type UserRepo interface {
 	BlockFriend(ctx context.Context, blockedfriend *model.Blacklist) error
 	UnblockFriend(ctx context.Context, userid int64, friendid int64) error
 	GetBlockedFriends(ctx context.Context, userid int64) ([]int64, error)
+	GetBlockedBy(ctx context.Context, userid int64, candidateIDs []int64) ([]int64, error)
+	// Presence
+	UpdatePresenceVisibility(ctx context.Context, userid int64, visibility model.Visibility) error
+	GetPresenceVisibilities(ctx context.Context, userIDs []int64) (map[int64]model.Visibility, error)
 }
 
 type userRepo struct {
@@ -364,3 +368,48 @@ func (s *userRepo) GetBlockedFriends(ctx context.Context, userid int64) ([]int64
 	}
 	return friendIds, nil
 }
+
+// 在 candidateIDs 中找出拉黑了 userid 的用户
+func (s *userRepo) GetBlockedBy(ctx context.Context, userid int64, candidateIDs []int64) ([]int64, error) {
+	var userIds []int64
+	if len(candidateIDs) == 0 {
+		return userIds, nil
+	}
+	if err := s.db.WithContext(ctx).
+		Model(&model.Blacklist{}).
+		Where("blocked_user_id = ? AND user_id IN ?", userid, candidateIDs).
+		Distinct().
+		Pluck("user_id", &userIds).Error; err != nil {
+		return nil, err
+	}
+	return userIds, nil
+}
+
+// 修改在线状态可见范围
+func (s *userRepo) UpdatePresenceVisibility(ctx context.Context, userid int64, visibility model.Visibility) error {
+	return s.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ?", userid).
+		Update("presence_visibility", visibility).Error
+}
+
+// 批量查询在线状态可见范围，查不到的用户不在结果里
+func (s *userRepo) GetPresenceVisibilities(ctx context.Context, userIDs []int64) (map[int64]model.Visibility, error) {
+	res := make(map[int64]model.Visibility, len(userIDs))
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+	var rows []struct {
+		ID                 int64
+		PresenceVisibility model.Visibility
+	}
+	if err := s.db.WithContext(ctx).Model(&model.User{}).
+		Select("id, presence_visibility").
+		Where("id IN ?", userIDs).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		res[row.ID] = row.PresenceVisibility
+	}
+	return res, nil
+}
@@ -18,6 +18,14 @@ type UserRedis interface {
 	SetSession(ctx context.Context, session *dto.UserSession) error
 	GetSession(ctx context.Context, userId int64) (*dto.UserSession, error)
 	DelSession(ctx context.Context, userId int64) error
+	// 在线状态
+	Heartbeat(ctx context.Context, userID int64, platform int) (bool, error)
+	Offline(ctx context.Context, userID int64, platform int) (bool, error)
+	SweepPresence(ctx context.Context) ([]int64, error)
+	GetPresences(ctx context.Context, userIDs []int64) (map[int64]*dto.Presence, error)
+	SubscribePresence(ctx context.Context, subscriberID int64, targetIDs []int64, ttl time.Duration) error
+	GetPresenceSubscribers(ctx context.Context, userID int64) ([]int64, error)
+	Publish(ctx context.Context, userIDs []int64, payload []byte) error
 }
 
 type userRedis struct {
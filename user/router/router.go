@@ -36,6 +36,14 @@ func SetupFriendRouter(r *gin.Engine, userHandler *handler.UserHandler) {
 	r.GET("account/blacklist/friendlists", userHandler.GetBlockedFriends)
 }
 
+func SetupPresenceRouter(r *gin.Engine, userHandler *handler.UserHandler) {
+	r.POST("/account/presence/heartbeat", userHandler.Heartbeat)
+	r.POST("/account/presence/offline", userHandler.Offline)
+	r.PUT("/account/presence/privacy", userHandler.UpdatePresenceVisibility)
+	r.POST("/account/presence/subscribe", userHandler.SubscribePresence)
+	r.GET("/account/presence", userHandler.GetPresences)
+}
+
 func SetupVerificationRouter(r *gin.Engine, verificationHandler *handler.VerificationHandler) {
 	r.POST("/account/code/send", verificationHandler.SendCode)
 	r.POST("/account/code/verify", verificationHandler.VerifyCode)
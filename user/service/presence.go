@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AdventureDe/LinkIM/user/dto"
+	"github.com/AdventureDe/LinkIM/user/repo"
+	"github.com/AdventureDe/LinkIM/user/repo/model"
+)
+
+/* ----------------------------------------------------- */
+// 在线状态部分
+
+const (
+	// 一次最多查询 / 订阅的用户数
+	maxPresenceBatch = 200
+	// 订阅有效期，客户端在列表可见期间需要定期重新订阅
+	presenceSubscribeTTL = 5 * time.Minute
+	// 扫描超时设备的间隔
+	presenceSweepInterval = 15 * time.Second
+)
+
+// gRPC 的批量查询直接复用 UserService
+var _ repo.PresenceReader = (*UserService)(nil)
+
+// 心跳，设备从离线变为在线时通知订阅者
+func (s *UserService) Heartbeat(ctx context.Context, userID int64, platform int) error {
+	if userID <= 0 || platform <= 0 {
+		return errors.New("invalid userID or platform")
+	}
+	cameOnline, err := s.redis.Heartbeat(ctx, userID, platform)
+	if err != nil {
+		return fmt.Errorf("fail to save heartbeat: %w", err)
+	}
+	if cameOnline {
+		s.notifyPresence(ctx, userID, false)
+	}
+	return nil
+}
+
+// 设备主动下线，platform 为 0 时下线所有设备
+func (s *UserService) Offline(ctx context.Context, userID int64, platform int) error {
+	if userID <= 0 || platform < 0 {
+		return errors.New("invalid userID or platform")
+	}
+	wentOffline, err := s.redis.Offline(ctx, userID, platform)
+	if err != nil {
+		return fmt.Errorf("fail to set offline: %w", err)
+	}
+	if wentOffline {
+		s.notifyPresence(ctx, userID, false)
+	}
+	return nil
+}
+
+// 修改在线状态可见范围，并把新的可见结果推给当前的订阅者
+func (s *UserService) UpdatePresenceVisibility(ctx context.Context, userID int64, visibility string) error {
+	v := model.Visibility(visibility)
+	if v != model.VisibleEveryone && v != model.VisibleFriends && v != model.VisibleNobody {
+		return errors.New("invalid visibility")
+	}
+	if err := s.repo.UpdatePresenceVisibility(ctx, userID, v); err != nil {
+		return fmt.Errorf("fail to update presence visibility: %w", err)
+	}
+	s.notifyPresence(ctx, userID, true)
+	return nil
+}
+
+// 订阅在线状态变化，同时返回当前状态
+func (s *UserService) SubscribePresence(ctx context.Context, userID int64, targetIDs []int64) ([]*dto.Presence, error) {
+	presences, err := s.GetPresences(ctx, userID, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.SubscribePresence(ctx, userID, targetIDs, presenceSubscribeTTL); err != nil {
+		return nil, fmt.Errorf("fail to subscribe presence: %w", err)
+	}
+	return presences, nil
+}
+
+// GetPresences 批量查询在线状态，viewerID 看不到的用户只返回 Visible=false
+func (s *UserService) GetPresences(ctx context.Context, viewerID int64, userIDs []int64) ([]*dto.Presence, error) {
+	if viewerID <= 0 {
+		return nil, errors.New("invalid viewerID")
+	}
+	if len(userIDs) > maxPresenceBatch {
+		return nil, errors.New("too many users")
+	}
+	if len(userIDs) == 0 {
+		return []*dto.Presence{}, nil
+	}
+
+	visibilities, err := s.repo.GetPresenceVisibilities(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get presence visibilities: %w", err)
+	}
+	friendIDs, err := s.repo.GetFriendLists(ctx, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get friend lists: %w", err)
+	}
+	friends := make(map[int64]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		friends[id] = true
+	}
+	blockedBy, err := s.repo.GetBlockedBy(ctx, viewerID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get blacklist: %w", err)
+	}
+	blocked := make(map[int64]bool, len(blockedBy))
+	for _, id := range blockedBy {
+		blocked[id] = true
+	}
+
+	states, err := s.redis.GetPresences(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get presences: %w", err)
+	}
+	res := make([]*dto.Presence, 0, len(userIDs))
+	for _, id := range userIDs {
+		v, exists := visibilities[id]
+		if !exists {
+			continue
+		}
+		if id == viewerID || (!blocked[id] && canSeePresence(v, friends[id])) {
+			res = append(res, states[id])
+		} else {
+			res = append(res, hiddenPresence(id))
+		}
+	}
+	return res, nil
+}
+
+func canSeePresence(v model.Visibility, isFriend bool) bool {
+	switch v {
+	case model.VisibleEveryone:
+		return true
+	case model.VisibleFriends:
+		return isFriend
+	default:
+		return false
+	}
+}
+
+func hiddenPresence(userID int64) *dto.Presence {
+	return &dto.Presence{UserID: userID, Platforms: []int{}}
+}
+
+// 推送在线状态给订阅者。includeHidden 为 true 时看不到的订阅者也会收到一条 Visible=false 的事件，
+// 用于可见范围收紧后让客户端清掉旧状态
+func (s *UserService) notifyPresence(ctx context.Context, userID int64, includeHidden bool) {
+	subscribers, err := s.redis.GetPresenceSubscribers(ctx, userID)
+	if err != nil {
+		log.Printf("fail to get presence subscribers: %v", err)
+		return
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+	visibilities, err := s.repo.GetPresenceVisibilities(ctx, []int64{userID})
+	if err != nil {
+		log.Printf("fail to get presence visibility: %v", err)
+		return
+	}
+	friendIDs, err := s.repo.GetFriendLists(ctx, userID)
+	if err != nil {
+		log.Printf("fail to get friend lists: %v", err)
+		return
+	}
+	friends := make(map[int64]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		friends[id] = true
+	}
+	blockedIDs, err := s.repo.GetBlockedFriends(ctx, userID)
+	if err != nil {
+		log.Printf("fail to get blacklist: %v", err)
+		return
+	}
+	blocked := make(map[int64]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	states, err := s.redis.GetPresences(ctx, []int64{userID})
+	if err != nil {
+		log.Printf("fail to get presence: %v", err)
+		return
+	}
+
+	var visible, hidden []int64
+	for _, id := range subscribers {
+		if !blocked[id] && canSeePresence(visibilities[userID], friends[id]) {
+			visible = append(visible, id)
+		} else if includeHidden {
+			hidden = append(hidden, id)
+		}
+	}
+	s.publishPresence(ctx, visible, states[userID])
+	s.publishPresence(ctx, hidden, hiddenPresence(userID))
+}
+
+func (s *UserService) publishPresence(ctx context.Context, userIDs []int64, presence *dto.Presence) {
+	if len(userIDs) == 0 {
+		return
+	}
+	payload, err := json.Marshal(&dto.PushEvent{Event: "presence", Data: presence})
+	if err != nil {
+		log.Printf("fail to marshal presence event: %v", err)
+		return
+	}
+	if err := s.redis.Publish(ctx, userIDs, payload); err != nil {
+		log.Printf("fail to publish presence event: %v", err)
+	}
+}
+
+// SweepPresence 定期清理心跳超时的设备，并通知因此离线的用户的订阅者，ctx 取消后退出
+func (s *UserService) SweepPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			offline, err := s.redis.SweepPresence(ctx)
+			if err != nil {
+				log.Printf("fail to sweep presence: %v", err)
+			}
+			for _, id := range offline {
+				s.notifyPresence(ctx, id, false)
+			}
+		}
+	}
+}
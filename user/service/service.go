@@ -173,6 +173,11 @@ func (s *UserService) Logout(ctx context.Context, req dto.LogoutRequest) error {
 		return fmt.Errorf("failed to delete session from Redis: %w", err)
 	}
 
+	// 退出登录后所有设备都视为离线
+	if err := s.Offline(ctx, req.UserID, 0); err != nil {
+		return err
+	}
+
 	return nil
 }
 